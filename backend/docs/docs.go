@@ -23,9 +23,109 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/ask": {
+            "post": {
+                "description": "Answers a question such as \"¿cuánto gasté en transporte en marzo?\", typed (JSON body, or a question form field) or recorded (an audio form field). The model translates the question into a query (aggregation sum, count, average, max or min, with optional from/to days, search words, merchant, units and group_by); the query is checked against a whitelist and answered from the stored expenses, so the number never comes from the model. Search words use full-text search, not semantic search. reply states the answer in the question's language.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ask"
+                ],
+                "summary": "Ask a question about expenses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Typed question (JSON body)",
+                        "name": "question",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.AskRequest"
+                        }
+                    },
+                    {
+                        "type": "file",
+                        "description": "Recorded question (m4a, mp3, wav, ogg or webm)",
+                        "name": "audio",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Answer",
+                        "schema": {
+                            "$ref": "#/definitions/models.AskAnswer"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing, empty or too long question",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Audio too large or too long",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported audio format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Question cannot be answered, or silent audio",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "OpenAI unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/expenses": {
             "get": {
-                "description": "Retrieves a paginated list of expenses with optional sorting",
+                "description": "Retrieves a paginated list of expenses with optional sorting. Pages with more expenses after them return next_cursor; passing it back as cursor (with the same order) continues after the last expense seen, so expenses created meanwhile are neither skipped nor repeated. With q, only expenses whose description or recording transcription match are listed, best match first, each with a match holding its rank and excerpts with the matching words in \u003cmark\u003e tags.",
                 "produces": [
                     "application/json"
                 ],
@@ -34,6 +134,12 @@ const docTemplate = `{
                 ],
                 "summary": "List expenses with pagination",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page (page is ignored when set)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "description": "Page number (default: 1)",
@@ -46,6 +152,12 @@ const docTemplate = `{
                         "name": "per_page",
                         "in": "query"
                     },
+                    {
+                        "type": "boolean",
+                        "description": "Count total and total_pages (default: true with page, false with cursor)",
+                        "name": "include_total",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Sort field: purchased_at or created_at (default: created_at)",
@@ -57,6 +169,60 @@ const docTemplate = `{
                         "description": "Sort direction: asc or desc (default: desc)",
                         "name": "order[dir]",
                         "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only expenses flagged as likely duplicates",
+                        "name": "possible_duplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses of this merchant",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only reimbursable expenses in this status: pending, submitted or reimbursed",
+                        "name": "reimbursement_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses with these tags (comma-separated)",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "any: expenses with any of the tags; all: with all of them (default: any)",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Full-text search over descriptions and transcriptions (Spanish stemming, accents ignored; phrases in double quotes, or and -word supported). Overrides the order.",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or after this day (YYYY-MM-DD, in the timezone of the X-User-ID user) or RFC3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or before this day (YYYY-MM-DD, in the timezone of the X-User-ID user) or RFC3339 time",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User whose expenses are listed and whose timezone applies to day filters (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -66,6 +232,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.PaginatedExpenses"
                         }
                     },
+                    "400": {
+                        "description": "Invalid merchant ID, reimbursement_status, tag_match, from, to or cursor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -78,46 +253,113 @@ const docTemplate = `{
                 }
             }
         },
-        "/upload": {
-            "post": {
-                "description": "Uploads an audio file, transcribes it using OpenAI Whisper, and extracts expense data using GPT-4",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/expenses/export": {
+            "get": {
+                "description": "Downloads every expense matching the list filters as CSV or XLSX, without pagination. Each row has the expense total (unit_price × quantity) and the last row the grand total. Purchase times are in the timezone of the X-User-ID user. CSV numbers are plain (\"1234.50\", comma-separated) unless locale or grouping is given; locales with a decimal comma (\"es\", \"es-ES\") use semicolons between fields. XLSX cells are numbers and dates that the spreadsheet formats for its own locale.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
                     "expenses"
                 ],
-                "summary": "Upload audio and extract expenses",
+                "summary": "Export expenses",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Audio file (m4a, mp3, wav, etc.)",
-                        "name": "audio",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "File format: csv or xlsx",
+                        "name": "format",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Purchase date/time in RFC3339 format (e.g., 2026-02-22T10:30:00Z)",
-                        "name": "purchased_at",
-                        "in": "formData"
+                        "description": "BCP 47 locale for CSV number formatting (e.g. es-PE, es)",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Group thousands (1,234.50) in CSV numbers and XLSX amounts",
+                        "name": "grouping",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: purchased_at or created_at (default: created_at)",
+                        "name": "order[by]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort direction: asc or desc (default: desc)",
+                        "name": "order[dir]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only expenses flagged as likely duplicates",
+                        "name": "possible_duplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses of this merchant",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only reimbursable expenses in this status: pending, submitted or reimbursed",
+                        "name": "reimbursement_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses with these tags (comma-separated)",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "any: expenses with any of the tags; all: with all of them (default: any)",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses matching this full-text search (kept in the chosen order)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User whose expenses are exported and whose timezone applies to days and times (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of extracted expenses",
+                        "description": "Export file, named expenses-YYYY-MM-DD.csv or .xlsx",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Expense"
-                            }
+                            "type": "file"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Invalid format, locale, merchant ID, reimbursement_status, tag_match, from or to",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -136,55 +378,2864 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Expense": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "purchased_at": {
-                    "type": "string"
-                },
-                "quantity": {
-                    "type": "number"
-                },
-                "unit": {
-                    "type": "string"
-                },
-                "unit_price": {
-                    "type": "number"
-                }
-            }
         },
-        "models.PaginatedExpenses": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Expense"
-                    }
-                },
-                "page": {
-                    "type": "integer"
-                },
-                "per_page": {
-                    "type": "integer"
-                },
-                "total": {
-                    "type": "integer"
-                },
-                "total_pages": {
-                    "type": "integer"
+        "/expenses/summary": {
+            "get": {
+                "description": "Returns the total and count of the expenses of the user identified by the X-User-ID header, optionally within a purchase date range and grouped. With group_by=merchant there is one group per merchant (key is the merchant ID, empty for expenses without a merchant), largest total first. With group_by=tag there is one group per tag (key is the tag, empty for untagged expenses), largest total first; an expense with several tags counts in each of their groups, but once in the summary totals. With group_by=day there is one group per purchase day in the user's timezone (key is YYYY-MM-DD), in date order. Imported transactions matched to a recorded expense by reconciliation are left out, since the recorded expense is the same purchase.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expenses"
+                ],
+                "summary": "Summarize expenses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grouping: merchant, tag or day",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses with these tags (comma-separated)",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "any: expenses with any of the tags; all: with all of them (default: any)",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Expense totals",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid group_by, tag_match, from or to",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}": {
+            "delete": {
+                "description": "Soft-deletes an expense: it is no longer listed, exported, summarized or matched, but stays in the database with its history and can be restored with POST /expenses/{id}/restore. Expenses flagged as its duplicates are unflagged until it is restored. An expense in a report not submitted yet leaves the report and is restored without one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expenses"
+                ],
+                "summary": "Delete an expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deleted expense, as it was before the deletion",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found, of another user or already deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Expense was claimed in a submitted report",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Changes the unit price, quantity, unit or description of an expense; omitted fields are left unchanged. Used to undo a spoken correction with the before values it returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expenses"
+                ],
+                "summary": "Update an expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID, or empty or out of bounds update",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Expense was claimed in a submitted report",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/confirm": {
+            "post": {
+                "description": "Marks an expense as confirmed, removing it from the review queue",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "review"
+                ],
+                "summary": "Confirm an expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Confirmed expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/duplicate/dismiss": {
+            "post": {
+                "description": "Marks an expense flagged as a possible duplicate as a separate purchase, keeping both expenses",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "duplicates"
+                ],
+                "summary": "Dismiss a duplicate flag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expenses belong to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the expense flagged as duplicate",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The expense, no longer flagged",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense, or the expense it duplicates, not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Expense is not flagged as a possible duplicate",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/duplicate/merge": {
+            "post": {
+                "description": "Deletes (soft-deletes, see POST /expenses/{id}/restore) an expense flagged as a possible duplicate and returns the earlier expense it duplicates",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "duplicates"
+                ],
+                "summary": "Merge a duplicate expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expenses belong to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the expense flagged as duplicate",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The kept (original) expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense, or the expense it duplicates, not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Expense is not flagged as a possible duplicate, or was claimed in a submitted report",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/history": {
+            "get": {
+                "description": "Lists the changes made to an expense, deleted or not, oldest first: its creation, updates (corrections, confirmation, duplicate flags), deletion and restoration, each with the user who made it and the expense before and after",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expenses"
+                ],
+                "summary": "Expense history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Events, oldest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ExpenseEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/reimbursable": {
+            "put": {
+                "description": "Flags an expense paid personally for work as reimbursable, with reimbursement_status pending until it is claimed in a submitted report (see POST /reports), or unflags it, taking it out of its report. Expenses of submitted reports cannot be changed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Flag an expense as reimbursable",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User making the change, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Whether the expense is reimbursable",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReimbursableRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID or request body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Expense was claimed in a submitted report",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/restore": {
+            "post": {
+                "description": "Undoes the deletion of an expense, by DELETE /expenses/{id}, a spoken deletion or a duplicate merge. Expenses its deletion unflagged as duplicates are flagged again, and its reconciliation matches count again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expenses"
+                ],
+                "summary": "Restore a deleted expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Expense is not deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/tags": {
+            "post": {
+                "description": "Adds tags to an expense and returns it. Tags are normalized: lowercase, accents folded and words joined by hyphens, so \"#Trip Cusco\" is \"trip-cusco\". Tags the expense already has are ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Tag an expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TagExpenseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tagged expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID, or no tags, tags longer than 50 characters or more than 20 tags on the expense",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/{id}/tags/{tag}": {
+            "delete": {
+                "description": "Removes a tag from an expense and returns it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Remove a tag from an expense",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the expense belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag to remove",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Untagged expense",
+                        "schema": {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Expense not found or of another user, or it does not have the tag",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/import": {
+            "post": {
+                "description": "Creates one expense (source import, status confirmed) per charge of a CSV or OFX bank statement. Credits are skipped. Each transaction's bank ID (OFX FITID, or the mapped external_id column of a CSV) is stored as external_id and transactions already imported are skipped, so importing overlapping statements is safe; CSV rows without an ID column are identified by their date, amount, description and repetition. Rows that cannot be read are reported in errors without failing the import.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "import"
+                ],
+                "summary": "Import expenses from a bank statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Bank statement (CSV or OFX, max 4 MB)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv or ofx (default: from the file extension)",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "CSV column mapping as JSON, e.g. {\\",
+                        "name": "mapping",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing file, unknown format, invalid mapping or unreadable statement",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request body or statement too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants": {
+            "get": {
+                "description": "Returns the merchants of the user identified by the X-User-ID header, with the aliases that resolve to each of them. Merchants are created when an expense mentions a store for the first time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "List merchants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Merchants ordered by name",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Merchant"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/{id}/aliases": {
+            "post": {
+                "description": "Makes another spelling of a merchant (e.g. \"super tottus\" for \"Tottus\") resolve to it in future expenses. Aliases are matched case- and accent-insensitively.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Add a merchant alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Alias to add",
+                        "name": "alias",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddMerchantAliasRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Merchant with its aliases",
+                        "schema": {
+                            "$ref": "#/definitions/models.Merchant"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid merchant ID or alias",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Merchant not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Alias already belongs to another merchant",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/receipts/{id}/image": {
+            "get": {
+                "description": "Returns the stored photo of a receipt of the user",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "receipts"
+                ],
+                "summary": "Get a receipt image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Receipt ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Receipt image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid receipt ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Receipt not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reconciliation": {
+            "get": {
+                "description": "Reports, with the matches made so far by POST /reconciliation, the transactions imported for the X-User-ID user and the recorded expenses (voice notes and receipts) they paid for, the recorded expenses without a bank transaction and the bank transactions that were never recorded. Nothing is matched or stored: matching writes, so it is POST /reconciliation, and GET stays safe to repeat, prefetch and cache. Without from and to, the report covers the period of the transactions imported in the last 90 days.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "import"
+                ],
+                "summary": "Get the reconciliation report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: 90 days before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: now)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matched and unmatched expenses",
+                        "schema": {
+                            "$ref": "#/definitions/models.Reconciliation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid from or to",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Matches the recorded expenses (voice notes and receipts) of the X-User-ID user to imported bank transactions with the same amount (within 1%) posted within 3 days, stores the new matches and returns the report of GET /reconciliation. All the expenses of a recording or receipt can match one transaction together. Matches never change once made, unless deleted with DELETE /reconciliation/matches/{transaction_id}. Without from and to, the last 90 days are reconciled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "import"
+                ],
+                "summary": "Reconcile expenses against the bank",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: 90 days before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: now)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matched and unmatched expenses",
+                        "schema": {
+                            "$ref": "#/definitions/models.Reconciliation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid from or to",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reconciliation/matches/{transaction_id}": {
+            "delete": {
+                "description": "Unlinks an imported bank transaction from the recorded expenses it was matched to. Later reconciliations do not match them together again, but may match them elsewhere.",
+                "tags": [
+                    "import"
+                ],
+                "summary": "Delete a reconciliation match",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the imported transaction",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Match deleted"
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction has no match",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports": {
+            "get": {
+                "description": "Returns the reports of the user identified by the X-User-ID header with their status and totals, without their expenses",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "List expense reports",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reports, latest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ExpenseReport"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a report of the user identified by the X-User-ID header claiming reimbursable expenses. The expenses must be pending reimbursable expenses of the user that no other report claims; more can be added until the report is submitted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Create an expense report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Report title and expenses",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created report with its expenses",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid title or expense IDs",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "An expense is not a pending reimbursable expense of the user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}": {
+            "get": {
+                "description": "Returns a report with its expenses, oldest purchase first, in the timezone of the report's user. Expenses read from voice notes include the transcription of their recording.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Get an expense report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the report belongs to (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report with its expenses",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}/document": {
+            "get": {
+                "description": "Renders a report as a printable HTML page (print it to PDF to attach it to a claim) listing its expenses with their totals and the transcriptions of their voice notes, the report's grand total, and the photos of their receipts, embedded in the page.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Get an expense report document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the report belongs to (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 tag formatting the amounts, e.g. es-PE (default: en)",
+                        "name": "locale",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML document",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report ID or locale",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}/expenses": {
+            "post": {
+                "description": "Adds pending reimbursable expenses of the report's user that no other report claims to a report that was not submitted. Either all the expenses are added or none.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Add expenses to a report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the report belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Expenses to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportExpensesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report with its expenses",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report ID or expense IDs",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Report was submitted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "An expense is not a pending reimbursable expense of the report's user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}/expenses/{expense_id}": {
+            "delete": {
+                "description": "Takes an expense out of a report that was not submitted. The expense stays reimbursable and pending, so it can be claimed in another report.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Remove an expense from a report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the report belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expense ID",
+                        "name": "expense_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report with its expenses",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report ID or expense ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found or of another user, or expense not in the report",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Report was submitted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}/reimburse": {
+            "post": {
+                "description": "Records that a submitted report was paid. The report and its expenses move to reimbursed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Mark an expense report as reimbursed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the report belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reimbursed report",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Report was not submitted or was already reimbursed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}/submit": {
+            "post": {
+                "description": "Submits a report for reimbursement, recording the submission date. The report and its expenses move to submitted and can no longer be changed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Submit an expense report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User the report belongs to, recorded in the expense history (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Submitted report",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExpenseReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid report ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found or of another user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Report is empty or was already submitted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/review": {
+            "get": {
+                "description": "Retrieves a paginated list of the user's low-confidence expenses (status needs_review), oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "review"
+                ],
+                "summary": "List expenses that need review",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page (page is ignored when set)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page (default: 10, max: 100)",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count total and total_pages (default: true with page, false with cursor)",
+                        "name": "include_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated list of expenses that need review",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaginatedExpenses"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid cursor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings": {
+            "get": {
+                "description": "Returns the settings of the user identified by the X-User-ID header (defaults are returned if none are stored)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get user settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User settings",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserSettings"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates the default language, used when the language of a recording cannot be detected, and the IANA timezone used to resolve spoken dates and to tell which day an expense falls on in list filters and summaries. Omitted fields keep their current value.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update user settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Settings to update (language: es, en or pt; timezone: IANA name such as America/Lima)",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated settings",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/upload": {
+            "post": {
+                "description": "Uploads an audio file, transcribes it using OpenAI Whisper, detects its language (es, en, pt; falls back to the user's default language), and extracts expense data using GPT-4.\n\nSpoken purchase dates (\"ayer compré...\", \"el lunes\", \"el 3 de febrero\") are resolved against purchased_at (the recording time, default now) in the given timezone and set per expense; purchased_at is used as is when no date is spoken.\n\nBatch mode: send up to 10 recordings (4 MB in total, the request limit) as audio[\u003ckey\u003e] parts, each with optional purchased_at[\u003ckey\u003e] and recording_id[\u003ckey\u003e] fields. They are processed concurrently and the response is a BatchUploadResponse with one result per recording, in upload order; a failing recording does not fail the others. The status is 200 if all succeed, 207 if some fail, and the failures' status (the highest if they differ) if all fail.\n\nRecordings that correct or delete an expense instead (\"el pan costó cinco, no cuatro\", \"borra el último gasto\") are applied to the most recent matching expense of the user, and the response is a VoiceCommandResult with the changes (the expense before and after) so the app can offer an undo; a question (\"¿cuánto gasté hoy?\") returns intent query and the transcription, to be sent to POST /ask. In a batch, such recordings have intent and changes instead of expenses.\n\nRetries are idempotent when the request has an Idempotency-Key header or a recording ID (recording_id, or recording_id[\u003ckey\u003e] per batch recording): the stored response is replayed with an Idempotent-Replayed header, and reusing a key with a different payload is rejected with 422. A batch in which a recording failed with a server error is not replayed whole: its retry processes that recording again and replays the others.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "expenses"
+                ],
+                "summary": "Upload audio and extract expenses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client-generated key; retries with the same key replay the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Audio file (m4a, mp3, wav, ogg or webm; detected from content, max 4 MB and 10 minutes). Required unless uploading a batch",
+                        "name": "audio",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Purchase date/time in RFC3339 format (e.g., 2026-02-22T10:30:00Z)",
+                        "name": "purchased_at",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client-side recording identifier, stored with the recording and used as idempotency key without an Idempotency-Key header",
+                        "name": "recording_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone of the device (e.g. America/Lima), used to resolve spoken dates like \\",
+                        "name": "timezone",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-recording results (batch upload where every recording succeeded)",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchUploadResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Per-recording results (batch upload where some recordings failed)",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchUploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same idempotency key is still being processed, or a spoken correction or deletion targets an expense claimed in a submitted report",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request body over 4 MB, or audio too long",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported audio format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Empty or silent recording, extracted data out of bounds (negative price, unknown unit, ...), no recent expense matching a correction or deletion, or idempotency key reused with a different payload",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "OpenAI unavailable (code: openai_unavailable or openai_circuit_open)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/upload/receipt": {
+            "post": {
+                "description": "Reads the text of a receipt photo with OCR (OpenAI vision or tesseract, see OCR_ENGINE), extracts one expense per item line with a receipt-specific prompt, and stores the image with the expenses (receipt_id)",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "receipts"
+                ],
+                "summary": "Upload a receipt photo and extract expenses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User identifier (default: default)",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Receipt photo (JPEG, PNG or WebP; detected from content, max 4 MB)",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Purchase date/time in RFC3339 format (e.g., 2026-02-22T10:30:00Z)",
+                        "name": "purchased_at",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Receipt language: es, en or pt (default: the user's language)",
+                        "name": "language",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of extracted expenses",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Expense"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request body or image too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported image format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "No text found on the image, or extracted data out of bounds",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "OpenAI unavailable (code: openai_unavailable or openai_circuit_open)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AddMerchantAliasRequest": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AskAnswer": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "description": "Answer is the aggregated value: an amount, or the number of expenses for count",
+                    "type": "number"
+                },
+                "count": {
+                    "description": "Count is the number of expenses the answer is computed from",
+                    "type": "integer"
+                },
+                "group": {
+                    "description": "Group is the top group of a grouped query (the largest value, or the smallest for min)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SummaryGroup"
+                        }
+                    ]
+                },
+                "prompt_version": {
+                    "type": "string"
+                },
+                "query": {
+                    "$ref": "#/definitions/models.AskQuery"
+                },
+                "question": {
+                    "description": "Question is the question asked, transcribed if it was recorded",
+                    "type": "string"
+                },
+                "reply": {
+                    "description": "Reply is the answer in words",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AskQuery": {
+            "type": "object",
+            "properties": {
+                "aggregation": {
+                    "description": "Aggregation is one of AskAggregations, over expense totals (count counts expenses)",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "From and To are whole days (YYYY-MM-DD) in the user's timezone, both optional",
+                    "type": "string"
+                },
+                "group_by": {
+                    "description": "GroupBy is one of SummaryGroupings (optional); the answer is then the top group",
+                    "type": "string"
+                },
+                "merchant": {
+                    "description": "Merchant is the name of one of the user's merchants (optional)",
+                    "type": "string"
+                },
+                "search": {
+                    "description": "Search is a full-text search over descriptions and transcriptions (optional), not a\nsemantic one: the model expands topics into the words to look for",
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "units": {
+                    "description": "Units limits the expenses to these units of measurement (optional)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.AskRequest": {
+            "type": "object",
+            "properties": {
+                "question": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BatchUploadResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UploadResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CreateReportRequest": {
+            "type": "object",
+            "properties": {
+                "expense_ids": {
+                    "description": "ExpenseIDs are pending reimbursable expenses to include (optional)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Expense": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is when the expense was deleted; deleted expenses are only seen in their history",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "external_id": {
+                    "description": "ExternalID is the bank's transaction ID of an imported expense",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "match": {
+                    "description": "Match is how the expense matched a full-text search, only set in search results",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ExpenseMatch"
+                        }
+                    ]
+                },
+                "merchant": {
+                    "description": "Merchant is the name of the linked merchant (read-only)",
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "possible_duplicate_of": {
+                    "description": "PossibleDuplicateOf is the ID of an earlier expense this one likely duplicates, until merged or dismissed",
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "type": "string"
+                },
+                "purchased_at": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "receipt_id": {
+                    "type": "string"
+                },
+                "recording_id": {
+                    "type": "string"
+                },
+                "reimbursable": {
+                    "description": "Reimbursable expenses were paid personally for work; ReimbursementStatus tracks their claim",
+                    "type": "boolean"
+                },
+                "reimbursement_status": {
+                    "type": "string"
+                },
+                "report_id": {
+                    "description": "ReportID is the expense report claiming the expense",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "Source is what the expense was read from: voice, receipt or import",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags are the expense's normalized tags, in name order",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "transcription": {
+                    "description": "Transcription is the text of the recording the expense was read from, only set in reports",
+                    "type": "string"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "unit_price": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExpenseChange": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Action is updated or deleted",
+                    "type": "string"
+                },
+                "after": {
+                    "description": "After is the updated expense, omitted for deletions",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Expense"
+                        }
+                    ]
+                },
+                "before": {
+                    "$ref": "#/definitions/models.Expense"
+                }
+            }
+        },
+        "models.ExpenseEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Action is create, update, delete or restore",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Actor is the user who made the change",
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "description": "Before and After are the expense as it was stored before and after the change; Before is\nomitted for creations",
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expense_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ExpenseMatch": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "transcription": {
+                    "description": "Transcription is an excerpt of the recording, empty if it did not match",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExpenseReport": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expenses": {
+                    "description": "Expenses are only loaded for a single report, oldest purchase first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reimbursed_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is pending until the report is submitted, then submitted and finally reimbursed",
+                    "type": "string"
+                },
+                "submitted_at": {
+                    "description": "SubmittedAt is when the report was submitted, nil while it can still be changed",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Total and Count cover the report's expenses that are not deleted",
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExpenseSummary": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "group_by": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryGroup"
+                    }
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.ExpenseUpdate": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "unit_price": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.ImportResult": {
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "description": "Duplicates counts the transactions whose external ID was already imported",
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportRowError"
+                    }
+                },
+                "expenses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "format": {
+                    "type": "string"
+                },
+                "imported": {
+                    "description": "Imported counts the new expenses, returned in Expenses",
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Skipped counts the credits (deposits, refunds), which are not expenses",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "external_id": {
+                    "type": "string"
+                },
+                "row": {
+                    "description": "Row is the line of a CSV row or the position of an OFX transaction (1-based)",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Merchant": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "description": "Aliases are the normalized mentions that resolve to this merchant",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name is the display name, as first mentioned",
+                    "type": "string"
+                },
+                "normalized_name": {
+                    "description": "NormalizedName is the lowercase, accent-folded name used to match mentions",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaginatedExpenses": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor continues the list after this page, empty on the last page",
+                    "type": "string"
+                },
+                "page": {
+                    "description": "Page is omitted when the page was requested with a cursor",
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Total and TotalPages are only counted when asked for",
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ReconciledTransaction": {
+            "type": "object",
+            "properties": {
+                "difference": {
+                    "description": "Difference is the transaction amount minus the total of the recorded expenses",
+                    "type": "number"
+                },
+                "expenses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "transaction": {
+                    "$ref": "#/definitions/models.Expense"
+                }
+            }
+        },
+        "models.Reconciliation": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "matched": {
+                    "description": "Matched are the transactions with recorded expenses, by posting date",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReconciledTransaction"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "unmatched_bank": {
+                    "description": "UnmatchedBank are bank transactions that were never recorded",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "unmatched_recorded": {
+                    "description": "UnmatchedRecorded are recorded expenses without a bank transaction (e.g. paid in cash)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                }
+            }
+        },
+        "models.ReimbursableRequest": {
+            "type": "object",
+            "properties": {
+                "reimbursable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ReportExpensesRequest": {
+            "type": "object",
+            "properties": {
+                "expense_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.SummaryGroup": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "Key identifies the group (the merchant ID, the tag, or the day as YYYY-MM-DD), empty for expenses without one",
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "description": "Min and Max are the smallest and largest expense totals",
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.TagExpenseRequest": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.UpdateSettingsRequest": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UploadResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExpenseChange"
+                    }
+                },
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "expenses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "intent": {
+                    "description": "Intent and Changes are set for recordings that corrected or deleted an expense\ninstead of recording new ones (see VoiceCommandResult)",
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "recording_id": {
+                    "type": "string"
+                },
+                "replayed": {
+                    "description": "Replayed is set when the recording was already processed and its stored result is returned",
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UserSettings": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone is an IANA name (e.g. America/Lima) used to tell which day an expense falls on",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.VoiceCommandResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Changes are what the correct and delete intents changed",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExpenseChange"
+                    }
+                },
+                "expenses": {
+                    "description": "Expenses are the expenses recorded by the add intent",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Expense"
+                    }
+                },
+                "intent": {
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "type": "string"
+                },
+                "recording_id": {
+                    "type": "string"
+                },
+                "transcription": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var errNoMovieHeader = errors.New("no mvhd box")
+
+// mp4Duration reads the duration of an MP4/M4A file from its movie header (moov/mvhd),
+// without decoding the audio
+func mp4Duration(r io.ReadSeeker) (time.Duration, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// moov is a top-level box, usually after ftyp and before or after mdat
+	moov, err := findBox(r, 0, end, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhd, err := findBox(r, moov.start, moov.end, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := r.Seek(mvhd.start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var version [4]byte // version and flags
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+
+	var timescale, duration uint64
+	if version[0] == 1 {
+		var header [28]byte // creation and modification times (8 each), timescale, duration
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, err
+		}
+		timescale = uint64(binary.BigEndian.Uint32(header[16:]))
+		duration = binary.BigEndian.Uint64(header[20:])
+	} else {
+		var header [16]byte // creation and modification times (4 each), timescale, duration
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, err
+		}
+		timescale = uint64(binary.BigEndian.Uint32(header[8:]))
+		duration = uint64(binary.BigEndian.Uint32(header[12:]))
+	}
+	if timescale == 0 {
+		return 0, errNoMovieHeader
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// mp4Box is the payload of a box, from just after its header to its end
+type mp4Box struct {
+	start, end int64
+}
+
+// findBox looks for a box of the given type among the boxes between start and end
+func findBox(r io.ReadSeeker, start, end int64, boxType string) (mp4Box, error) {
+	for offset := start; offset+8 <= end; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return mp4Box{}, err
+		}
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return mp4Box{}, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0: // the box extends to the end
+			size = end - offset
+		case 1: // 64-bit size follows the type
+			var large [8]byte
+			if _, err := io.ReadFull(r, large[:]); err != nil {
+				return mp4Box{}, err
+			}
+			size = int64(binary.BigEndian.Uint64(large[:]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return mp4Box{}, errNoMovieHeader
+		}
+		if string(header[4:]) == boxType {
+			return mp4Box{start: offset + headerSize, end: offset + size}, nil
+		}
+		offset += size
+	}
+	return mp4Box{}, errNoMovieHeader
+}
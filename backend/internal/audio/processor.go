@@ -0,0 +1,278 @@
+// Package audio validates and normalizes audio recordings before they are sent to Whisper.
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation errors
+var (
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	ErrTooLarge          = errors.New("audio file too large")
+	ErrTooLong           = errors.New("audio recording too long")
+	ErrEmpty             = errors.New("audio recording is empty")
+	ErrSilent            = errors.New("audio recording is silent")
+)
+
+// Limits configures the accepted recordings
+type Limits struct {
+	MaxBytes           int64         // Whisper rejects files over 25 MB
+	MaxDuration        time.Duration // longer recordings are rejected
+	MinDuration        time.Duration // shorter recordings are considered empty
+	SilenceThresholdDB float64       // recordings whose peak is below this level (dBFS) are silent
+}
+
+// DefaultLimits returns the limits used in production
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBytes:           25 << 20,
+		MaxDuration:        10 * time.Minute,
+		MinDuration:        500 * time.Millisecond,
+		SilenceThresholdDB: -50,
+	}
+}
+
+// Prepared is a validated recording ready for transcription
+type Prepared struct {
+	// Path has an extension matching the detected format
+	Path       string
+	Format     Format
+	Duration   time.Duration // zero if it could not be determined
+	Transcoded bool
+
+	cleanup []string
+}
+
+// Cleanup removes the files created while preparing the recording
+func (p *Prepared) Cleanup() {
+	for _, path := range p.cleanup {
+		os.Remove(path)
+	}
+}
+
+// Processor validates recordings and, when an ffmpeg binary is configured,
+// measures them and transcodes formats Whisper does not accept
+type Processor struct {
+	limits     Limits
+	ffmpegPath string
+}
+
+// NewProcessor creates a new audio processor. ffmpegPath is optional.
+func NewProcessor(limits Limits, ffmpegPath string) *Processor {
+	return &Processor{
+		limits:     limits,
+		ffmpegPath: ffmpegPath,
+	}
+}
+
+// Prepare validates the recording at path and returns the file to send to Whisper.
+// The caller must call Cleanup on the result.
+func (p *Processor) Prepare(ctx context.Context, path string) (*Prepared, error) {
+	format, err := p.check(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatUnknown {
+		return nil, fmt.Errorf("%w: content is not m4a, mp3, wav, ogg or webm", ErrUnsupportedFormat)
+	}
+
+	prepared := &Prepared{Path: path, Format: format}
+
+	if !format.WhisperSupported() {
+		if p.ffmpegPath == "" {
+			return nil, fmt.Errorf("%w: %s needs transcoding, which is not configured", ErrUnsupportedFormat, format)
+		}
+		transcoded, err := p.transcode(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		prepared.cleanup = append(prepared.cleanup, transcoded)
+		prepared.Path = transcoded
+		prepared.Format = FormatM4A
+		prepared.Transcoded = true
+
+		if _, err := p.check(transcoded); err != nil {
+			prepared.Cleanup()
+			return nil, err
+		}
+	}
+
+	if err := p.measure(ctx, prepared); err != nil {
+		prepared.Cleanup()
+		return nil, err
+	}
+
+	// Whisper infers the format from the file name, so never trust the uploaded extension
+	if filepath.Ext(prepared.Path) != prepared.Format.Extension() {
+		linked, err := linkWithExtension(prepared.Path, prepared.Format.Extension())
+		if err != nil {
+			prepared.Cleanup()
+			return nil, err
+		}
+		prepared.cleanup = append(prepared.cleanup, linked)
+		prepared.Path = linked
+	}
+
+	return prepared, nil
+}
+
+// check validates the size and sniffs the format of a file
+func (p *Processor) check(path string) (Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FormatUnknown, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return FormatUnknown, fmt.Errorf("failed to stat audio file: %w", err)
+	}
+	if info.Size() == 0 {
+		return FormatUnknown, ErrEmpty
+	}
+	if p.limits.MaxBytes > 0 && info.Size() > p.limits.MaxBytes {
+		return FormatUnknown, fmt.Errorf("%w: %d bytes (max %d)", ErrTooLarge, info.Size(), p.limits.MaxBytes)
+	}
+
+	header := make([]byte, sniffHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return FormatUnknown, fmt.Errorf("failed to read audio file: %w", err)
+	}
+
+	return Sniff(header[:n]), nil
+}
+
+// measure determines duration and loudness (natively for PCM WAV, the duration natively
+// for m4a, otherwise with ffmpeg if configured) and enforces the duration and silence limits
+func (p *Processor) measure(ctx context.Context, prepared *Prepared) error {
+	duration, peak := time.Duration(0), math.NaN()
+
+	switch prepared.Format {
+	case FormatWAV, FormatM4A:
+		file, err := os.Open(prepared.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open audio file: %w", err)
+		}
+		if prepared.Format == FormatWAV {
+			if info, err := inspectWAV(file); err == nil {
+				duration, peak = info.duration, info.peakDBFS
+			}
+		} else if d, err := mp4Duration(file); err == nil {
+			duration = d
+		}
+		file.Close()
+	}
+
+	if (duration == 0 || math.IsNaN(peak)) && p.ffmpegPath != "" {
+		d, pk, err := p.probe(ctx, prepared.Path)
+		if err != nil {
+			return err
+		}
+		if duration == 0 {
+			duration = d
+		}
+		if math.IsNaN(peak) {
+			peak = pk
+		}
+	}
+
+	prepared.Duration = duration
+
+	if p.limits.MaxDuration > 0 && duration > p.limits.MaxDuration {
+		return fmt.Errorf("%w: %s (max %s)", ErrTooLong, duration.Round(time.Second), p.limits.MaxDuration)
+	}
+	if duration > 0 && duration < p.limits.MinDuration {
+		return fmt.Errorf("%w: %s long", ErrEmpty, duration)
+	}
+	if !math.IsNaN(peak) && peak < p.limits.SilenceThresholdDB {
+		return fmt.Errorf("%w: peak level %.1f dBFS", ErrSilent, peak)
+	}
+	return nil
+}
+
+var (
+	ffmpegDuration  = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+	ffmpegMaxVolume = regexp.MustCompile(`max_volume: (-?(?:\d+(?:\.\d+)?|inf)) dB`)
+)
+
+// probe runs ffmpeg's volumedetect filter and parses the duration and peak level
+func (p *Processor) probe(ctx context.Context, path string) (time.Duration, float64, error) {
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, "-hide_banner", "-nostats", "-i", path, "-af", "volumedetect", "-f", "null", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, 0, fmt.Errorf("%w: ffmpeg could not decode the recording: %v: %s", ErrUnsupportedFormat, err, lastLine(stderr.String()))
+	}
+	output := stderr.String()
+
+	var duration time.Duration
+	if m := ffmpegDuration.FindStringSubmatch(output); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		seconds, _ := strconv.ParseFloat(m[3], 64)
+		duration = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+	}
+
+	peak := math.NaN()
+	if m := ffmpegMaxVolume.FindStringSubmatch(output); m != nil {
+		if m[1] == "-inf" {
+			peak = math.Inf(-1)
+		} else if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+			peak = v
+		}
+	}
+
+	return duration, peak, nil
+}
+
+// transcode converts a recording to mono AAC in an m4a container
+func (p *Processor) transcode(ctx context.Context, path string) (string, error) {
+	out, err := os.CreateTemp("", "audio-transcoded-*.m4a")
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcoding output: %w", err)
+	}
+	out.Close()
+
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, "-hide_banner", "-nostats", "-y", "-i", path, "-vn", "-ac", "1", "-c:a", "aac", "-b:a", "64k", out.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("%w: transcoding failed: %v: %s", ErrUnsupportedFormat, err, lastLine(stderr.String()))
+	}
+	return out.Name(), nil
+}
+
+// linkWithExtension makes the file available under a name with the given extension
+func linkWithExtension(path string, ext string) (string, error) {
+	linked := strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	if linked == path {
+		return path, nil
+	}
+	if err := os.Link(path, linked); err != nil {
+		return "", fmt.Errorf("failed to rename audio file: %w", err)
+	}
+	return linked, nil
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
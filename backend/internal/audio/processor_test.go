@@ -0,0 +1,181 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// wavFile builds a 16-bit mono PCM WAV with a sine wave of the given amplitude (0-1)
+func wavFile(sampleRate int, duration time.Duration, amplitude float64) []byte {
+	samples := int(float64(sampleRate) * duration.Seconds())
+	var data bytes.Buffer
+	for i := 0; i < samples; i++ {
+		v := amplitude * 32767 * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate))
+		binary.Write(&data, binary.LittleEndian, int16(v))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+data.Len()))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // mono
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))   // sample rate
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2)) // byte rate
+	binary.Write(&buf, binary.LittleEndian, uint16(2))            // block align
+	binary.Write(&buf, binary.LittleEndian, uint16(16))           // bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+func writeTemp(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   Format
+	}{
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00"), FormatM4A},
+		{"mp4", []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00"), FormatM4A},
+		{"3gp", []byte("\x00\x00\x00\x18ftyp3gp4\x00\x00\x00\x00"), Format3GP},
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), FormatWAV},
+		{"ogg", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00"), FormatOGG},
+		{"webm", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86, 0x81}, FormatWebM},
+		{"mp3 id3", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), FormatMP3},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x64}, FormatMP3},
+		{"amr", []byte("#!AMR\n"), FormatAMR},
+		{"text", []byte("hello, this is not audio"), FormatUnknown},
+		{"riff avi", []byte("RIFF\x24\x00\x00\x00AVI LIST"), FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sniff(tt.header); got != tt.want {
+				t.Errorf("Sniff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareAcceptsSpeechWAV(t *testing.T) {
+	path := writeTemp(t, "upload.bin", wavFile(8000, 2*time.Second, 0.5))
+
+	prepared, err := NewProcessor(DefaultLimits(), "").Prepare(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer prepared.Cleanup()
+
+	if prepared.Format != FormatWAV || prepared.Duration != 2*time.Second {
+		t.Errorf("unexpected result: %+v", prepared)
+	}
+	if filepath.Ext(prepared.Path) != ".wav" {
+		t.Errorf("path %q should have the sniffed extension", prepared.Path)
+	}
+
+	prepared.Cleanup()
+	if _, err := os.Stat(prepared.Path); !os.IsNotExist(err) {
+		t.Error("Cleanup should remove the renamed file")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("Cleanup must not remove the original upload")
+	}
+}
+
+// m4aFile builds an m4a with a movie header of the given duration and an empty media box
+func m4aFile(duration time.Duration, version byte) []byte {
+	box := func(boxType string, payload []byte) []byte {
+		b := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+		return append(append(b, boxType...), payload...)
+	}
+	const timescale = 1000
+	mvhd := []byte{version, 0, 0, 0}
+	if version == 1 {
+		mvhd = append(mvhd, make([]byte, 16)...)
+		mvhd = binary.BigEndian.AppendUint32(mvhd, timescale)
+		mvhd = binary.BigEndian.AppendUint64(mvhd, uint64(duration.Milliseconds()))
+	} else {
+		mvhd = append(mvhd, make([]byte, 8)...)
+		mvhd = binary.BigEndian.AppendUint32(mvhd, timescale)
+		mvhd = binary.BigEndian.AppendUint32(mvhd, uint32(duration.Milliseconds()))
+	}
+	mvhd = append(mvhd, make([]byte, 80)...) // rate, volume, matrix and the rest
+
+	file := box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	file = append(file, box("mdat", make([]byte, 64))...)
+	return append(file, box("moov", box("mvhd", mvhd))...)
+}
+
+func TestPrepareMeasuresM4AWithoutFFmpeg(t *testing.T) {
+	for _, version := range []byte{0, 1} {
+		path := writeTemp(t, "upload.bin", m4aFile(90*time.Second, version))
+		prepared, err := NewProcessor(DefaultLimits(), "").Prepare(context.Background(), path)
+		if err != nil {
+			t.Fatalf("mvhd version %d: %v", version, err)
+		}
+		prepared.Cleanup()
+		if prepared.Duration != 90*time.Second {
+			t.Errorf("mvhd version %d: duration = %s, want 1m30s", version, prepared.Duration)
+		}
+	}
+
+	// Without a movie header the duration is unknown and the recording is not rejected
+	path := writeTemp(t, "upload.m4a", []byte("\x00\x00\x00\x18ftypM4A \x00\x00\x00\x00rest"))
+	prepared, err := NewProcessor(DefaultLimits(), "").Prepare(context.Background(), path)
+	if err != nil {
+		t.Fatalf("m4a without mvhd: %v", err)
+	}
+	defer prepared.Cleanup()
+	if prepared.Duration != 0 {
+		t.Errorf("m4a without mvhd: duration = %s, want unknown", prepared.Duration)
+	}
+}
+
+func TestPrepareRejections(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxBytes = 64 << 10
+	limits.MaxDuration = 3 * time.Second
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    error
+	}{
+		{"empty file", nil, ErrEmpty},
+		{"too short", wavFile(8000, 100*time.Millisecond, 0.5), ErrEmpty},
+		{"silent", wavFile(8000, time.Second, 0), ErrSilent},
+		{"very quiet", wavFile(8000, time.Second, 0.0001), ErrSilent},
+		{"too long", wavFile(2000, 4*time.Second, 0.5), ErrTooLong},
+		{"too large", wavFile(8000, 5*time.Second, 0.5), ErrTooLarge},
+		{"m4a too long", m4aFile(4*time.Second, 0), ErrTooLong},
+		{"m4a too short", m4aFile(100*time.Millisecond, 1), ErrEmpty},
+		{"not audio", []byte("this is a text file pretending to be audio"), ErrUnsupportedFormat},
+		{"3gp without ffmpeg", []byte("\x00\x00\x00\x18ftyp3gp4\x00\x00\x00\x00rest"), ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, "upload.m4a", tt.content)
+			_, err := NewProcessor(limits, "").Prepare(context.Background(), path)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Prepare() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
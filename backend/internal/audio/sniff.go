@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"bytes"
+)
+
+// Format is an audio container format detected from the file content
+type Format string
+
+// Detected formats. Whisper accepts the first group; the second needs transcoding.
+const (
+	FormatM4A  Format = "m4a"
+	FormatMP3  Format = "mp3"
+	FormatWAV  Format = "wav"
+	FormatOGG  Format = "ogg"
+	FormatWebM Format = "webm"
+
+	Format3GP Format = "3gp"
+	FormatAMR Format = "amr"
+	FormatCAF Format = "caf"
+
+	FormatUnknown Format = ""
+)
+
+// WhisperSupported reports whether Whisper accepts the format as is
+func (f Format) WhisperSupported() bool {
+	switch f {
+	case FormatM4A, FormatMP3, FormatWAV, FormatOGG, FormatWebM:
+		return true
+	}
+	return false
+}
+
+// Extension returns the file extension Whisper expects for the format
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// sniffHeaderSize is the number of bytes needed by Sniff
+const sniffHeaderSize = 16
+
+// Sniff detects the audio format from the first bytes of a file
+func Sniff(header []byte) Format {
+	switch {
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		brand := string(header[8:11])
+		if brand == "3gp" || brand == "3g2" {
+			return Format3GP
+		}
+		return FormatM4A
+	case len(header) >= 12 && bytes.HasPrefix(header, []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return FormatWAV
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return FormatOGG
+	case bytes.HasPrefix(header, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return FormatWebM
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return FormatMP3
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 != 0:
+		// MPEG audio frame sync with a valid layer
+		return FormatMP3
+	case bytes.HasPrefix(header, []byte("#!AMR")):
+		return FormatAMR
+	case bytes.HasPrefix(header, []byte("caff")):
+		return FormatCAF
+	}
+	return FormatUnknown
+}
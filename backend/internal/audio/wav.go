@@ -0,0 +1,122 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// wavInfo describes an uncompressed PCM WAV file
+type wavInfo struct {
+	duration time.Duration
+	// peakDBFS is the loudest sample relative to full scale, or NaN if unknown
+	peakDBFS float64
+}
+
+var errUnsupportedWAV = errors.New("unsupported WAV encoding")
+
+// inspectWAV reads the duration from the header and, for 8/16-bit PCM, the peak level
+func inspectWAV(r io.ReadSeeker) (*wavInfo, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, err
+	}
+
+	var (
+		audioFormat   uint16
+		channels      uint16
+		byteRate      uint32
+		bitsPerSample uint16
+		haveFmt       bool
+	)
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, err
+		}
+		id := string(chunk[:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		switch id {
+		case "fmt ":
+			var fmtChunk [16]byte
+			if size < 16 {
+				return nil, errUnsupportedWAV
+			}
+			if _, err := io.ReadFull(r, fmtChunk[:]); err != nil {
+				return nil, err
+			}
+			audioFormat = binary.LittleEndian.Uint16(fmtChunk[0:])
+			channels = binary.LittleEndian.Uint16(fmtChunk[2:])
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:])
+			bitsPerSample = binary.LittleEndian.Uint16(fmtChunk[14:])
+			haveFmt = true
+			if _, err := r.Seek(size-16+size%2, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		case "data":
+			if !haveFmt || byteRate == 0 || channels == 0 {
+				return nil, errUnsupportedWAV
+			}
+			info := &wavInfo{
+				duration: time.Duration(float64(size) / float64(byteRate) * float64(time.Second)),
+				peakDBFS: math.NaN(),
+			}
+			if audioFormat == 1 && (bitsPerSample == 8 || bitsPerSample == 16) {
+				peak, err := pcmPeak(io.LimitReader(r, size), bitsPerSample)
+				if err != nil {
+					return nil, err
+				}
+				info.peakDBFS = peak
+			}
+			return info, nil
+		default:
+			if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// pcmPeak returns the peak level of PCM samples in dBFS (-Inf for digital silence)
+func pcmPeak(r io.Reader, bitsPerSample uint16) (float64, error) {
+	buf := make([]byte, 32*1024)
+	var peak, fullScale float64
+	if bitsPerSample == 8 {
+		fullScale = 128
+	} else {
+		fullScale = 32768
+	}
+
+	var carry []byte
+	for {
+		n, err := r.Read(buf)
+		data := append(carry, buf[:n]...)
+		carry = nil
+		if bitsPerSample == 8 {
+			for _, b := range data {
+				peak = math.Max(peak, math.Abs(float64(int(b)-128)))
+			}
+		} else {
+			even := len(data) &^ 1
+			for i := 0; i < even; i += 2 {
+				sample := int16(binary.LittleEndian.Uint16(data[i:]))
+				peak = math.Max(peak, math.Abs(float64(sample)))
+			}
+			if even < len(data) {
+				carry = []byte{data[even]}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return 20 * math.Log10(peak/fullScale), nil
+}
@@ -0,0 +1,1194 @@
+package fakes
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+)
+
+// ExpenseRepository is an in-memory repositories.ExpenseRepository
+type ExpenseRepository struct {
+	mu       sync.Mutex
+	expenses map[string]*models.Expense
+	events   []*models.ExpenseEvent
+
+	// CreateErr, if set, is returned by Create
+	CreateErr error
+	// ExportErr, if set, is returned by Export once the expenses were passed on, like a
+	// connection lost halfway through the cursor
+	ExportErr error
+	// Recordings, if set, provides the transcriptions searched by List
+	Recordings *RecordingRepository
+	// Matches, if set, provides the reconciliation matches whose imported transactions
+	// Summarize leaves out
+	Matches *ReconciliationRepository
+}
+
+var _ repositories.ExpenseRepository = (*ExpenseRepository)(nil)
+
+// NewExpenseRepository creates an empty in-memory expense repository
+func NewExpenseRepository() *ExpenseRepository {
+	return &ExpenseRepository{expenses: make(map[string]*models.Expense)}
+}
+
+func (r *ExpenseRepository) Create(ctx context.Context, expense *models.Expense) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.CreateErr != nil {
+		return r.CreateErr
+	}
+	if _, exists := r.expenses[expense.ID]; exists {
+		return fmt.Errorf("failed to insert expense: duplicate id %s", expense.ID)
+	}
+	r.store(expense)
+	return nil
+}
+
+// store saves a copy of an expense with the PostgreSQL defaults and records its creation;
+// the caller holds the lock
+func (r *ExpenseRepository) store(expense *models.Expense) {
+	stored := *expense
+	if stored.UserID == "" {
+		stored.UserID = models.DefaultUserID
+	}
+	if stored.Source == "" {
+		stored.Source = models.ExpenseSourceVoice
+	}
+	stored.Tags = addTags(nil, expense.Tags)
+	r.expenses[expense.ID] = &stored
+	r.record(expense.ID, models.ExpenseEventCreate, stored.UserID, nil, &stored)
+}
+
+// record appends an event to the history of an expense; the caller holds the lock
+func (r *ExpenseRepository) record(id string, action string, actor string, before *models.Expense, after *models.Expense) {
+	event := &models.ExpenseEvent{
+		ID:        int64(len(r.events) + 1),
+		ExpenseID: id,
+		Action:    action,
+		Actor:     actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	if before != nil {
+		event.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		event.After, _ = json.Marshal(after)
+	}
+	r.events = append(r.events, event)
+}
+
+// change applies a change to a stored expense and records it, like the PostgreSQL
+// repository; the caller holds the lock
+func (r *ExpenseRepository) change(id string, action string, actor string, apply func(expense *models.Expense)) (*models.Expense, error) {
+	expense, ok := r.expenses[id]
+	if !ok {
+		return nil, repositories.ErrExpenseNotFound
+	}
+	if action == models.ExpenseEventRestore && expense.DeletedAt == nil {
+		return nil, repositories.ErrExpenseNotDeleted
+	}
+	if action != models.ExpenseEventRestore && expense.DeletedAt != nil {
+		return nil, repositories.ErrExpenseNotFound
+	}
+
+	before := *expense
+	apply(expense)
+	after := *expense
+	r.record(id, action, actor, &before, &after)
+	return &after, nil
+}
+
+// checkUnclaimed returns ErrExpenseClaimed for an expense claimed in a submitted report, like
+// the PostgreSQL repository's updates of claimable fields
+func (r *ExpenseRepository) checkUnclaimed(id string) error {
+	expense, ok := r.expenses[id]
+	if ok && expense.DeletedAt == nil && expense.ReimbursementStatus != "" && expense.ReimbursementStatus != models.ReimbursementPending {
+		return repositories.ErrExpenseClaimed
+	}
+	return nil
+}
+
+func (r *ExpenseRepository) Import(ctx context.Context, expenses []*models.Expense) ([]*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.CreateErr != nil {
+		return nil, r.CreateErr
+	}
+
+	imported := make(map[string]bool)
+	for _, expense := range r.expenses {
+		if expense.ExternalID != "" {
+			imported[expense.UserID+"\x00"+expense.ExternalID] = true
+		}
+	}
+
+	var created []*models.Expense
+	for _, expense := range expenses {
+		key := expense.UserID + "\x00" + expense.ExternalID
+		if expense.ExternalID != "" && imported[key] {
+			continue
+		}
+		imported[key] = true
+		r.store(expense)
+		created = append(created, expense)
+	}
+	return created, nil
+}
+
+func (r *ExpenseRepository) FindByID(ctx context.Context, id string) (*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expense, ok := r.expenses[id]
+	if !ok || expense.DeletedAt != nil {
+		return nil, repositories.ErrExpenseNotFound
+	}
+	found := *expense
+	return &found, nil
+}
+
+func (r *ExpenseRepository) FindByIDWithDeleted(ctx context.Context, id string) (*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expense, ok := r.expenses[id]
+	if !ok {
+		return nil, repositories.ErrExpenseNotFound
+	}
+	found := *expense
+	return &found, nil
+}
+
+func (r *ExpenseRepository) List(ctx context.Context, params models.ListExpensesParams) (*models.PaginatedExpenses, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Same defaults as the PostgreSQL repository
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PerPage < 1 || params.PerPage > 100 {
+		params.PerPage = 10
+	}
+	if params.OrderBy != "purchased_at" && params.OrderBy != "created_at" {
+		params.OrderBy = "created_at"
+	}
+	if params.OrderDir != "asc" && params.OrderDir != "desc" {
+		params.OrderDir = "desc"
+	}
+	if params.Query != "" {
+		params.OrderBy, params.OrderDir = repositories.OrderByRank, "desc"
+	}
+
+	userID := params.UserID
+	if userID == "" {
+		userID = models.DefaultUserID
+	}
+
+	var matching []*models.Expense
+	for _, expense := range r.expenses {
+		if expense.DeletedAt != nil || expense.UserID != userID {
+			continue
+		}
+		if params.Status != "" && expense.Status != params.Status {
+			continue
+		}
+		if params.PossibleDuplicate && expense.PossibleDuplicateOf == nil {
+			continue
+		}
+		if params.MerchantID != "" && (expense.MerchantID == nil || *expense.MerchantID != params.MerchantID) {
+			continue
+		}
+		if params.ReimbursementStatus != "" && expense.ReimbursementStatus != params.ReimbursementStatus {
+			continue
+		}
+		if !hasTags(expense, params.TagFilter) {
+			continue
+		}
+		if params.From != nil && expense.PurchasedAt.Before(*params.From) || params.To != nil && expense.PurchasedAt.After(*params.To) {
+			continue
+		}
+		found := *expense
+		if params.Query != "" {
+			found.Match = r.search(params.Query, &found)
+			if found.Match == nil {
+				continue
+			}
+		}
+		matching = append(matching, &found)
+	}
+
+	// position is the sort key of an expense, like the cursor's
+	position := func(expense *models.Expense) repositories.ListPosition {
+		switch params.OrderBy {
+		case repositories.OrderByRank:
+			return repositories.ListPosition{Rank: expense.Match.Rank, ID: expense.ID}
+		case "purchased_at":
+			return repositories.ListPosition{Value: expense.PurchasedAt, ID: expense.ID}
+		default:
+			return repositories.ListPosition{Value: expense.CreatedAt, ID: expense.ID}
+		}
+	}
+	// before reports whether a comes first in the list order
+	before := func(a, b repositories.ListPosition) bool {
+		c := a.Value.Compare(b.Value)
+		if c == 0 {
+			c = cmp.Compare(a.Rank, b.Rank)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if params.OrderDir == "desc" {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return before(position(matching[i]), position(matching[j]))
+	})
+
+	result := &models.PaginatedExpenses{PerPage: params.PerPage}
+	if params.IncludeTotal {
+		total := len(matching)
+		totalPages := (total + params.PerPage - 1) / params.PerPage
+		result.Total = &total
+		result.TotalPages = &totalPages
+	}
+
+	start := 0
+	if params.Cursor != "" {
+		after, err := repositories.DecodeListCursor(params.Cursor, params.OrderBy, params.OrderDir)
+		if err != nil {
+			return nil, err
+		}
+		// Skip to the first expense after the cursor's position
+		for start < len(matching) && !before(*after, position(matching[start])) {
+			start++
+		}
+	} else {
+		result.Page = params.Page
+		start = min((params.Page-1)*params.PerPage, len(matching))
+	}
+	end := min(start+params.PerPage, len(matching))
+
+	result.Data = matching[start:end]
+	if end < len(matching) {
+		result.NextCursor = repositories.EncodeListCursor(matching[end-1], params.OrderBy, params.OrderDir)
+	}
+	return result, nil
+}
+
+func (r *ExpenseRepository) Export(ctx context.Context, params models.ListExpensesParams, fn func(*models.Expense) error) error {
+	// Pages through List, releasing the lock before calling fn like the cursor
+	params.Page, params.PerPage, params.Cursor = 1, 100, ""
+	for {
+		result, err := r.List(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, expense := range result.Data {
+			if err := fn(expense); err != nil {
+				return err
+			}
+		}
+		if result.NextCursor == "" {
+			return r.ExportErr
+		}
+		params.Cursor = result.NextCursor
+	}
+}
+
+func (r *ExpenseRepository) UpdateStatus(ctx context.Context, id string, status string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.change(id, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+		expense.Status = status
+	})
+	return err
+}
+
+func (r *ExpenseRepository) Update(ctx context.Context, expense *models.Expense, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnclaimed(expense.ID); err != nil {
+		return err
+	}
+	_, err := r.change(expense.ID, models.ExpenseEventUpdate, actor, func(stored *models.Expense) {
+		stored.UnitPrice = expense.UnitPrice
+		stored.Quantity = expense.Quantity
+		stored.Unit = expense.Unit
+		stored.Description = expense.Description
+	})
+	return err
+}
+
+func (r *ExpenseRepository) UpdateReimbursable(ctx context.Context, id string, reimbursable bool, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnclaimed(id); err != nil {
+		return err
+	}
+	_, err := r.change(id, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+		expense.Reimbursable = reimbursable
+		expense.ReimbursementStatus = ""
+		if reimbursable {
+			expense.ReimbursementStatus = models.ReimbursementPending
+		}
+		expense.ReportID = nil
+	})
+	return err
+}
+
+func (r *ExpenseRepository) FindLatest(ctx context.Context, userID string, limit int) ([]*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest []*models.Expense
+	for _, expense := range r.expenses {
+		if expense.UserID != userID || expense.DeletedAt != nil || expense.Source == models.ExpenseSourceImport {
+			continue
+		}
+		found := *expense
+		latest = append(latest, &found)
+	}
+	sort.Slice(latest, func(i, j int) bool {
+		if !latest[i].CreatedAt.Equal(latest[j].CreatedAt) {
+			return latest[i].CreatedAt.After(latest[j].CreatedAt)
+		}
+		return latest[i].ID > latest[j].ID
+	})
+	if len(latest) > limit {
+		latest = latest[:limit]
+	}
+	return latest, nil
+}
+
+func (r *ExpenseRepository) FindRecent(ctx context.Context, userID string, from time.Time, to time.Time) ([]*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var recent []*models.Expense
+	for _, expense := range r.expenses {
+		if expense.UserID != userID || expense.DeletedAt != nil || expense.PurchasedAt.Before(from) || expense.PurchasedAt.After(to) {
+			continue
+		}
+		found := *expense
+		recent = append(recent, &found)
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].PurchasedAt.After(recent[j].PurchasedAt) })
+	return recent, nil
+}
+
+func (r *ExpenseRepository) UpdatePossibleDuplicate(ctx context.Context, id string, duplicateOf *string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.change(id, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+		expense.PossibleDuplicateOf = duplicateOf
+	})
+	return err
+}
+
+func (r *ExpenseRepository) Delete(ctx context.Context, id string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnclaimed(id); err != nil {
+		return err
+	}
+	_, err := r.change(id, models.ExpenseEventDelete, actor, func(expense *models.Expense) {
+		now := time.Now().UTC()
+		expense.DeletedAt = &now
+		expense.ReportID = nil
+	})
+	if err != nil {
+		return err
+	}
+	// Same as the PostgreSQL repository
+	for _, expense := range r.expenses {
+		if expense.DeletedAt == nil && expense.PossibleDuplicateOf != nil && *expense.PossibleDuplicateOf == id {
+			r.change(expense.ID, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+				expense.PossibleDuplicateOf = nil
+			})
+		}
+	}
+	return nil
+}
+
+func (r *ExpenseRepository) Restore(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The duplicates unflagged by the last deletion are updated after it
+	deletion := -1
+	for i, event := range r.events {
+		if event.ExpenseID == id && event.Action == models.ExpenseEventDelete {
+			deletion = i
+		}
+	}
+	events := slices.Clone(r.events[deletion+1:])
+
+	restored, err := r.change(id, models.ExpenseEventRestore, actor, func(expense *models.Expense) {
+		expense.DeletedAt = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Same as the PostgreSQL repository
+	for _, event := range events {
+		var before, after models.Expense
+		json.Unmarshal(event.Before, &before)
+		json.Unmarshal(event.After, &after)
+		if event.Action != models.ExpenseEventUpdate || before.PossibleDuplicateOf == nil || *before.PossibleDuplicateOf != id || after.PossibleDuplicateOf != nil {
+			continue
+		}
+		if duplicate := r.expenses[event.ExpenseID]; duplicate.DeletedAt == nil && duplicate.PossibleDuplicateOf == nil {
+			r.change(duplicate.ID, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+				expense.PossibleDuplicateOf = &id
+			})
+		}
+	}
+	return restored, nil
+}
+
+func (r *ExpenseRepository) History(ctx context.Context, id string) ([]*models.ExpenseEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.expenses[id]; !ok {
+		return nil, repositories.ErrExpenseNotFound
+	}
+	events := []*models.ExpenseEvent{}
+	for _, event := range r.events {
+		if event.ExpenseID == id {
+			found := *event
+			events = append(events, &found)
+		}
+	}
+	return events, nil
+}
+
+func (r *ExpenseRepository) AddTags(ctx context.Context, id string, tags []string, actor string) (*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.change(id, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+		expense.Tags = addTags(expense.Tags, tags)
+	})
+}
+
+func (r *ExpenseRepository) RemoveTag(ctx context.Context, id string, tag string, actor string) (*models.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.change(id, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+		expense.Tags = slices.DeleteFunc(slices.Clone(expense.Tags), func(t string) bool { return t == tag })
+		if len(expense.Tags) == 0 {
+			expense.Tags = nil
+		}
+	})
+}
+
+func (r *ExpenseRepository) Summarize(ctx context.Context, params models.SummaryParams) (*models.ExpenseSummary, error) {
+	var matches []models.ReconciliationMatch
+	if r.Matches != nil {
+		matches, _ = r.Matches.ListMatches(ctx, params.UserID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// An imported transaction matched to a recorded expense is the same purchase
+	matched := make(map[string]bool)
+	for _, match := range matches {
+		matched[match.TransactionID] = true
+	}
+
+	timezone := params.Timezone
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize expenses: %w", err)
+	}
+
+	summary := &models.ExpenseSummary{GroupBy: params.GroupBy, Timezone: timezone, From: params.From, To: params.To}
+	var overall models.SummaryGroup
+	groups := make(map[string]*models.SummaryGroup)
+	for _, expense := range r.expenses {
+		if expense.UserID != params.UserID || expense.DeletedAt != nil || matched[expense.ID] {
+			continue
+		}
+		if params.From != nil && expense.PurchasedAt.Before(*params.From) || params.To != nil && expense.PurchasedAt.After(*params.To) {
+			continue
+		}
+		if params.MerchantID != "" && (expense.MerchantID == nil || *expense.MerchantID != params.MerchantID) {
+			continue
+		}
+		if params.Query != "" && r.search(params.Query, expense) == nil {
+			continue
+		}
+		if len(params.Units) > 0 && !slices.Contains(params.Units, expense.Unit) {
+			continue
+		}
+		if !hasTags(expense, params.TagFilter) {
+			continue
+		}
+		accumulate(&overall, expense)
+
+		var key, label string
+		switch params.GroupBy {
+		case models.SummaryGroupByTag:
+			// Like the PostgreSQL join, an expense counts in the group of each of its tags
+			tags := expense.Tags
+			if len(tags) == 0 {
+				tags = []string{""}
+			}
+			for _, tag := range tags {
+				group, ok := groups[tag]
+				if !ok {
+					group = &models.SummaryGroup{Key: tag, Label: tag}
+					groups[tag] = group
+				}
+				accumulate(group, expense)
+			}
+			continue
+		case models.SummaryGroupByMerchant:
+			if expense.MerchantID != nil {
+				key = *expense.MerchantID
+			}
+			label = expense.Merchant
+		case models.SummaryGroupByDay:
+			key = expense.PurchasedAt.In(location).Format("2006-01-02")
+			label = key
+		default:
+			continue
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &models.SummaryGroup{Key: key, Label: label}
+			groups[key] = group
+		}
+		accumulate(group, expense)
+	}
+	summary.Total, summary.Count, summary.Min, summary.Max = overall.Total, overall.Count, overall.Min, overall.Max
+
+	for _, group := range groups {
+		summary.Groups = append(summary.Groups, *group)
+	}
+	// Same order as the PostgreSQL repository: days in order, merchants by total
+	sort.Slice(summary.Groups, func(i, j int) bool {
+		a, b := summary.Groups[i], summary.Groups[j]
+		if params.GroupBy == models.SummaryGroupByDay {
+			return a.Key < b.Key
+		}
+		if a.Total == b.Total {
+			return a.Label < b.Label
+		}
+		return a.Total > b.Total
+	})
+	return summary, nil
+}
+
+// hasTags reports whether an expense matches a tag filter
+func hasTags(expense *models.Expense, filter models.TagFilter) bool {
+	if len(filter.Tags) == 0 {
+		return true
+	}
+	for _, tag := range filter.Tags {
+		has := slices.Contains(expense.Tags, tag)
+		if has && filter.TagMatch != models.TagMatchAll {
+			return true
+		}
+		if !has && filter.TagMatch == models.TagMatchAll {
+			return false
+		}
+	}
+	return filter.TagMatch == models.TagMatchAll
+}
+
+// addTags returns a copy of tags with the new ones added, in name order like the
+// PostgreSQL repository returns them
+func addTags(tags []string, added []string) []string {
+	var merged []string
+	for _, tag := range slices.Concat(tags, added) {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	slices.Sort(merged)
+	return merged
+}
+
+// accumulate adds an expense to the totals of a summary group
+func accumulate(group *models.SummaryGroup, expense *models.Expense) {
+	total := expense.Total()
+	if group.Count == 0 || total < group.Min {
+		group.Min = total
+	}
+	if group.Count == 0 || total > group.Max {
+		group.Max = total
+	}
+	group.Total += total
+	group.Count++
+}
+
+// All returns every stored expense that is not deleted, in no particular order
+func (r *ExpenseRepository) All() []*models.Expense {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var all []*models.Expense
+	for _, expense := range r.expenses {
+		if expense.DeletedAt != nil {
+			continue
+		}
+		found := *expense
+		all = append(all, &found)
+	}
+	return all
+}
+
+// RecordingRepository is an in-memory repositories.RecordingRepository
+type RecordingRepository struct {
+	mu         sync.Mutex
+	recordings map[string]*models.Recording
+}
+
+var _ repositories.RecordingRepository = (*RecordingRepository)(nil)
+
+// NewRecordingRepository creates an empty in-memory recording repository
+func NewRecordingRepository() *RecordingRepository {
+	return &RecordingRepository{recordings: make(map[string]*models.Recording)}
+}
+
+func (r *RecordingRepository) Create(ctx context.Context, recording *models.Recording) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *recording
+	r.recordings[recording.ID] = &stored
+	return nil
+}
+
+// Count returns the number of stored recordings
+func (r *RecordingRepository) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.recordings)
+}
+
+// Get returns a stored recording, or nil
+func (r *RecordingRepository) Get(id string) *models.Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recording, ok := r.recordings[id]
+	if !ok {
+		return nil
+	}
+	found := *recording
+	return &found
+}
+
+// SettingsRepository is an in-memory repositories.SettingsRepository
+type SettingsRepository struct {
+	mu       sync.Mutex
+	settings map[string]*models.UserSettings
+}
+
+var _ repositories.SettingsRepository = (*SettingsRepository)(nil)
+
+// NewSettingsRepository creates an empty in-memory settings repository
+func NewSettingsRepository() *SettingsRepository {
+	return &SettingsRepository{settings: make(map[string]*models.UserSettings)}
+}
+
+func (r *SettingsRepository) Get(ctx context.Context, userID string) (*models.UserSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	settings, ok := r.settings[userID]
+	if !ok {
+		return nil, nil
+	}
+	found := *settings
+	return &found, nil
+}
+
+func (r *SettingsRepository) Upsert(ctx context.Context, settings *models.UserSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *settings
+	r.settings[settings.UserID] = &stored
+	return nil
+}
+
+// IdempotencyRepository is an in-memory repositories.IdempotencyRepository
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+var _ repositories.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+// NewIdempotencyRepository creates an empty in-memory idempotency key repository
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+func (r *IdempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyRecord, reclaimBefore time.Time, abandonedBefore time.Time) (*models.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := record.UserID + "\x00" + record.Key
+	if existing, ok := r.records[id]; ok {
+		expired := existing.CreatedAt.Before(reclaimBefore)
+		abandoned := !existing.Completed() && existing.CreatedAt.Before(abandonedBefore)
+		if !expired && !abandoned {
+			found := *existing
+			return &found, false, nil
+		}
+	}
+	stored := *record
+	r.records[id] = &stored
+	return nil, true, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[userID+"\x00"+key]
+	if !ok {
+		return nil
+	}
+	completedAt := time.Now().UTC()
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = append([]byte(nil), body...)
+	record.CompletedAt = &completedAt
+	return nil
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, userID string, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, userID+"\x00"+key)
+	return nil
+}
+
+// Get returns a stored record, or nil
+func (r *IdempotencyRepository) Get(userID string, key string) *models.IdempotencyRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[userID+"\x00"+key]
+	if !ok {
+		return nil
+	}
+	found := *record
+	return &found
+}
+
+// ReceiptRepository is an in-memory repositories.ReceiptRepository
+type ReceiptRepository struct {
+	mu       sync.Mutex
+	receipts map[string]*models.Receipt
+}
+
+var _ repositories.ReceiptRepository = (*ReceiptRepository)(nil)
+
+// NewReceiptRepository creates an empty in-memory receipt repository
+func NewReceiptRepository() *ReceiptRepository {
+	return &ReceiptRepository{receipts: make(map[string]*models.Receipt)}
+}
+
+func (r *ReceiptRepository) Create(ctx context.Context, receipt *models.Receipt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *receipt
+	r.receipts[receipt.ID] = &stored
+	return nil
+}
+
+// Count returns the number of stored receipts
+func (r *ReceiptRepository) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.receipts)
+}
+
+func (r *ReceiptRepository) FindByID(ctx context.Context, id string) (*models.Receipt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	receipt, ok := r.receipts[id]
+	if !ok {
+		return nil, repositories.ErrReceiptNotFound
+	}
+	found := *receipt
+	return &found, nil
+}
+
+// MerchantRepository is an in-memory repositories.MerchantRepository
+type MerchantRepository struct {
+	mu        sync.Mutex
+	merchants map[string]*models.Merchant
+	// aliases maps user ID and alias to a merchant ID
+	aliases map[string]string
+}
+
+var _ repositories.MerchantRepository = (*MerchantRepository)(nil)
+
+// NewMerchantRepository creates an empty in-memory merchant repository
+func NewMerchantRepository() *MerchantRepository {
+	return &MerchantRepository{
+		merchants: make(map[string]*models.Merchant),
+		aliases:   make(map[string]string),
+	}
+}
+
+func (r *MerchantRepository) FindOrCreate(ctx context.Context, merchant *models.Merchant) (*models.Merchant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	aliasKey := merchant.UserID + "\x00" + merchant.NormalizedName
+	if id, ok := r.aliases[aliasKey]; ok {
+		return r.merchantLocked(id), nil
+	}
+	stored := *merchant
+	stored.Aliases = []string{merchant.NormalizedName}
+	r.merchants[merchant.ID] = &stored
+	r.aliases[aliasKey] = merchant.ID
+	return r.merchantLocked(merchant.ID), nil
+}
+
+func (r *MerchantRepository) FindByID(ctx context.Context, id string) (*models.Merchant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.merchants[id]; !ok {
+		return nil, repositories.ErrMerchantNotFound
+	}
+	return r.merchantLocked(id), nil
+}
+
+func (r *MerchantRepository) List(ctx context.Context, userID string) ([]*models.Merchant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var merchants []*models.Merchant
+	for id, merchant := range r.merchants {
+		if merchant.UserID == userID {
+			merchants = append(merchants, r.merchantLocked(id))
+		}
+	}
+	sort.Slice(merchants, func(i, j int) bool { return merchants[i].Name < merchants[j].Name })
+	return merchants, nil
+}
+
+func (r *MerchantRepository) AddAlias(ctx context.Context, merchantID string, userID string, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	aliasKey := userID + "\x00" + alias
+	if owner, ok := r.aliases[aliasKey]; ok {
+		if owner != merchantID {
+			return repositories.ErrMerchantAliasTaken
+		}
+		return nil
+	}
+	r.aliases[aliasKey] = merchantID
+	merchant := r.merchants[merchantID]
+	merchant.Aliases = append(merchant.Aliases, alias)
+	sort.Strings(merchant.Aliases)
+	return nil
+}
+
+// merchantLocked returns a copy of a stored merchant; r.mu must be held
+func (r *MerchantRepository) merchantLocked(id string) *models.Merchant {
+	found := *r.merchants[id]
+	found.Aliases = append([]string(nil), found.Aliases...)
+	return &found
+}
+
+// ReconciliationRepository is an in-memory repositories.ReconciliationRepository over the
+// expenses of an in-memory ExpenseRepository
+type ReconciliationRepository struct {
+	mu       sync.Mutex
+	expenses *ExpenseRepository
+	// matches maps user ID to the matches of the user, keyed by expense ID
+	matches map[string]map[string]models.ReconciliationMatch
+	// rejections maps user ID to the matches the user deleted
+	rejections map[string][]models.ReconciliationRejection
+}
+
+var _ repositories.ReconciliationRepository = (*ReconciliationRepository)(nil)
+
+// NewReconciliationRepository creates an empty in-memory reconciliation repository for the
+// given expenses
+func NewReconciliationRepository(expenses *ExpenseRepository) *ReconciliationRepository {
+	return &ReconciliationRepository{
+		expenses:   expenses,
+		matches:    make(map[string]map[string]models.ReconciliationMatch),
+		rejections: make(map[string][]models.ReconciliationRejection),
+	}
+}
+
+func (r *ReconciliationRepository) SaveMatches(ctx context.Context, userID string, matches []models.ReconciliationMatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.matches[userID] == nil {
+		r.matches[userID] = make(map[string]models.ReconciliationMatch)
+	}
+	for _, match := range matches {
+		if _, exists := r.matches[userID][match.ExpenseID]; !exists {
+			r.matches[userID][match.ExpenseID] = match
+		}
+	}
+	return nil
+}
+
+func (r *ReconciliationRepository) ListMatches(ctx context.Context, userID string) ([]models.ReconciliationMatch, error) {
+	r.mu.Lock()
+	var stored []models.ReconciliationMatch
+	for _, match := range r.matches[userID] {
+		stored = append(stored, match)
+	}
+	r.mu.Unlock()
+
+	// Like the PostgreSQL join, matches of deleted expenses are left out
+	var matches []models.ReconciliationMatch
+	for _, match := range stored {
+		if _, err := r.expenses.FindByID(ctx, match.ExpenseID); err != nil {
+			continue
+		}
+		if _, err := r.expenses.FindByID(ctx, match.TransactionID); err != nil {
+			continue
+		}
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].MatchedAt.Equal(matches[j].MatchedAt) {
+			return matches[i].MatchedAt.Before(matches[j].MatchedAt)
+		}
+		return matches[i].ExpenseID < matches[j].ExpenseID
+	})
+	return matches, nil
+}
+
+func (r *ReconciliationRepository) DeleteMatches(ctx context.Context, userID string, transactionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted := false
+	for expenseID, match := range r.matches[userID] {
+		if match.TransactionID != transactionID {
+			continue
+		}
+		delete(r.matches[userID], expenseID)
+		r.rejections[userID] = append(r.rejections[userID], models.ReconciliationRejection{
+			ExpenseID:     expenseID,
+			TransactionID: transactionID,
+			RejectedAt:    time.Now().UTC(),
+		})
+		deleted = true
+	}
+	if !deleted {
+		return repositories.ErrMatchNotFound
+	}
+	return nil
+}
+
+func (r *ReconciliationRepository) ListRejections(ctx context.Context, userID string) ([]models.ReconciliationRejection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.rejections[userID]), nil
+}
+
+// ReportRepository is an in-memory repositories.ReportRepository over the expenses of an
+// in-memory ExpenseRepository
+type ReportRepository struct {
+	mu       sync.Mutex
+	reports  map[string]*models.ExpenseReport
+	expenses *ExpenseRepository
+}
+
+var _ repositories.ReportRepository = (*ReportRepository)(nil)
+
+// NewReportRepository creates an empty in-memory report repository for the given expenses
+func NewReportRepository(expenses *ExpenseRepository) *ReportRepository {
+	return &ReportRepository{
+		reports:  make(map[string]*models.ExpenseReport),
+		expenses: expenses,
+	}
+}
+
+func (r *ReportRepository) Create(ctx context.Context, report *models.ExpenseReport, expenseIDs []string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *report
+	stored.Expenses = nil
+	if err := r.addExpenses(&stored, expenseIDs, actor); err != nil {
+		return err
+	}
+	r.reports[report.ID] = &stored
+	return nil
+}
+
+func (r *ReportRepository) FindByID(ctx context.Context, id string) (*models.ExpenseReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report, err := r.reportLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	report.Expenses = r.reportExpenses(id)
+	for _, expense := range report.Expenses {
+		if expense.RecordingID == "" || r.expenses.Recordings == nil {
+			continue
+		}
+		if recording := r.expenses.Recordings.Get(expense.RecordingID); recording != nil {
+			expense.Transcription = recording.Transcription
+		}
+	}
+	return report, nil
+}
+
+func (r *ReportRepository) List(ctx context.Context, userID string) ([]*models.ExpenseReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reports := []*models.ExpenseReport{}
+	for id, report := range r.reports {
+		if report.UserID == userID {
+			found, _ := r.reportLocked(id)
+			reports = append(reports, found)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		}
+		return reports[i].ID > reports[j].ID
+	})
+	return reports, nil
+}
+
+func (r *ReportRepository) AddExpenses(ctx context.Context, id string, expenseIDs []string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report, ok := r.reports[id]
+	if !ok {
+		return repositories.ErrReportNotFound
+	}
+	if report.SubmittedAt != nil {
+		return fmt.Errorf("%w: report already submitted", repositories.ErrReportStatus)
+	}
+	return r.addExpenses(report, expenseIDs, actor)
+}
+
+func (r *ReportRepository) RemoveExpense(ctx context.Context, id string, expenseID string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report, ok := r.reports[id]
+	if !ok {
+		return repositories.ErrReportNotFound
+	}
+	if report.SubmittedAt != nil {
+		return fmt.Errorf("%w: report already submitted", repositories.ErrReportStatus)
+	}
+
+	r.expenses.mu.Lock()
+	defer r.expenses.mu.Unlock()
+	expense, ok := r.expenses.expenses[expenseID]
+	if !ok || expense.DeletedAt != nil || expense.ReportID == nil || *expense.ReportID != id {
+		return repositories.ErrExpenseNotInReport
+	}
+	_, err := r.expenses.change(expenseID, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+		expense.ReportID = nil
+	})
+	return err
+}
+
+func (r *ReportRepository) UpdateStatus(ctx context.Context, id string, status string, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report, err := r.reportLocked(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	stored := r.reports[id]
+	switch {
+	case status == models.ReimbursementSubmitted && report.SubmittedAt == nil:
+		if report.Count == 0 {
+			return fmt.Errorf("%w: report has no expenses", repositories.ErrReportStatus)
+		}
+		stored.SubmittedAt = &now
+	case status == models.ReimbursementReimbursed && report.SubmittedAt != nil && report.ReimbursedAt == nil:
+		stored.ReimbursedAt = &now
+	default:
+		return fmt.Errorf("%w: report is %s", repositories.ErrReportStatus, report.Status)
+	}
+
+	r.expenses.mu.Lock()
+	defer r.expenses.mu.Unlock()
+	for expenseID, expense := range r.expenses.expenses {
+		if expense.DeletedAt == nil && expense.ReportID != nil && *expense.ReportID == id {
+			r.expenses.change(expenseID, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+				expense.ReimbursementStatus = status
+			})
+		}
+	}
+	return nil
+}
+
+// addExpenses makes a report claim expenses, like the PostgreSQL repository; all of them or
+// none are added. r.mu must be held.
+func (r *ReportRepository) addExpenses(report *models.ExpenseReport, expenseIDs []string, actor string) error {
+	r.expenses.mu.Lock()
+	defer r.expenses.mu.Unlock()
+	for _, expenseID := range expenseIDs {
+		expense, ok := r.expenses.expenses[expenseID]
+		if !ok || expense.DeletedAt != nil || expense.UserID != report.UserID ||
+			expense.ReimbursementStatus != models.ReimbursementPending || expense.ReportID != nil {
+			return fmt.Errorf("%w: %s", repositories.ErrExpenseNotReportable, expenseID)
+		}
+	}
+	for _, expenseID := range expenseIDs {
+		r.expenses.change(expenseID, models.ExpenseEventUpdate, actor, func(expense *models.Expense) {
+			reportID := report.ID
+			expense.ReportID = &reportID
+		})
+	}
+	return nil
+}
+
+// reportLocked returns a copy of a stored report with its status and totals; r.mu must be held
+func (r *ReportRepository) reportLocked(id string) (*models.ExpenseReport, error) {
+	stored, ok := r.reports[id]
+	if !ok {
+		return nil, repositories.ErrReportNotFound
+	}
+	report := *stored
+	report.Status = models.ReimbursementPending
+	if report.SubmittedAt != nil {
+		report.Status = models.ReimbursementSubmitted
+	}
+	if report.ReimbursedAt != nil {
+		report.Status = models.ReimbursementReimbursed
+	}
+	for _, expense := range r.reportExpenses(id) {
+		report.Total += expense.Total()
+		report.Count++
+	}
+	return &report, nil
+}
+
+// reportExpenses returns copies of the expenses a report claims, oldest purchase first
+func (r *ReportRepository) reportExpenses(id string) []*models.Expense {
+	r.expenses.mu.Lock()
+	defer r.expenses.mu.Unlock()
+	var expenses []*models.Expense
+	for _, expense := range r.expenses.expenses {
+		if expense.DeletedAt == nil && expense.ReportID != nil && *expense.ReportID == id {
+			found := *expense
+			expenses = append(expenses, &found)
+		}
+	}
+	sort.Slice(expenses, func(i, j int) bool {
+		if !expenses[i].PurchasedAt.Equal(expenses[j].PurchasedAt) {
+			return expenses[i].PurchasedAt.Before(expenses[j].PurchasedAt)
+		}
+		return expenses[i].ID < expenses[j].ID
+	})
+	return expenses
+}
@@ -0,0 +1,200 @@
+// Package fakes provides in-process fakes of the external dependencies
+// (OpenAI and PostgreSQL) for tests and local experiments.
+package fakes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// OpenAI endpoints implemented by the fake
+const (
+	EndpointTranscriptions  = "/v1/audio/transcriptions"
+	EndpointChatCompletions = "/v1/chat/completions"
+)
+
+// Segment is a Whisper verbose_json segment
+type Segment struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Text       string  `json:"text"`
+	AvgLogprob float64 `json:"avg_logprob"`
+}
+
+// Transcription is a scripted Whisper verbose_json response
+type Transcription struct {
+	Text     string    `json:"text"`
+	Language string    `json:"language"` // Whisper language name, e.g. "spanish"
+	Duration float64   `json:"duration"`
+	Segments []Segment `json:"segments"`
+}
+
+// Request is a request received by the fake
+type Request struct {
+	Endpoint string
+	// Messages holds the chat messages of a chat completion request
+	Messages []openai.ChatCompletionMessage
+	// Audio holds the uploaded file of a transcription request
+	Audio []byte
+	// Form holds the non-file fields of a transcription request
+	Form map[string]string
+}
+
+type scripted struct {
+	status     int
+	retryAfter string
+	body       any
+}
+
+// OpenAIServer is a fake of the OpenAI endpoints used by go-openai, answering
+// each endpoint with the responses queued for it, in order
+type OpenAIServer struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	queues   map[string][]scripted
+	requests []Request
+}
+
+// NewOpenAIServer starts a fake OpenAI server; call Close when done
+func NewOpenAIServer() *OpenAIServer {
+	f := &OpenAIServer{queues: make(map[string][]scripted)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+EndpointTranscriptions, f.handleTranscription)
+	mux.HandleFunc("POST "+EndpointChatCompletions, f.handleChatCompletion)
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+// BaseURL is the value for the go-openai BaseURL setting
+func (f *OpenAIServer) BaseURL() string {
+	return f.server.URL + "/v1"
+}
+
+// Close shuts the server down
+func (f *OpenAIServer) Close() {
+	f.server.Close()
+}
+
+// QueueTranscription scripts the next transcription response
+func (f *OpenAIServer) QueueTranscription(t Transcription) {
+	f.queue(EndpointTranscriptions, scripted{status: http.StatusOK, body: t})
+}
+
+// QueueChatCompletion scripts the next chat completion response with the given assistant content
+func (f *OpenAIServer) QueueChatCompletion(content string) {
+	f.queue(EndpointChatCompletions, scripted{status: http.StatusOK, body: openai.ChatCompletionResponse{
+		Object: "chat.completion",
+		Model:  openai.GPT4,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			FinishReason: openai.FinishReasonStop,
+		}},
+	}})
+}
+
+// QueueExpenses scripts the next chat completion to return the given expenses as JSON
+func (f *OpenAIServer) QueueExpenses(expenses any) {
+	content, err := json.Marshal(expenses)
+	if err != nil {
+		panic(fmt.Sprintf("fakes: cannot marshal expenses: %v", err))
+	}
+	f.QueueChatCompletion(string(content))
+}
+
+// QueueError scripts the next response of an endpoint as an OpenAI API error
+func (f *OpenAIServer) QueueError(endpoint string, status int, retryAfter string) {
+	f.queue(endpoint, scripted{status: status, retryAfter: retryAfter, body: openai.ErrorResponse{
+		Error: &openai.APIError{Message: fmt.Sprintf("scripted %d error", status), Type: "server_error"},
+	}})
+}
+
+// Requests returns the requests received so far
+func (f *OpenAIServer) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// RequestsTo returns the requests received so far by an endpoint
+func (f *OpenAIServer) RequestsTo(endpoint string) []Request {
+	var matching []Request
+	for _, r := range f.Requests() {
+		if r.Endpoint == endpoint {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+func (f *OpenAIServer) queue(endpoint string, s scripted) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queues[endpoint] = append(f.queues[endpoint], s)
+}
+
+func (f *OpenAIServer) next(endpoint string, req Request) (scripted, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	queue := f.queues[endpoint]
+	if len(queue) == 0 {
+		return scripted{}, false
+	}
+	f.queues[endpoint] = queue[1:]
+	return queue[0], true
+}
+
+func (f *OpenAIServer) handleTranscription(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeJSON(w, http.StatusBadRequest, openai.ErrorResponse{Error: &openai.APIError{Message: err.Error()}})
+		return
+	}
+
+	req := Request{Endpoint: EndpointTranscriptions, Form: make(map[string]string)}
+	for key, values := range r.MultipartForm.Value {
+		req.Form[key] = values[0]
+	}
+	if file, _, err := r.FormFile("file"); err == nil {
+		req.Audio, _ = io.ReadAll(file)
+		file.Close()
+	}
+
+	f.respond(w, EndpointTranscriptions, req)
+}
+
+func (f *OpenAIServer) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
+	var body openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, openai.ErrorResponse{Error: &openai.APIError{Message: err.Error()}})
+		return
+	}
+
+	f.respond(w, EndpointChatCompletions, Request{Endpoint: EndpointChatCompletions, Messages: body.Messages})
+}
+
+func (f *OpenAIServer) respond(w http.ResponseWriter, endpoint string, req Request) {
+	s, ok := f.next(endpoint, req)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, openai.ErrorResponse{
+			Error: &openai.APIError{Message: "fakes: no scripted response for " + endpoint, Type: "fake_error"},
+		})
+		return
+	}
+	if s.retryAfter != "" {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	writeJSON(w, s.status, s.body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,143 @@
+package fakes
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"upload-lambda/internal/models"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The in-memory search approximates the PostgreSQL full-text search of expenses well enough
+// for the handler tests; repositories.TestPostgresSearch runs the real one.
+
+// searchWord matches the words the PostgreSQL parser would index
+var searchWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// searchStopWords are common Spanish words the es_unaccent configuration does not index
+var searchStopWords = map[string]bool{
+	"a": true, "al": true, "con": true, "de": true, "del": true, "el": true, "en": true, "la": true,
+	"las": true, "lo": true, "los": true, "o": true, "para": true, "por": true, "que": true,
+	"un": true, "una": true, "unas": true, "unos": true, "y": true,
+}
+
+// searchTerm approximates es_unaccent for a word: lowercase, accent-folded and without the
+// plural ending, so "Pilas" and "pila" or "cafés" and "cafe" share a term
+func searchTerm(word string) string {
+	term, _, _ := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), strings.ToLower(word))
+	switch {
+	case len(term) > 4 && strings.HasSuffix(term, "es"):
+		return strings.TrimSuffix(term, "es")
+	case len(term) > 3 && strings.HasSuffix(term, "s"):
+		return strings.TrimSuffix(term, "s")
+	}
+	return term
+}
+
+// highlight wraps the words of text whose term is in terms with <mark> tags, and reports
+// how many it wrapped
+func highlight(text string, terms map[string]bool) (string, int) {
+	count := 0
+	marked := searchWord.ReplaceAllStringFunc(text, func(word string) string {
+		if !terms[searchTerm(word)] {
+			return word
+		}
+		count++
+		return "<mark>" + word + "</mark>"
+	})
+	return marked, count
+}
+
+// searchQuery is a parsed websearch_to_tsquery query: alternatives joined with "or", each
+// requiring all of its terms and none of its excluded terms
+type searchQuery struct {
+	alternatives []searchAlternative
+	terms        map[string]bool
+}
+
+type searchAlternative struct {
+	required, excluded []string
+}
+
+// parseSearchQuery parses the query syntax used by websearch_to_tsquery, without phrases
+func parseSearchQuery(query string) searchQuery {
+	parsed := searchQuery{terms: make(map[string]bool)}
+	current := searchAlternative{}
+	for _, token := range strings.Fields(query) {
+		if strings.EqualFold(token, "or") {
+			parsed.alternatives = append(parsed.alternatives, current)
+			current = searchAlternative{}
+			continue
+		}
+		excluded := strings.HasPrefix(token, "-")
+		for _, word := range searchWord.FindAllString(token, -1) {
+			if searchStopWords[strings.ToLower(word)] {
+				continue
+			}
+			if excluded {
+				current.excluded = append(current.excluded, searchTerm(word))
+			} else {
+				current.required = append(current.required, searchTerm(word))
+				parsed.terms[searchTerm(word)] = true
+			}
+		}
+	}
+	parsed.alternatives = append(parsed.alternatives, current)
+	return parsed
+}
+
+// matches reports whether a document with the given terms matches the query
+func (q searchQuery) matches(found map[string]bool) bool {
+	for _, alternative := range q.alternatives {
+		if len(alternative.required) == 0 {
+			continue
+		}
+		matched := true
+		for _, term := range alternative.required {
+			matched = matched && found[term]
+		}
+		for _, term := range alternative.excluded {
+			matched = matched && !found[term]
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// search matches an expense against a query like websearch_to_tsquery, ranking description
+// matches above transcription matches. The caller holds the lock.
+func (r *ExpenseRepository) search(query string, expense *models.Expense) *models.ExpenseMatch {
+	parsed := parseSearchQuery(query)
+
+	transcription := ""
+	if r.Recordings != nil && expense.RecordingID != "" {
+		if recording := r.Recordings.Get(expense.RecordingID); recording != nil {
+			transcription = recording.Transcription
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, text := range []string{expense.Description, transcription} {
+		for _, word := range searchWord.FindAllString(text, -1) {
+			found[searchTerm(word)] = true
+		}
+	}
+	if !parsed.matches(found) {
+		return nil
+	}
+
+	match := &models.ExpenseMatch{}
+	description, inDescription := highlight(expense.Description, parsed.terms)
+	match.Description = description
+	if marked, inTranscription := highlight(transcription, parsed.terms); inTranscription > 0 {
+		match.Transcription = marked
+		match.Rank += 0.4 * float64(inTranscription)
+	}
+	match.Rank += float64(inDescription)
+	return match
+}
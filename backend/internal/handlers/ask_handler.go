@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/services"
+)
+
+// maxQuestionBodyBytes bounds the JSON body of a typed question
+const maxQuestionBodyBytes = 16 << 10
+
+// AskHandler handles HTTP requests for questions about expenses
+type AskHandler struct {
+	service services.AskService
+}
+
+// NewAskHandler creates a new ask handler
+func NewAskHandler(service services.AskService) *AskHandler {
+	return &AskHandler{
+		service: service,
+	}
+}
+
+// HandleAsk handles questions about expenses
+// @Summary Ask a question about expenses
+// @Description Answers a question such as "¿cuánto gasté en transporte en marzo?", typed (JSON body, or a question form field) or recorded (an audio form field). The model translates the question into a query (aggregation sum, count, average, max or min, with optional from/to days, search words, merchant, units and group_by); the query is checked against a whitelist and answered from the stored expenses, so the number never comes from the model. Search words use full-text search, not semantic search. reply states the answer in the question's language.
+// @Tags ask
+// @Accept json,multipart/form-data
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param question body models.AskRequest false "Typed question (JSON body)"
+// @Param audio formData file false "Recorded question (m4a, mp3, wav, ogg or webm)"
+// @Success 200 {object} models.AskAnswer "Answer"
+// @Failure 400 {object} map[string]string "Missing, empty or too long question"
+// @Failure 413 {object} map[string]string "Audio too large or too long"
+// @Failure 415 {object} map[string]string "Unsupported audio format"
+// @Failure 422 {object} map[string]string "Question cannot be answered, or silent audio"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 503 {object} map[string]string "OpenAI unavailable"
+// @Router /ask [post]
+func (h *AskHandler) HandleAsk(w http.ResponseWriter, r *http.Request) {
+	params := models.AskParams{UserID: userIDFromRequest(r)}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		upload, err := readUpload(w, r)
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		defer upload.Cleanup()
+
+		params.Question = upload.Fields["question"]
+		for _, f := range upload.Files {
+			if f.Field == "audio" {
+				params.AudioPath = f.Path
+			}
+		}
+	} else {
+		var req models.AskRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQuestionBodyBytes)).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		params.Question = req.Question
+	}
+
+	answer, err := h.service.Ask(r.Context(), params)
+	if errors.Is(err, services.ErrInvalidQuestion) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrUnanswerableQuestion) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		status, code, message := processingError(err)
+		if status == http.StatusInternalServerError {
+			message = fmt.Sprintf("Failed to answer question: %v", err)
+		}
+		if code != "" {
+			writeJSONError(w, status, code, message)
+			return
+		}
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(answer)
+}
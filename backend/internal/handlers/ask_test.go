@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/models"
+)
+
+// seedAskExpenses records transport and grocery expenses at two merchants
+func seedAskExpenses(h *harness) {
+	h.t.Helper()
+	h.openai.QueueTranscription(spanish("Dos pasajes de bus y un taxi"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 2.5, Quantity: 2, Unit: "pasaje", Description: "pasaje de bus"},
+		{UnitPrice: 12, Quantity: 1, Unit: "u", Description: "taxi al aeropuerto"},
+	})
+	decode[[]models.Expense](h.t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	h.openai.QueueTranscription(spanish("En Tottus pan y leche, en Lider arroz"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 3, Quantity: 1, Unit: "kg", Description: "pan", Merchant: "Tottus"},
+		{UnitPrice: 1.5, Quantity: 2, Unit: "litro", Description: "leche", Merchant: "Tottus"},
+		{UnitPrice: 10, Quantity: 1, Unit: "kg", Description: "arroz", Merchant: "Lider"},
+	})
+	decode[[]models.Expense](h.t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+}
+
+func TestAskTypedQuestion(t *testing.T) {
+	h := newHarness(t)
+	seedAskExpenses(h)
+
+	h.openai.QueueChatCompletion(`{"query": {"aggregation": "sum", "search": "pasaje or taxi"}, "reply": "Gastaste {answer} en transporte."}`)
+	rec := h.do(http.MethodPost, "/ask", strings.NewReader(`{"question": "¿cuánto gasté en transporte?"}`), nil)
+	answer := decode[models.AskAnswer](t, rec, http.StatusOK)
+
+	if answer.Answer != 17 || answer.Count != 2 {
+		t.Errorf("answer = %v over %d expenses, want 17 over 2", answer.Answer, answer.Count)
+	}
+	if answer.Reply != "Gastaste 17,00 en transporte." {
+		t.Errorf("reply = %q", answer.Reply)
+	}
+	if answer.Question != "¿cuánto gasté en transporte?" || answer.PromptVersion == "" {
+		t.Errorf("answer = %+v", answer)
+	}
+
+	// The question reaches the model delimited, with the user's merchants in the prompt
+	chats := h.openai.RequestsTo(fakes.EndpointChatCompletions)
+	messages := chats[len(chats)-1].Messages
+	if !strings.Contains(messages[0].Content, `"Tottus"`) {
+		t.Errorf("prompt does not list merchants: %s", messages[0].Content)
+	}
+	if !strings.Contains(messages[len(messages)-1].Content, "<question>\n¿cuánto gasté en transporte?\n</question>") {
+		t.Errorf("question message = %q", messages[len(messages)-1].Content)
+	}
+}
+
+func TestAskGroupedQuestion(t *testing.T) {
+	h := newHarness(t)
+	seedAskExpenses(h)
+
+	h.openai.QueueChatCompletion(`{"query": {"aggregation": "sum", "units": ["kg", "litro"], "group_by": "merchant"}, "reply": "Donde más gastaste fue {group}: {answer}."}`)
+	rec := h.do(http.MethodPost, "/ask", strings.NewReader(`{"question": "¿dónde gasté más en el súper?"}`), nil)
+	answer := decode[models.AskAnswer](t, rec, http.StatusOK)
+
+	if answer.Group == nil || answer.Group.Label != "Lider" || answer.Answer != 10 {
+		t.Fatalf("answer = %+v, group = %+v", answer, answer.Group)
+	}
+	if answer.Reply != "Donde más gastaste fue Lider: 10,00." {
+		t.Errorf("reply = %q", answer.Reply)
+	}
+}
+
+func TestAskRecordedQuestion(t *testing.T) {
+	h := newHarness(t)
+	seedAskExpenses(h)
+
+	h.openai.QueueTranscription(spanish("¿Cuántas cosas compré en tottus?"))
+	h.openai.QueueChatCompletion(`{"query": {"aggregation": "count", "merchant": "tottus"}, "reply": "Compraste {answer} cosas en Tottus."}`)
+	answer := decode[models.AskAnswer](t, h.postForm("/ask", fakeAudio, nil, nil), http.StatusOK)
+
+	if answer.Question != "¿Cuántas cosas compré en tottus?" || answer.Answer != 2 {
+		t.Errorf("answer = %+v", answer)
+	}
+	if answer.Reply != "Compraste 2 cosas en Tottus." {
+		t.Errorf("reply = %q", answer.Reply)
+	}
+}
+
+func TestAskRejectsUnanswerableQueries(t *testing.T) {
+	h := newHarness(t)
+	seedAskExpenses(h)
+
+	for name, translation := range map[string]string{
+		"aggregation": `{"query": {"aggregation": "DELETE FROM expenses"}, "reply": "{answer}"}`,
+		"merchant":    `{"query": {"aggregation": "sum", "merchant": "Jumbo"}, "reply": "{answer}"}`,
+		"unit":        `{"query": {"aggregation": "sum", "units": ["barril"]}, "reply": "{answer}"}`,
+		"dates":       `{"query": {"aggregation": "sum", "from": "2026-03-31", "to": "2026-03-01"}, "reply": "{answer}"}`,
+		"group_by":    `{"query": {"aggregation": "sum", "group_by": "user_id"}, "reply": "{answer}"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			h.openai.QueueChatCompletion(translation)
+			rec := h.do(http.MethodPost, "/ask", strings.NewReader(`{"question": "¿cuánto gasté?"}`), nil)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want 422: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// A reply without the placeholder falls back to the default one
+	h.openai.QueueChatCompletion(`{"query": {"aggregation": "max"}, "reply": "Gastaste 1.000.000"}`)
+	answer := decode[models.AskAnswer](t, h.do(http.MethodPost, "/ask", strings.NewReader(`{"question": "¿cuál fue mi gasto más caro?"}`), nil), http.StatusOK)
+	if answer.Reply != "Resultado: 12,00" {
+		t.Errorf("reply = %q", answer.Reply)
+	}
+}
+
+func TestAskEmptyQuestion(t *testing.T) {
+	h := newHarness(t)
+	for _, body := range []string{`{"question": "  "}`, `{`} {
+		if rec := h.do(http.MethodPost, "/ask", strings.NewReader(body), nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if len(h.openai.RequestsTo(fakes.EndpointChatCompletions)) != 0 {
+		t.Error("empty question reached the model")
+	}
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+// uploadBread records "dos panes" bought at the given time
+func uploadBread(t *testing.T, h *harness, description string, purchasedAt string) models.Expense {
+	t.Helper()
+	h.openai.QueueTranscription(spanish("dos " + description))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 0.5, Quantity: 2, Unit: "u", Description: description}})
+	expenses := decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": purchasedAt}, nil), http.StatusOK)
+	return expenses[0]
+}
+
+func TestUploadFlagsLikelyDuplicates(t *testing.T) {
+	h := newHarness(t)
+
+	original := uploadBread(t, h, "panes", "2026-02-21T08:00:00Z")
+	if original.PossibleDuplicateOf != nil {
+		t.Fatalf("first expense flagged as duplicate of %s", *original.PossibleDuplicateOf)
+	}
+
+	again := uploadBread(t, h, "pan", "2026-02-21T19:30:00Z")
+	if again.PossibleDuplicateOf == nil || *again.PossibleDuplicateOf != original.ID {
+		t.Fatalf("possible_duplicate_of = %v, want %s", again.PossibleDuplicateOf, original.ID)
+	}
+
+	nextWeek := uploadBread(t, h, "pan", "2026-02-28T08:00:00Z")
+	if nextWeek.PossibleDuplicateOf != nil {
+		t.Errorf("purchase a week later flagged as duplicate of %s", *nextWeek.PossibleDuplicateOf)
+	}
+
+	flagged := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses?possible_duplicate=true", nil, nil), http.StatusOK)
+	if *flagged.Total != 1 || flagged.Data[0].ID != again.ID {
+		t.Errorf("flagged expenses = %+v, want only %s", flagged.Data, again.ID)
+	}
+}
+
+func TestMergeDuplicateKeepsOriginal(t *testing.T) {
+	h := newHarness(t)
+	original := uploadBread(t, h, "panes", "2026-02-21T08:00:00Z")
+	again := uploadBread(t, h, "panes", "2026-02-21T09:00:00Z")
+
+	kept := decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+again.ID+"/duplicate/merge", nil, nil), http.StatusOK)
+	if kept.ID != original.ID {
+		t.Errorf("kept = %s, want the original %s", kept.ID, original.ID)
+	}
+	if got := len(h.expenses.All()); got != 1 {
+		t.Errorf("stored expenses = %d, want 1", got)
+	}
+
+	// The merged duplicate is soft-deleted, so the merge can be undone
+	restored := decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+again.ID+"/restore", nil, nil), http.StatusOK)
+	if restored.ID != again.ID || restored.DeletedAt != nil {
+		t.Errorf("restored = %+v", restored)
+	}
+
+	rec := h.do(http.MethodPost, "/expenses/"+original.ID+"/duplicate/merge", nil, nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("merging an unflagged expense: status = %d, want 409", rec.Code)
+	}
+}
+
+func TestDismissDuplicateKeepsBoth(t *testing.T) {
+	h := newHarness(t)
+	uploadBread(t, h, "panes", "2026-02-21T08:00:00Z")
+	again := uploadBread(t, h, "panes", "2026-02-21T09:00:00Z")
+
+	dismissed := decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+again.ID+"/duplicate/dismiss", nil, nil), http.StatusOK)
+	if dismissed.PossibleDuplicateOf != nil {
+		t.Errorf("possible_duplicate_of = %v after dismissing", *dismissed.PossibleDuplicateOf)
+	}
+	if got := len(h.expenses.All()); got != 2 {
+		t.Errorf("stored expenses = %d, want 2", got)
+	}
+}
+
+func TestDuplicatesOfOtherUsersAreNotFound(t *testing.T) {
+	h := newHarness(t)
+	uploadBread(t, h, "panes", "2026-02-21T08:00:00Z")
+	again := uploadBread(t, h, "panes", "2026-02-21T09:00:00Z")
+	ana := map[string]string{"X-User-ID": "ana"}
+
+	for _, action := range []string{"merge", "dismiss"} {
+		if rec := h.do(http.MethodPost, "/expenses/"+again.ID+"/duplicate/"+action, nil, ana); rec.Code != http.StatusNotFound {
+			t.Errorf("%s of another user's duplicate: status = %d, want 404", action, rec.Code)
+		}
+	}
+	if expense, _ := h.expenses.FindByID(t.Context(), again.ID); expense == nil || expense.DeletedAt != nil || expense.PossibleDuplicateOf == nil {
+		t.Errorf("duplicate after other users' attempts = %+v, want it untouched", expense)
+	}
+}
+
+func TestRestoringAnOriginalFlagsItsDuplicatesAgain(t *testing.T) {
+	h := newHarness(t)
+	original := uploadBread(t, h, "panes", "2026-02-21T08:00:00Z")
+	again := uploadBread(t, h, "panes", "2026-02-21T09:00:00Z")
+
+	decode[models.Expense](t, h.do(http.MethodDelete, "/expenses/"+original.ID, nil, nil), http.StatusOK)
+	if stored, _ := h.expenses.FindByID(t.Context(), again.ID); stored.PossibleDuplicateOf != nil {
+		t.Fatalf("duplicate of a deleted expense still flagged: %+v", stored)
+	}
+
+	decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+original.ID+"/restore", nil, nil), http.StatusOK)
+	stored, _ := h.expenses.FindByID(t.Context(), again.ID)
+	if stored.PossibleDuplicateOf == nil || *stored.PossibleDuplicateOf != original.ID {
+		t.Errorf("possible_duplicate_of after restoring = %v, want %s", stored.PossibleDuplicateOf, original.ID)
+	}
+}
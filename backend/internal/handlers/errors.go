@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"upload-lambda/internal/audio"
+	"upload-lambda/internal/repositories"
+	"upload-lambda/internal/services"
+)
+
+// Error codes returned when OpenAI is unavailable
+const (
+	errorCodeOpenAIUnavailable = "openai_unavailable"
+	errorCodeCircuitOpen       = "openai_circuit_open"
+)
+
+// writeJSONError writes an error response with a machine-readable code
+func writeJSONError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+		"code":  code,
+	})
+}
+
+// upstreamError returns the error code and message if err comes from OpenAI being unavailable, or an empty code
+func upstreamError(err error) (code string, message string) {
+	switch {
+	case errors.Is(err, repositories.ErrCircuitOpen):
+		return errorCodeCircuitOpen, "OpenAI is failing, requests are paused for a while: " + err.Error()
+	case errors.Is(err, repositories.ErrOpenAIUnavailable):
+		return errorCodeOpenAIUnavailable, err.Error()
+	}
+	return "", ""
+}
+
+// audioErrorStatus maps audio validation errors to HTTP status codes, returning 0 for other errors
+func audioErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, audio.ErrTooLarge), errors.Is(err, audio.ErrTooLong):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, audio.ErrUnsupportedFormat):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, audio.ErrEmpty), errors.Is(err, audio.ErrSilent):
+		return http.StatusUnprocessableEntity
+	}
+	return 0
+}
+
+// receiptErrorStatus maps receipt image errors to HTTP status codes, returning 0 for other errors
+func receiptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrNoReceiptText):
+		return http.StatusUnprocessableEntity
+	}
+	return 0
+}
+
+// processingError maps an error from processing a recording or receipt to an HTTP status, an
+// error code (only for upstream failures) and a message
+func processingError(err error) (status int, code string, message string) {
+	if status := audioErrorStatus(err); status != 0 {
+		return status, "", fmt.Sprintf("Invalid audio: %v", err)
+	}
+	if status := receiptErrorStatus(err); status != 0 {
+		return status, "", fmt.Sprintf("Invalid receipt image: %v", err)
+	}
+	if code, message := upstreamError(err); code != "" {
+		return http.StatusServiceUnavailable, code, message
+	}
+	if errors.Is(err, services.ErrSuspiciousExtraction) {
+		return http.StatusUnprocessableEntity, "", fmt.Sprintf("Rejected extracted expenses: %v", err)
+	}
+	if errors.Is(err, services.ErrNoMatchingExpense) {
+		return http.StatusUnprocessableEntity, "", err.Error()
+	}
+	if errors.Is(err, repositories.ErrExpenseClaimed) {
+		return http.StatusConflict, "", err.Error()
+	}
+	return http.StatusInternalServerError, "", fmt.Sprintf("Failed to process expenses: %v", err)
+}
+
+// writeProcessingError writes an error from processing a recording or receipt
+func writeProcessingError(w http.ResponseWriter, err error) {
+	status, code, message := processingError(err)
+	if code != "" {
+		writeJSONError(w, status, code, message)
+		return
+	}
+	http.Error(w, message, status)
+}
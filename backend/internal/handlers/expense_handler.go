@@ -1,40 +1,68 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
 	"upload-lambda/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // ExpenseHandler handles HTTP requests for expenses
 type ExpenseHandler struct {
-	service services.ExpenseService
+	service     services.ExpenseService
+	idempotency services.IdempotencyService
 }
 
 // NewExpenseHandler creates a new expense handler
-func NewExpenseHandler(service services.ExpenseService) *ExpenseHandler {
+func NewExpenseHandler(service services.ExpenseService, idempotency services.IdempotencyService) *ExpenseHandler {
 	return &ExpenseHandler{
-		service: service,
+		service:     service,
+		idempotency: idempotency,
 	}
 }
 
 // HandleUpload handles the upload of audio files
 // @Summary Upload audio and extract expenses
-// @Description Uploads an audio file, transcribes it using OpenAI Whisper, and extracts expense data using GPT-4
+// @Description Uploads an audio file, transcribes it using OpenAI Whisper, detects its language (es, en, pt; falls back to the user's default language), and extracts expense data using GPT-4.
+// @Description
+// @Description Spoken purchase dates ("ayer compré...", "el lunes", "el 3 de febrero") are resolved against purchased_at (the recording time, default now) in the given timezone and set per expense; purchased_at is used as is when no date is spoken.
+// @Description
+// @Description Batch mode: send up to 10 recordings (4 MB in total, the request limit) as audio[<key>] parts, each with optional purchased_at[<key>] and recording_id[<key>] fields. They are processed concurrently and the response is a BatchUploadResponse with one result per recording, in upload order; a failing recording does not fail the others. The status is 200 if all succeed, 207 if some fail, and the failures' status (the highest if they differ) if all fail.
+// @Description
+// @Description Recordings that correct or delete an expense instead ("el pan costó cinco, no cuatro", "borra el último gasto") are applied to the most recent matching expense of the user, and the response is a VoiceCommandResult with the changes (the expense before and after) so the app can offer an undo; a question ("¿cuánto gasté hoy?") returns intent query and the transcription, to be sent to POST /ask. In a batch, such recordings have intent and changes instead of expenses.
+// @Description
+// @Description Retries are idempotent when the request has an Idempotency-Key header or a recording ID (recording_id, or recording_id[<key>] per batch recording): the stored response is replayed with an Idempotent-Replayed header, and reusing a key with a different payload is rejected with 422. A batch in which a recording failed with a server error is not replayed whole: its retry processes that recording again and replays the others.
 // @Tags expenses
 // @Accept multipart/form-data
 // @Produce json
-// @Param audio formData file true "Audio file (m4a, mp3, wav, etc.)"
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param Idempotency-Key header string false "Client-generated key; retries with the same key replay the first response"
+// @Param audio formData file false "Audio file (m4a, mp3, wav, ogg or webm; detected from content, max 4 MB and 10 minutes). Required unless uploading a batch"
 // @Param purchased_at formData string false "Purchase date/time in RFC3339 format (e.g., 2026-02-22T10:30:00Z)"
-// @Success 200 {array} models.Expense "List of extracted expenses"
+// @Param recording_id formData string false "Client-side recording identifier, stored with the recording and used as idempotency key without an Idempotency-Key header"
+// @Param timezone formData string false "IANA timezone of the device (e.g. America/Lima), used to resolve spoken dates like \"ayer\" (default: the user's timezone setting)"
+// @Success 200 {array} models.Expense "List of extracted expenses (single upload)"
+// @Success 200 {object} models.VoiceCommandResult "Changes made by a correction or deletion, or a question (single upload)"
+// @Success 200 {object} models.BatchUploadResponse "Per-recording results (batch upload where every recording succeeded)"
+// @Success 207 {object} models.BatchUploadResponse "Per-recording results (batch upload where some recordings failed)"
 // @Failure 400 {object} map[string]string "Bad request"
+// @Failure 409 {object} map[string]string "A request with the same idempotency key is still being processed, or a spoken correction or deletion targets an expense claimed in a submitted report"
+// @Failure 413 {object} map[string]string "Request body over 4 MB, or audio too long"
+// @Failure 415 {object} map[string]string "Unsupported audio format"
+// @Failure 422 {object} map[string]string "Empty or silent recording, extracted data out of bounds (negative price, unknown unit, ...), no recent expense matching a correction or deletion, or idempotency key reused with a different payload"
 // @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 503 {object} map[string]string "OpenAI unavailable (code: openai_unavailable or openai_circuit_open)"
 // @Router /upload [post]
 func (h *ExpenseHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -42,72 +70,439 @@ func (h *ExpenseHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (32 MB max)
-	err := r.ParseMultipartForm(32 << 20)
+	// Stream the multipart body (audio goes straight to a temp file)
+	upload, err := readUpload(w, r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to parse form: %v", err), http.StatusBadRequest)
+		writeUploadError(w, err)
 		return
 	}
+	defer upload.Cleanup()
 
-	// Get purchased_at from form (optional, defaults to now)
-	purchasedAtStr := r.FormValue("purchased_at")
-	var purchasedAt time.Time
-	if purchasedAtStr != "" {
-		purchasedAt, err = time.Parse(time.RFC3339, purchasedAtStr)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Invalid purchased_at format (expected RFC3339): %v", err), http.StatusBadRequest)
-			return
-		}
-	} else {
-		purchasedAt = time.Now().UTC()
+	// Make retries idempotent: the header covers the whole request, a single upload's
+	// recording_id covers that recording
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	mode := "request"
+	if key == "" && upload.Fields["recording_id"] != "" {
+		key = recordingKey(upload.Fields["recording_id"])
+		mode = "recording"
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		http.Error(w, fmt.Sprintf("Idempotency key exceeds %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+		return
+	}
+	if key == "" {
+		h.serveUpload(w, r, upload, "")
+		return
 	}
 
-	// Get audio file
-	file, header, err := r.FormFile("audio")
+	fingerprint, err := uploadFingerprint(mode, upload)
 	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to process upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	serveIdempotent(w, r, h.idempotency, key, fingerprint, func(w http.ResponseWriter) {
+		h.serveUpload(w, r, upload, key)
+	})
+}
+
+// serveUpload processes a received upload. requestKey is the idempotency key of the whole
+// request, if any; batch recordings are idempotent on their own, by a key derived from it or
+// otherwise by their recording_id[<key>].
+func (h *ExpenseHandler) serveUpload(w http.ResponseWriter, r *http.Request, upload *multipartUpload, requestKey string) {
+	// Get audio files, either a single "audio" part or a batch of "audio[<key>]" parts
+	var audioFiles, batchFiles []uploadedFile
+	for _, f := range upload.Files {
+		if f.Field == "audio" {
+			audioFiles = append(audioFiles, f)
+		} else if _, ok := batchKey(f.Field, "audio"); ok {
+			batchFiles = append(batchFiles, f)
+		}
+	}
+	if len(batchFiles) > 0 {
+		if len(audioFiles) > 0 {
+			http.Error(w, "Send either a single audio part or audio[<key>] parts, not both", http.StatusBadRequest)
+			return
+		}
+		h.serveBatchUpload(w, r, upload, batchFiles, requestKey)
+		return
+	}
+	if len(audioFiles) == 0 {
 		http.Error(w, "No audio file provided", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
+	if len(audioFiles) > 1 {
+		http.Error(w, "Only one audio part is allowed, use audio[<key>] parts to upload a batch", http.StatusBadRequest)
+		return
+	}
 
-	// Save to temp file
-	tmpFile, err := os.CreateTemp("", "audio-*"+filepath.Ext(header.Filename))
+	// Get purchased_at from form (optional, defaults to now); it may come before or after the audio
+	purchasedAt, err := parsePurchasedAt(upload.Fields["purchased_at"])
 	if err != nil {
-		http.Error(w, "Failed to create temp file", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
 
-	_, err = io.Copy(tmpFile, file)
+	location, err := parseTimezone(upload.Fields["timezone"])
 	if err != nil {
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Process expenses (may be multiple)
-	expenses, err := h.service.ProcessAudioExpense(r.Context(), tmpFile.Name(), purchasedAt)
+	// Process expenses (may be multiple), or a correction or deletion
+	result, err := h.service.ProcessAudioExpense(r.Context(), models.ProcessAudioParams{
+		AudioPath:         audioFiles[0].Path,
+		PurchasedAt:       purchasedAt,
+		Location:          location,
+		UserID:            userIDFromRequest(r),
+		ClientRecordingID: upload.Fields["recording_id"],
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process expenses: %v", err), http.StatusInternalServerError)
+		writeProcessingError(w, err)
 		return
 	}
 
-	// Return success
+	// Return success: the created expenses, or what a command changed
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(expenses)
+	if result.Intent == models.VoiceIntentAdd {
+		json.NewEncoder(w).Encode(result.Expenses)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// serveBatchUpload processes audio[<key>] parts concurrently and writes one result per part.
+// Recordings with invalid fields fail on their own without reaching the service.
+func (h *ExpenseHandler) serveBatchUpload(w http.ResponseWriter, r *http.Request, upload *multipartUpload, files []uploadedFile, requestKey string) {
+	if len(files) > maxBatchRecordings {
+		http.Error(w, fmt.Sprintf("A batch may contain at most %d recordings, got %d", maxBatchRecordings, len(files)), http.StatusBadRequest)
+		return
+	}
+
+	// The device timezone applies to every recording of the batch
+	location, err := parseTimezone(upload.Fields["timezone"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := userIDFromRequest(r)
+	results := make([]models.UploadResult, len(files))
+	var batch []models.ProcessAudioParams
+	var batchIndex []int
+	var batchKeys []string
+	seen := make(map[string]bool)
+	for i, f := range files {
+		key, _ := batchKey(f.Field, "audio")
+		result := &results[i]
+		result.Key = key
+		result.ClientRecordingID = upload.Fields[fmt.Sprintf("recording_id[%s]", key)]
+
+		if seen[key] {
+			result.Status = http.StatusBadRequest
+			result.Error = fmt.Sprintf("Duplicate key %q", key)
+			continue
+		}
+		seen[key] = true
+
+		purchasedAtStr := upload.Fields[fmt.Sprintf("purchased_at[%s]", key)]
+		purchasedAt, err := parsePurchasedAt(purchasedAtStr)
+		if err != nil {
+			result.Status = http.StatusBadRequest
+			result.Error = err.Error()
+			continue
+		}
+
+		// A retried recording replays its stored result instead of being processed again
+		idempotencyKey := ""
+		switch {
+		case requestKey != "":
+			idempotencyKey = batchRecordingKey(requestKey, key)
+		case result.ClientRecordingID != "":
+			idempotencyKey = recordingKey(result.ClientRecordingID)
+		}
+		if idempotencyKey != "" {
+			if replayed := h.beginBatchRecording(r, userID, idempotencyKey, purchasedAtStr, f, result); replayed {
+				continue
+			}
+		}
+
+		batch = append(batch, models.ProcessAudioParams{
+			AudioPath:         f.Path,
+			PurchasedAt:       purchasedAt,
+			Location:          location,
+			UserID:            userID,
+			ClientRecordingID: result.ClientRecordingID,
+		})
+		batchIndex = append(batchIndex, i)
+		batchKeys = append(batchKeys, idempotencyKey)
+	}
+
+	ctx := context.WithoutCancel(r.Context())
+	for j, outcome := range h.service.ProcessAudioBatch(r.Context(), batch) {
+		result := &results[batchIndex[j]]
+		if outcome.Err != nil {
+			result.Status, result.Code, result.Error = processingError(outcome.Err)
+		} else {
+			result.Status = http.StatusOK
+			result.Expenses = outcome.Result.Expenses
+			if outcome.Result.Intent != models.VoiceIntentAdd {
+				result.Intent = outcome.Result.Intent
+				result.Changes = outcome.Result.Changes
+			}
+		}
+
+		if batchKeys[j] == "" {
+			continue
+		}
+		if !storableStatus(result.Status) {
+			h.idempotency.Abandon(ctx, userID, batchKeys[j])
+			continue
+		}
+		if body, err := json.Marshal(result); err == nil {
+			h.idempotency.Complete(ctx, userID, batchKeys[j], result.Status, "application/json", body)
+		}
+	}
+
+	response := models.BatchUploadResponse{Results: results}
+	for _, result := range results {
+		if result.Status == http.StatusOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+		// A retry of the request must process the recordings that hit a server error again,
+		// so the response is not stored whole; the others replay from their own keys
+		if !storableStatus(result.Status) {
+			markRetryable(w)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(batchStatus(response))
+	json.NewEncoder(w).Encode(response)
+}
+
+// batchStatus is the status of a batch response: 200 if every recording succeeded, 207 if
+// only some did, and if all failed, their status or, when it differs, the highest of them
+// (a server error, which is worth retrying, over a client error)
+func batchStatus(response models.BatchUploadResponse) int {
+	switch {
+	case response.Failed == 0:
+		return http.StatusOK
+	case response.Succeeded > 0:
+		return http.StatusMultiStatus
+	}
+	status := 0
+	for _, result := range response.Results {
+		status = max(status, result.Status)
+	}
+	return status
+}
+
+// beginBatchRecording claims the idempotency key of a batch recording. It reports true if
+// the recording must not be processed, with result filled in from the stored result or the error.
+func (h *ExpenseHandler) beginBatchRecording(r *http.Request, userID string, key string, purchasedAt string, f uploadedFile, result *models.UploadResult) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		result.Status = http.StatusBadRequest
+		result.Error = fmt.Sprintf("Recording ID exceeds %d characters", maxIdempotencyKeyLength-len(recordingKey("")))
+		return true
+	}
+
+	fingerprint, err := recordingFingerprint(purchasedAt, f)
+	if err != nil {
+		result.Status = http.StatusInternalServerError
+		result.Error = fmt.Sprintf("Failed to process upload: %v", err)
+		return true
+	}
+
+	stored, err := h.idempotency.Begin(r.Context(), userID, key, fingerprint)
+	if err != nil {
+		result.Status, result.Error = idempotencyErrorStatus(err)
+		return true
+	}
+	if stored == nil {
+		return false
+	}
+
+	var replayed models.UploadResult
+	if err := json.Unmarshal(stored.Body, &replayed); err != nil {
+		result.Status = http.StatusInternalServerError
+		result.Error = fmt.Sprintf("Failed to replay stored result: %v", err)
+		return true
+	}
+	result.Status = replayed.Status
+	result.Code = replayed.Code
+	result.Error = replayed.Error
+	result.Expenses = replayed.Expenses
+	result.Intent = replayed.Intent
+	result.Changes = replayed.Changes
+	result.Replayed = true
+	return true
+}
+
+// parsePurchasedAt parses an optional RFC3339 purchase time as UTC, defaulting to now
+func parsePurchasedAt(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+	purchasedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid purchased_at format (expected RFC3339): %v", err)
+	}
+	return purchasedAt.UTC(), nil
+}
+
+// parseDateRange reads the optional from and to query parameters, each either a whole day
+// (YYYY-MM-DD, resolved in the user's timezone by the service) or an RFC3339 time
+func parseDateRange(query url.Values) (models.DateRange, error) {
+	var dateRange models.DateRange
+	bounds := []struct {
+		name string
+		day  *string
+		at   **time.Time
+	}{
+		{"from", &dateRange.FromDay, &dateRange.From},
+		{"to", &dateRange.ToDay, &dateRange.To},
+	}
+	for _, bound := range bounds {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err == nil {
+			*bound.day = value
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return models.DateRange{}, fmt.Errorf("Invalid %s format (expected YYYY-MM-DD or RFC3339): %q", bound.name, value)
+		}
+		*bound.at = &at
+	}
+	return dateRange, nil
+}
+
+// parseTimezone parses an optional IANA timezone name, returning nil if empty
+func parseTimezone(value string) (*time.Location, error) {
+	if value == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid timezone (expected an IANA name like America/Lima): %v", err)
+	}
+	return location, nil
+}
+
+// parseListFilters reads the sorting and filter query parameters shared by listing and exporting
+func parseListFilters(r *http.Request) (models.ListExpensesParams, error) {
+	query := r.URL.Query()
+
+	// Get order[by] (default: created_at)
+	orderBy := query.Get("order[by]")
+	if orderBy == "" {
+		orderBy = "created_at"
+	}
+
+	// Get order[dir] (default: desc)
+	orderDir := query.Get("order[dir]")
+	if orderDir == "" {
+		orderDir = "desc"
+	}
+
+	params := models.ListExpensesParams{
+		OrderBy:             orderBy,
+		OrderDir:            orderDir,
+		PossibleDuplicate:   query.Get("possible_duplicate") == "true",
+		MerchantID:          query.Get("merchant_id"),
+		ReimbursementStatus: query.Get("reimbursement_status"),
+		Query:               strings.TrimSpace(query.Get("q")),
+		UserID:              userIDFromRequest(r),
+	}
+	if params.MerchantID != "" {
+		if _, err := uuid.Parse(params.MerchantID); err != nil {
+			return models.ListExpensesParams{}, errors.New("Invalid merchant ID")
+		}
+	}
+	if params.ReimbursementStatus != "" && !slices.Contains(models.ReimbursementStatuses, params.ReimbursementStatus) {
+		return models.ListExpensesParams{}, fmt.Errorf("Invalid reimbursement_status (expected pending, submitted or reimbursed): %q", params.ReimbursementStatus)
+	}
+	tagFilter, err := parseTagFilter(query)
+	if err != nil {
+		return models.ListExpensesParams{}, err
+	}
+	params.TagFilter = tagFilter
+	dateRange, err := parseDateRange(query)
+	if err != nil {
+		return models.ListExpensesParams{}, err
+	}
+	params.DateRange = dateRange
+	return params, nil
+}
+
+// parseTagFilter reads the optional tags (comma-separated) and tag_match query parameters
+func parseTagFilter(query url.Values) (models.TagFilter, error) {
+	var filter models.TagFilter
+	for _, value := range query["tags"] {
+		filter.Tags = append(filter.Tags, strings.Split(value, ",")...)
+	}
+	filter.TagMatch = query.Get("tag_match")
+	if filter.TagMatch != "" && filter.TagMatch != models.TagMatchAny && filter.TagMatch != models.TagMatchAll {
+		return models.TagFilter{}, fmt.Errorf("Invalid tag_match (expected any or all): %q", filter.TagMatch)
+	}
+	return filter, nil
+}
+
+// parsePagination reads the cursor, page, per_page and include_total query parameters. The
+// total is counted by default for offset pages only, as cursor clients page without it.
+func parsePagination(r *http.Request, params *models.ListExpensesParams) {
+	query := r.URL.Query()
+
+	// Get page (default: 1)
+	params.Page = 1
+	if pageStr := query.Get("page"); pageStr != "" {
+		fmt.Sscanf(pageStr, "%d", &params.Page)
+	}
+
+	// Get per_page (default: 10, max: 100)
+	params.PerPage = 10
+	if perPageStr := query.Get("per_page"); perPageStr != "" {
+		fmt.Sscanf(perPageStr, "%d", &params.PerPage)
+	}
+
+	params.Cursor = query.Get("cursor")
+	params.IncludeTotal = params.Cursor == ""
+	switch query.Get("include_total") {
+	case "true":
+		params.IncludeTotal = true
+	case "false":
+		params.IncludeTotal = false
+	}
 }
 
 // HandleList handles the listing of expenses with pagination
 // @Summary List expenses with pagination
-// @Description Retrieves a paginated list of expenses with optional sorting
+// @Description Retrieves a paginated list of expenses with optional sorting. Pages with more expenses after them return next_cursor; passing it back as cursor (with the same order) continues after the last expense seen, so expenses created meanwhile are neither skipped nor repeated. With q, only expenses whose description or recording transcription match are listed, best match first, each with a match holding its rank and excerpts with the matching words in <mark> tags.
 // @Tags expenses
 // @Produce json
+// @Param cursor query string false "next_cursor of the previous page (page is ignored when set)"
 // @Param page query int false "Page number (default: 1)"
 // @Param per_page query int false "Items per page (default: 10, max: 100)"
+// @Param include_total query bool false "Count total and total_pages (default: true with page, false with cursor)"
 // @Param order[by] query string false "Sort field: purchased_at or created_at (default: created_at)"
 // @Param order[dir] query string false "Sort direction: asc or desc (default: desc)"
+// @Param possible_duplicate query bool false "Only expenses flagged as likely duplicates"
+// @Param merchant_id query string false "Only expenses of this merchant"
+// @Param reimbursement_status query string false "Only reimbursable expenses in this status: pending, submitted or reimbursed"
+// @Param tags query string false "Only expenses with these tags (comma-separated)"
+// @Param tag_match query string false "any: expenses with any of the tags; all: with all of them (default: any)"
+// @Param q query string false "Full-text search over descriptions and transcriptions (Spanish stemming, accents ignored; phrases in double quotes, or and -word supported). Overrides the order."
+// @Param from query string false "Only expenses purchased on or after this day (YYYY-MM-DD, in the timezone of the X-User-ID user) or RFC3339 time"
+// @Param to query string false "Only expenses purchased on or before this day (YYYY-MM-DD, in the timezone of the X-User-ID user) or RFC3339 time"
+// @Param X-User-ID header string false "User whose expenses are listed and whose timezone applies to day filters (default: default)"
 // @Success 200 {object} models.PaginatedExpenses "Paginated list of expenses"
+// @Failure 400 {object} map[string]string "Invalid merchant ID, reimbursement_status, tag_match, from, to or cursor"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /expenses [get]
 func (h *ExpenseHandler) HandleList(w http.ResponseWriter, r *http.Request) {
@@ -116,50 +511,533 @@ func (h *ExpenseHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query parameters
+	// Create params
+	params, err := parseListFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parsePagination(r, &params)
+
+	// Call service
+	result, err := h.service.ListExpenses(r.Context(), params)
+	if errors.Is(err, services.ErrInvalidDateRange) || errors.Is(err, repositories.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list expenses: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleSummary handles summarizing the user's expenses
+// @Summary Summarize expenses
+// @Description Returns the total and count of the expenses of the user identified by the X-User-ID header, optionally within a purchase date range and grouped. With group_by=merchant there is one group per merchant (key is the merchant ID, empty for expenses without a merchant), largest total first. With group_by=tag there is one group per tag (key is the tag, empty for untagged expenses), largest total first; an expense with several tags counts in each of their groups, but once in the summary totals. With group_by=day there is one group per purchase day in the user's timezone (key is YYYY-MM-DD), in date order. Imported transactions matched to a recorded expense by reconciliation are left out, since the recorded expense is the same purchase.
+// @Tags expenses
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param group_by query string false "Grouping: merchant, tag or day"
+// @Param tags query string false "Only expenses with these tags (comma-separated)"
+// @Param tag_match query string false "any: expenses with any of the tags; all: with all of them (default: any)"
+// @Param from query string false "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time"
+// @Param to query string false "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time"
+// @Success 200 {object} models.ExpenseSummary "Expense totals"
+// @Failure 400 {object} map[string]string "Invalid group_by, tag_match, from or to"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/summary [get]
+func (h *ExpenseHandler) HandleSummary(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	// Get page (default: 1)
-	page := 1
-	if pageStr := query.Get("page"); pageStr != "" {
-		fmt.Sscanf(pageStr, "%d", &page)
+	dateRange, err := parseDateRange(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Get per_page (default: 10, max: 100)
-	perPage := 10
-	if perPageStr := query.Get("per_page"); perPageStr != "" {
-		fmt.Sscanf(perPageStr, "%d", &perPage)
+	tagFilter, err := parseTagFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Get order[by] (default: created_at)
-	orderBy := query.Get("order[by]")
-	if orderBy == "" {
-		orderBy = "created_at"
+	params := models.SummaryParams{
+		UserID:    userIDFromRequest(r),
+		GroupBy:   query.Get("group_by"),
+		DateRange: dateRange,
+		TagFilter: tagFilter,
 	}
 
-	// Get order[dir] (default: desc)
-	orderDir := query.Get("order[dir]")
-	if orderDir == "" {
-		orderDir = "desc"
+	summary, err := h.service.Summarize(r.Context(), params)
+	if errors.Is(err, services.ErrUnsupportedGrouping) || errors.Is(err, services.ErrInvalidDateRange) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to summarize expenses: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	// Create params
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// HandleReview handles the listing of expenses that need review
+// @Summary List expenses that need review
+// @Description Retrieves a paginated list of the user's low-confidence expenses (status needs_review), oldest first
+// @Tags review
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param cursor query string false "next_cursor of the previous page (page is ignored when set)"
+// @Param page query int false "Page number (default: 1)"
+// @Param per_page query int false "Items per page (default: 10, max: 100)"
+// @Param include_total query bool false "Count total and total_pages (default: true with page, false with cursor)"
+// @Success 200 {object} models.PaginatedExpenses "Paginated list of expenses that need review"
+// @Failure 400 {object} map[string]string "Invalid cursor"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /review [get]
+func (h *ExpenseHandler) HandleReview(w http.ResponseWriter, r *http.Request) {
 	params := models.ListExpensesParams{
-		Page:     page,
-		PerPage:  perPage,
-		OrderBy:  orderBy,
-		OrderDir: orderDir,
+		OrderBy:  "created_at",
+		OrderDir: "asc",
+		Status:   models.ExpenseStatusNeedsReview,
+		UserID:   userIDFromRequest(r),
 	}
+	parsePagination(r, &params)
 
-	// Call service
 	result, err := h.service.ListExpenses(r.Context(), params)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list expenses: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to list expenses for review: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Return success
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
+
+// HandleDelete handles deleting an expense
+// @Summary Delete an expense
+// @Description Soft-deletes an expense: it is no longer listed, exported, summarized or matched, but stays in the database with its history and can be restored with POST /expenses/{id}/restore. Expenses flagged as its duplicates are unflagged until it is restored. An expense in a report not submitted yet leaves the report and is restored without one.
+// @Tags expenses
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Success 200 {object} models.Expense "Deleted expense, as it was before the deletion"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense not found, of another user or already deleted"
+// @Failure 409 {object} map[string]string "Expense was claimed in a submitted report"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id} [delete]
+func (h *ExpenseHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.DeleteExpense(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseClaimed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleRestore handles restoring a deleted expense
+// @Summary Restore a deleted expense
+// @Description Undoes the deletion of an expense, by DELETE /expenses/{id}, a spoken deletion or a duplicate merge. Expenses its deletion unflagged as duplicates are flagged again, and its reconciliation matches count again.
+// @Tags expenses
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Success 200 {object} models.Expense "Restored expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense not found or of another user"
+// @Failure 409 {object} map[string]string "Expense is not deleted"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/restore [post]
+func (h *ExpenseHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.RestoreExpense(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseNotDeleted) {
+		http.Error(w, "Expense is not deleted", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to restore expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleHistory handles the audit history of an expense
+// @Summary Expense history
+// @Description Lists the changes made to an expense, deleted or not, oldest first: its creation, updates (corrections, confirmation, duplicate flags), deletion and restoration, each with the user who made it and the expense before and after
+// @Tags expenses
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to (default: default)"
+// @Param id path string true "Expense ID"
+// @Success 200 {array} models.ExpenseEvent "Events, oldest first"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense not found or of another user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/history [get]
+func (h *ExpenseHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.service.ExpenseHistory(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get expense history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// HandleConfirm handles confirming a reviewed expense
+// @Summary Confirm an expense
+// @Description Marks an expense as confirmed, removing it from the review queue
+// @Tags review
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Success 200 {object} models.Expense "Confirmed expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense not found or of another user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/confirm [post]
+func (h *ExpenseHandler) HandleConfirm(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.ConfirmExpense(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to confirm expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleUpdate handles partial updates of an expense
+// @Summary Update an expense
+// @Description Changes the unit price, quantity, unit or description of an expense; omitted fields are left unchanged. Used to undo a spoken correction with the before values it returned.
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Param update body models.ExpenseUpdate true "Fields to change"
+// @Success 200 {object} models.Expense "Updated expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID, or empty or out of bounds update"
+// @Failure 404 {object} map[string]string "Expense not found or of another user"
+// @Failure 409 {object} map[string]string "Expense was claimed in a submitted report"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id} [patch]
+func (h *ExpenseHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	var update models.ExpenseUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.UpdateExpense(r.Context(), id, update, userIDFromRequest(r))
+	if errors.Is(err, services.ErrInvalidExpenseUpdate) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseClaimed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleReimbursable handles flagging an expense as reimbursable
+// @Summary Flag an expense as reimbursable
+// @Description Flags an expense paid personally for work as reimbursable, with reimbursement_status pending until it is claimed in a submitted report (see POST /reports), or unflags it, taking it out of its report. Expenses of submitted reports cannot be changed.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User making the change, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Param request body models.ReimbursableRequest true "Whether the expense is reimbursable"
+// @Success 200 {object} models.Expense "Updated expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID or request body"
+// @Failure 404 {object} map[string]string "Expense not found"
+// @Failure 409 {object} map[string]string "Expense was claimed in a submitted report"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/reimbursable [put]
+func (h *ExpenseHandler) HandleReimbursable(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.ReimbursableRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.SetReimbursable(r.Context(), id, request.Reimbursable, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseClaimed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleTag handles adding tags to an expense
+// @Summary Tag an expense
+// @Description Adds tags to an expense and returns it. Tags are normalized: lowercase, accents folded and words joined by hyphens, so "#Trip Cusco" is "trip-cusco". Tags the expense already has are ignored.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Param request body models.TagExpenseRequest true "Tags to add"
+// @Success 200 {object} models.Expense "Tagged expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID, or no tags, tags longer than 50 characters or more than 20 tags on the expense"
+// @Failure 404 {object} map[string]string "Expense not found or of another user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/tags [post]
+func (h *ExpenseHandler) HandleTag(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.TagExpenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.TagExpense(r.Context(), id, request.Tags, userIDFromRequest(r))
+	if errors.Is(err, services.ErrInvalidTags) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to tag expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleUntag handles removing a tag from an expense
+// @Summary Remove a tag from an expense
+// @Description Removes a tag from an expense and returns it
+// @Tags tags
+// @Produce json
+// @Param X-User-ID header string false "User the expense belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Expense ID"
+// @Param tag path string true "Tag to remove"
+// @Success 200 {object} models.Expense "Untagged expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense not found or of another user, or it does not have the tag"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/tags/{tag} [delete]
+func (h *ExpenseHandler) HandleUntag(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+	tag, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		http.Error(w, "Invalid tag", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.UntagExpense(r.Context(), id, tag, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrTagNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleMergeDuplicate handles merging an expense into the expense it duplicates
+// @Summary Merge a duplicate expense
+// @Description Deletes (soft-deletes, see POST /expenses/{id}/restore) an expense flagged as a possible duplicate and returns the earlier expense it duplicates
+// @Tags duplicates
+// @Produce json
+// @Param X-User-ID header string false "User the expenses belong to, recorded in the expense history (default: default)"
+// @Param id path string true "ID of the expense flagged as duplicate"
+// @Success 200 {object} models.Expense "The kept (original) expense"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense, or the expense it duplicates, not found or of another user"
+// @Failure 409 {object} map[string]string "Expense is not flagged as a possible duplicate, or was claimed in a submitted report"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/duplicate/merge [post]
+func (h *ExpenseHandler) HandleMergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.MergeDuplicate(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrNotFlaggedAsDuplicate) {
+		http.Error(w, "Expense is not flagged as a possible duplicate", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repositories.ErrExpenseClaimed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to merge duplicate expense: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
+
+// HandleDismissDuplicate handles dismissing a duplicate flag
+// @Summary Dismiss a duplicate flag
+// @Description Marks an expense flagged as a possible duplicate as a separate purchase, keeping both expenses
+// @Tags duplicates
+// @Produce json
+// @Param X-User-ID header string false "User the expenses belong to, recorded in the expense history (default: default)"
+// @Param id path string true "ID of the expense flagged as duplicate"
+// @Success 200 {object} models.Expense "The expense, no longer flagged"
+// @Failure 400 {object} map[string]string "Invalid expense ID"
+// @Failure 404 {object} map[string]string "Expense, or the expense it duplicates, not found or of another user"
+// @Failure 409 {object} map[string]string "Expense is not flagged as a possible duplicate"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/{id}/duplicate/dismiss [post]
+func (h *ExpenseHandler) HandleDismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	expense, err := h.service.DismissDuplicate(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrExpenseNotFound) {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrNotFlaggedAsDuplicate) {
+		http.Error(w, "Expense is not flagged as a possible duplicate", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to dismiss duplicate flag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expense)
+}
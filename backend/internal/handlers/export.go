@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/services"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Export formats
+const (
+	exportFormatCSV  = "csv"
+	exportFormatXLSX = "xlsx"
+)
+
+// exportColumns is the header row of an export
+var exportColumns = []string{"purchased_at", "description", "merchant", "quantity", "unit", "unit_price", "total", "status", "id"}
+
+// exportTotalColumn is the (zero-based) index of the total column
+const exportTotalColumn = 6
+
+// exportTimeLayout formats purchase times in CSV exports, in the user's timezone
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// expenseWriter writes the rows of an export
+type expenseWriter interface {
+	WriteExpense(expense *models.Expense) error
+	// Close writes the totals row and flushes the file
+	Close(total float64) error
+	// Discard releases the resources of a failed export
+	Discard()
+}
+
+// numberFormat formats CSV numbers. The zero value writes plain numbers ("1234.5").
+type numberFormat struct {
+	printer  *message.Printer
+	grouping bool
+}
+
+// parseNumberFormat reads the locale (BCP 47 tag) and grouping query parameters
+func parseNumberFormat(locale string, grouping bool) (numberFormat, error) {
+	if locale == "" && !grouping {
+		return numberFormat{}, nil
+	}
+	tag := language.English
+	if locale != "" {
+		parsed, err := language.Parse(locale)
+		if err != nil {
+			return numberFormat{}, fmt.Errorf("Invalid locale (expected a BCP 47 tag like es-PE): %q", locale)
+		}
+		tag = parsed
+	}
+	return numberFormat{printer: message.NewPrinter(tag), grouping: grouping}, nil
+}
+
+// amount formats a price or total with two decimals
+func (f numberFormat) amount(value float64) string {
+	if f.printer == nil {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+	return f.printer.Sprint(number.Decimal(value, f.options(number.Scale(2))...))
+}
+
+// quantity formats a quantity with up to three decimals
+func (f numberFormat) quantity(value float64) string {
+	if f.printer == nil {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return f.printer.Sprint(number.Decimal(value, f.options(number.MaxFractionDigits(3))...))
+}
+
+func (f numberFormat) options(options ...number.Option) []number.Option {
+	if !f.grouping {
+		options = append(options, number.NoSeparator())
+	}
+	return options
+}
+
+// delimiter returns the CSV field separator: a semicolon when the decimal separator is a comma,
+// as spreadsheets of those locales expect
+func (f numberFormat) delimiter() rune {
+	if strings.Contains(f.amount(0.5), ",") {
+		return ';'
+	}
+	return ','
+}
+
+type csvExpenseWriter struct {
+	writer *csv.Writer
+	format numberFormat
+}
+
+func newCSVExpenseWriter(w io.Writer, format numberFormat) (*csvExpenseWriter, error) {
+	writer := csv.NewWriter(w)
+	writer.Comma = format.delimiter()
+	if err := writer.Write(exportColumns); err != nil {
+		return nil, err
+	}
+	return &csvExpenseWriter{writer: writer, format: format}, nil
+}
+
+func (c *csvExpenseWriter) WriteExpense(expense *models.Expense) error {
+	return c.writer.Write([]string{
+		expense.PurchasedAt.Format(exportTimeLayout),
+		expense.Description,
+		expense.Merchant,
+		c.format.quantity(expense.Quantity),
+		expense.Unit,
+		c.format.amount(expense.UnitPrice),
+		c.format.amount(expense.Total()),
+		expense.Status,
+		expense.ID,
+	})
+}
+
+func (c *csvExpenseWriter) Close(total float64) error {
+	row := make([]string, len(exportColumns))
+	row[0] = "total"
+	row[exportTotalColumn] = c.format.amount(total)
+	if err := c.writer.Write(row); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvExpenseWriter) Discard() {}
+
+// xlsxExpenseWriter writes a workbook with typed cells; the spreadsheet application
+// formats numbers and dates for the reader's locale
+type xlsxExpenseWriter struct {
+	w          io.Writer
+	file       *excelize.File
+	stream     *excelize.StreamWriter
+	row        int
+	dateStyle  int
+	moneyStyle int
+}
+
+func newXLSXExpenseWriter(w io.Writer, grouping bool) (*xlsxExpenseWriter, error) {
+	file := excelize.NewFile()
+	const sheet = "Expenses"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	moneyFormat := "0.00"
+	if grouping {
+		moneyFormat = "#,##0.00"
+	}
+	dateFormat := "yyyy-mm-dd hh:mm"
+	x := &xlsxExpenseWriter{w: w, file: file, stream: stream, row: 1}
+	if x.dateStyle, err = file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat}); err != nil {
+		return nil, err
+	}
+	if x.moneyStyle, err = file.NewStyle(&excelize.Style{CustomNumFmt: &moneyFormat}); err != nil {
+		return nil, err
+	}
+	headerStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: column}
+	}
+	if err := x.writeRow(header); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxExpenseWriter) writeRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	x.row++
+	return x.stream.SetRow(cell, values)
+}
+
+func (x *xlsxExpenseWriter) WriteExpense(expense *models.Expense) error {
+	// Spreadsheet dates have no timezone: keep the local wall clock
+	local := expense.PurchasedAt
+	purchasedAt := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+	return x.writeRow([]interface{}{
+		excelize.Cell{StyleID: x.dateStyle, Value: purchasedAt},
+		expense.Description,
+		expense.Merchant,
+		expense.Quantity,
+		expense.Unit,
+		excelize.Cell{StyleID: x.moneyStyle, Value: expense.UnitPrice},
+		excelize.Cell{StyleID: x.moneyStyle, Value: expense.Total()},
+		expense.Status,
+		expense.ID,
+	})
+}
+
+func (x *xlsxExpenseWriter) Close(total float64) error {
+	defer x.file.Close()
+
+	totalCell := excelize.Cell{StyleID: x.moneyStyle, Value: total}
+	if x.row > 2 {
+		column, err := excelize.ColumnNumberToName(exportTotalColumn + 1)
+		if err != nil {
+			return err
+		}
+		totalCell.Formula = fmt.Sprintf("SUM(%s2:%s%d)", column, column, x.row-1)
+	}
+	row := make([]interface{}, exportTotalColumn+1)
+	row[0] = "total"
+	row[exportTotalColumn] = totalCell
+	if err := x.writeRow(row); err != nil {
+		return err
+	}
+
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
+
+func (x *xlsxExpenseWriter) Discard() {
+	x.file.Close()
+}
+
+// responseStarted records whether anything was written to a response, after which
+// a failed export can no longer be reported with an error status
+type responseStarted struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (s *responseStarted) Write(p []byte) (int, error) {
+	s.started = true
+	return s.ResponseWriter.Write(p)
+}
+
+// HandleExport handles exporting expenses as a file
+// @Summary Export expenses
+// @Description Downloads every expense matching the list filters as CSV or XLSX, without pagination. Each row has the expense total (unit_price × quantity) and the last row the grand total. Purchase times are in the timezone of the X-User-ID user. CSV numbers are plain ("1234.50", comma-separated) unless locale or grouping is given; locales with a decimal comma ("es", "es-ES") use semicolons between fields. XLSX cells are numbers and dates that the spreadsheet formats for its own locale.
+// @Tags expenses
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string true "File format: csv or xlsx"
+// @Param locale query string false "BCP 47 locale for CSV number formatting (e.g. es-PE, es)"
+// @Param grouping query bool false "Group thousands (1,234.50) in CSV numbers and XLSX amounts"
+// @Param order[by] query string false "Sort field: purchased_at or created_at (default: created_at)"
+// @Param order[dir] query string false "Sort direction: asc or desc (default: desc)"
+// @Param possible_duplicate query bool false "Only expenses flagged as likely duplicates"
+// @Param merchant_id query string false "Only expenses of this merchant"
+// @Param reimbursement_status query string false "Only reimbursable expenses in this status: pending, submitted or reimbursed"
+// @Param tags query string false "Only expenses with these tags (comma-separated)"
+// @Param tag_match query string false "any: expenses with any of the tags; all: with all of them (default: any)"
+// @Param q query string false "Only expenses matching this full-text search (kept in the chosen order)"
+// @Param from query string false "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time"
+// @Param to query string false "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time"
+// @Param X-User-ID header string false "User whose expenses are exported and whose timezone applies to days and times (default: default)"
+// @Success 200 {file} file "Export file, named expenses-YYYY-MM-DD.csv or .xlsx"
+// @Failure 400 {object} map[string]string "Invalid format, locale, merchant ID, reimbursement_status, tag_match, from or to"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /expenses/export [get]
+func (h *ExpenseHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format != exportFormatCSV && format != exportFormatXLSX {
+		http.Error(w, "Invalid format (expected csv or xlsx)", http.StatusBadRequest)
+		return
+	}
+	grouping := query.Get("grouping") == "true"
+	numbers, err := parseNumberFormat(query.Get("locale"), grouping)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params, err := parseListFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := &responseStarted{ResponseWriter: w}
+	var writer expenseWriter
+	contentType := "text/csv; charset=utf-8"
+	if format == exportFormatXLSX {
+		writer, err = newXLSXExpenseWriter(response, grouping)
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	} else {
+		writer, err = newCSVExpenseWriter(response, numbers)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export expenses: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("expenses-%s.%s", time.Now().UTC().Format(time.DateOnly), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	var total float64
+	err = h.service.ExportExpenses(r.Context(), params, func(expense *models.Expense) error {
+		total += expense.Total()
+		return writer.WriteExpense(expense)
+	})
+	if err == nil {
+		err = writer.Close(total)
+	}
+	if err == nil {
+		return
+	}
+	writer.Discard()
+
+	// Once rows were sent the status is already 200: cut the file short instead, so the
+	// client sees a failed download rather than a complete-looking file. The Lambda handler,
+	// which buffers the response, turns this into a 500.
+	if response.started {
+		panic(http.ErrAbortHandler)
+	}
+	w.Header().Del("Content-Disposition")
+	if errors.Is(err, services.ErrInvalidDateRange) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Failed to export expenses: %v", err), http.StatusInternalServerError)
+}
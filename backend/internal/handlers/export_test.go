@@ -0,0 +1,181 @@
+package handlers_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// uploadGroceries records 2 kg of rice (2 × 1500.5) and a litre of oil (12.25) for a user
+func uploadGroceries(t *testing.T, h *harness, userID string) {
+	t.Helper()
+	h.openai.QueueTranscription(spanish("dos kilos de arroz a mil quinientos cincuenta y un litro de aceite a doce veinticinco"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 1500.5, Quantity: 2, Unit: "kg", Description: "arroz"},
+		{UnitPrice: 12.25, Quantity: 1, Unit: "litro", Description: "aceite"},
+	})
+	rec := h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-21T03:00:00Z"}, map[string]string{"X-User-ID": userID})
+	decode[[]models.Expense](t, rec, http.StatusOK)
+}
+
+func exportCSV(t *testing.T, h *harness, query string, comma rune) [][]string {
+	t.Helper()
+	rec := h.do(http.MethodGet, "/expenses/export?"+query, nil, map[string]string{"X-User-ID": "sam"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	reader := csv.NewReader(rec.Body)
+	reader.Comma = comma
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	return records
+}
+
+// exportRow returns the exported row of an expense
+func exportRow(t *testing.T, records [][]string, description string) []string {
+	t.Helper()
+	for _, record := range records {
+		if record[1] == description {
+			return record
+		}
+	}
+	t.Fatalf("no %s row in %v", description, records)
+	return nil
+}
+
+func TestExportCSV(t *testing.T) {
+	h := newHarness(t)
+	setTimezone(t, h, "sam", "America/Lima")
+	uploadGroceries(t, h, "sam")
+
+	rec := h.do(http.MethodGet, "/expenses/export?format=csv", nil, map[string]string{"X-User-ID": "sam"})
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="expenses-`) || !strings.HasSuffix(disposition, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", disposition)
+	}
+
+	records := exportCSV(t, h, "format=csv", ',')
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want header, 2 expenses and totals: %v", len(records), records)
+	}
+	if strings.Join(records[0], ",") != "purchased_at,description,merchant,quantity,unit,unit_price,total,status,id" {
+		t.Errorf("header = %v", records[0])
+	}
+	// Times are in the user's timezone
+	if rice := exportRow(t, records, "arroz"); rice[0] != "2026-02-20 22:00:00" || rice[1] != "arroz" || rice[3] != "2" || rice[5] != "1500.50" || rice[6] != "3001.00" {
+		t.Errorf("rice row = %v", rice)
+	}
+	if totals := records[3]; totals[0] != "total" || totals[6] != "3013.25" {
+		t.Errorf("totals row = %v", totals)
+	}
+}
+
+func TestExportCSVLocaleFormatting(t *testing.T) {
+	h := newHarness(t)
+	uploadGroceries(t, h, "sam")
+
+	// A decimal comma switches the delimiter to a semicolon
+	records := exportCSV(t, h, "format=csv&locale=es&grouping=true", ';')
+	if rice := exportRow(t, records, "arroz"); rice[5] != "1.500,50" || rice[6] != "3.001,00" {
+		t.Errorf("rice row with locale es = %v", rice)
+	}
+
+	records = exportCSV(t, h, "format=csv&locale=es-PE&grouping=true", ',')
+	if totals := records[len(records)-1]; totals[6] != "3,013.25" {
+		t.Errorf("totals row with locale es-PE = %v", totals)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	h := newHarness(t)
+	uploadGroceries(t, h, "sam")
+
+	rec := h.do(http.MethodGet, "/expenses/export?format=xlsx", nil, map[string]string{"X-User-ID": "sam"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.HasSuffix(disposition, `.xlsx"`) {
+		t.Errorf("Content-Disposition = %q", disposition)
+	}
+
+	file, err := excelize.OpenReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid workbook: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows("Expenses")
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("got %d rows (%v), want header, 2 expenses and totals", len(rows), err)
+	}
+	if rows[1][1] != "arroz" && rows[2][1] != "arroz" {
+		t.Errorf("rows = %v, want rice", rows)
+	}
+	if formula, _ := file.GetCellFormula("Expenses", "G4"); formula != "SUM(G2:G3)" {
+		t.Errorf("total formula = %q", formula)
+	}
+	if total, _ := file.GetCellValue("Expenses", "G4", excelize.Options{RawCellValue: true}); total != "3013.25" {
+		t.Errorf("total = %q, want 3013.25", total)
+	}
+}
+
+func TestExportRejectsInvalidParameters(t *testing.T) {
+	h := newHarness(t)
+
+	for _, query := range []string{"", "format=pdf", "format=csv&locale=not_a_locale!", "format=csv&from=yesterday"} {
+		if rec := h.do(http.MethodGet, "/expenses/export?"+query, nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestExportOnlyIncludesTheUsersExpenses(t *testing.T) {
+	h := newHarness(t)
+	uploadGroceries(t, h, "sam")
+
+	rec := h.do(http.MethodGet, "/expenses/export?format=csv", nil, map[string]string{"X-User-ID": "ana"})
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("status = %d, %v", rec.Code, err)
+	}
+	// Only the header and the grand total
+	if len(records) != 2 {
+		t.Errorf("export of another user = %v, want no expense rows", records)
+	}
+}
+
+func TestExportFailingMidwayThroughLambda(t *testing.T) {
+	h := newHarness(t)
+	// Enough rows to fill the CSV buffer, so the response has started when the export fails
+	for i := range 200 {
+		h.expenses.Create(t.Context(), &models.Expense{
+			ID: uuid.New().String(), UserID: "sam", UnitPrice: 1, Quantity: 1, Unit: "u",
+			Description: fmt.Sprintf("item %d", i), PurchasedAt: time.Now(), CreatedAt: time.Now(),
+		})
+	}
+	h.expenses.ExportErr = errors.New("connection lost")
+
+	request := events.APIGatewayV2HTTPRequest{
+		RawPath:               "/expenses/export",
+		Headers:               map[string]string{"X-User-ID": "sam"},
+		QueryStringParameters: map[string]string{"format": "csv"},
+	}
+	request.RequestContext.HTTP.Method = http.MethodGet
+	response, err := h.lambda.Handle(t.Context(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusInternalServerError || !strings.Contains(response.Body, "aborted") {
+		t.Errorf("status = %d, body %q, want a 500 instead of a cut file", response.StatusCode, response.Body)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"upload-lambda/internal/audio"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/handlers"
+	"upload-lambda/internal/prompts"
+	"upload-lambda/internal/repositories"
+	"upload-lambda/internal/services"
+)
+
+// harness wires the real router and services to a fake OpenAI server and in-memory repositories
+type harness struct {
+	t          *testing.T
+	openai     *fakes.OpenAIServer
+	expenses   *fakes.ExpenseRepository
+	recordings *fakes.RecordingRepository
+	receipts   *fakes.ReceiptRepository
+	settings   *fakes.SettingsRepository
+	keys       *fakes.IdempotencyRepository
+	merchants  *fakes.MerchantRepository
+	matches    *fakes.ReconciliationRepository
+	reports    *fakes.ReportRepository
+	router     http.Handler
+	lambda     *handlers.LambdaHandler
+}
+
+// noRetries fails on the first upstream error and never opens the circuit
+func noRetries() repositories.ResilienceConfig {
+	return repositories.ResilienceConfig{
+		MaxRetries:     0,
+		BaseDelay:      time.Millisecond,
+		MaxDelay:       time.Millisecond,
+		AttemptTimeout: 5 * time.Second,
+	}
+}
+
+func newHarness(t *testing.T) *harness {
+	return newHarnessWithResilience(t, noRetries())
+}
+
+func newHarnessWithResilience(t *testing.T, resilience repositories.ResilienceConfig) *harness {
+	t.Helper()
+
+	registry, err := prompts.Load("")
+	if err != nil {
+		t.Fatalf("failed to load prompts: %v", err)
+	}
+
+	h := &harness{
+		t:          t,
+		openai:     fakes.NewOpenAIServer(),
+		expenses:   fakes.NewExpenseRepository(),
+		recordings: fakes.NewRecordingRepository(),
+		receipts:   fakes.NewReceiptRepository(),
+		settings:   fakes.NewSettingsRepository(),
+		keys:       fakes.NewIdempotencyRepository(),
+		merchants:  fakes.NewMerchantRepository(),
+	}
+	t.Cleanup(h.openai.Close)
+	h.expenses.Recordings = h.recordings
+	h.matches = fakes.NewReconciliationRepository(h.expenses)
+	h.expenses.Matches = h.matches
+	h.reports = fakes.NewReportRepository(h.expenses)
+
+	openaiConfig := repositories.OpenAIConfig{
+		APIKey:     "test-key",
+		BaseURL:    h.openai.BaseURL(),
+		Resilience: resilience,
+	}
+	openaiRepo := repositories.NewOpenAIRepository(openaiConfig, registry)
+	ocrRepo := repositories.NewOpenAIVisionOCR(openaiConfig)
+
+	settingsService := services.NewSettingsService(h.settings)
+	audioProcessor := audio.NewProcessor(audio.DefaultLimits(), "")
+	merchantService := services.NewMerchantService(h.merchants)
+	expenseService := services.NewExpenseService(openaiRepo, h.expenses, h.recordings, h.receipts, ocrRepo, settingsService, merchantService, audioProcessor)
+	idempotencyService := services.NewIdempotencyService(h.keys)
+	reconciliationService := services.NewReconciliationService(h.expenses, h.matches, settingsService)
+	askService := services.NewAskService(openaiRepo, expenseService, settingsService, merchantService, audioProcessor)
+	reportService := services.NewReportService(h.reports, h.receipts, settingsService)
+	h.router = handlers.NewRouter(expenseService, settingsService, idempotencyService, merchantService, reconciliationService, askService, reportService)
+	h.lambda = handlers.NewLambdaHandler(expenseService, settingsService, idempotencyService, merchantService, reconciliationService, askService, reportService)
+	return h
+}
+
+// do sends a request through the router
+func (h *harness) do(method, path string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	req := httptest.NewRequest(method, path, body)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// upload posts a multipart form with an optional audio part and extra fields
+func (h *harness) upload(audio []byte, fields map[string]string, headers map[string]string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.postForm("/upload", audio, fields, headers)
+}
+
+// postForm posts a multipart form with an optional audio part and extra fields to path
+func (h *harness) postForm(path string, audio []byte, fields map[string]string, headers map[string]string) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	if audio != nil {
+		part, err := writer.CreateFormFile("audio", "note.m4a")
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		part.Write(audio)
+	}
+	if err := writer.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Content-Type"] = writer.FormDataContentType()
+	return h.do(http.MethodPost, path, &body, headers)
+}
+
+// decode unmarshals a JSON response, failing the test on unexpected status codes
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder, wantStatus int) T {
+	t.Helper()
+	var v T
+	if rec.Code != wantStatus {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, wantStatus, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+// spanish is a confident Spanish transcription
+func spanish(text string) fakes.Transcription {
+	return fakes.Transcription{
+		Text:     text,
+		Language: "spanish",
+		Duration: 3,
+		Segments: []fakes.Segment{{Start: 0, End: 3, Text: text, AvgLogprob: -0.05}},
+	}
+}
+
+// expenseJSON is the model output for one expense
+type expenseJSON struct {
+	UnitPrice   float64  `json:"unit_price"`
+	Quantity    float64  `json:"quantity"`
+	Unit        string   `json:"unit"`
+	Description string   `json:"description"`
+	Merchant    string   `json:"merchant,omitempty"`
+	DaysAgo     *int     `json:"days_ago,omitempty"`
+	PurchasedAt string   `json:"purchased_at,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Confidence  *float64 `json:"confidence,omitempty"`
+}
+
+func confidence(v float64) *float64 {
+	return &v
+}
+
+// fakeAudio starts with an m4a "ftyp" box so it passes content sniffing
+var fakeAudio = append([]byte("\x00\x00\x00\x18ftypM4A \x00\x00\x00\x00"), []byte("fake-m4a-audio")...)
+
+// silentWAV is one second of 8-bit mono PCM silence
+func silentWAV() []byte {
+	const rate = 8000
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+rate))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(rate), uint16(1), uint16(8)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(rate))
+	buf.Write(bytes.Repeat([]byte{128}, rate))
+	return buf.Bytes()
+}
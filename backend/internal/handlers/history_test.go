@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+func TestDeleteRestoreAndHistory(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+	recordExpense(h, "leche", 3)
+	ana := map[string]string{"X-User-ID": "ana"}
+
+	decode[models.Expense](t, h.do(http.MethodPatch, "/expenses/"+bread.ID, strings.NewReader(`{"unit_price": 5}`), nil), http.StatusOK)
+	if rec := h.do(http.MethodDelete, "/expenses/"+bread.ID, nil, ana); rec.Code != http.StatusNotFound {
+		t.Errorf("deleting another user's expense: status = %d, want 404", rec.Code)
+	}
+	deleted := decode[models.Expense](t, h.do(http.MethodDelete, "/expenses/"+bread.ID, nil, nil), http.StatusOK)
+	if deleted.ID != bread.ID || deleted.UnitPrice != 5 {
+		t.Errorf("deleted = %+v", deleted)
+	}
+
+	// Deleted expenses are not listed, searched, summarized or changed
+	list := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses", nil, nil), http.StatusOK)
+	if *list.Total != 1 || list.Data[0].Description != "leche" {
+		t.Errorf("listed = %+v, want only leche", list.Data)
+	}
+	if found := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses?q="+url.QueryEscape("pan"), nil, nil), http.StatusOK); len(found.Data) != 0 {
+		t.Errorf("search found %+v", found.Data)
+	}
+	if summary := decode[models.ExpenseSummary](t, h.do(http.MethodGet, "/expenses/summary", nil, nil), http.StatusOK); summary.Total != 3 {
+		t.Errorf("summary total = %v, want 3", summary.Total)
+	}
+	for _, rec := range []*httptest.ResponseRecorder{
+		h.do(http.MethodDelete, "/expenses/"+bread.ID, nil, nil),
+		h.do(http.MethodPatch, "/expenses/"+bread.ID, strings.NewReader(`{"unit_price": 1}`), nil),
+		h.do(http.MethodPost, "/expenses/"+bread.ID+"/confirm", nil, nil),
+	} {
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("change of a deleted expense: status = %d, want 404", rec.Code)
+		}
+	}
+
+	// Deleted or not, other users do not see it
+	for _, rec := range []*httptest.ResponseRecorder{
+		h.do(http.MethodPost, "/expenses/"+bread.ID+"/restore", nil, ana),
+		h.do(http.MethodGet, "/expenses/"+bread.ID+"/history", nil, ana),
+	} {
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("another user's deleted expense: status = %d, want 404", rec.Code)
+		}
+	}
+
+	restored := decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+bread.ID+"/restore", nil, nil), http.StatusOK)
+	if restored.DeletedAt != nil || restored.UnitPrice != 5 {
+		t.Errorf("restored = %+v", restored)
+	}
+	if rec := h.do(http.MethodPost, "/expenses/"+bread.ID+"/restore", nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("restoring twice: status = %d, want 409", rec.Code)
+	}
+	if list := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses", nil, nil), http.StatusOK); *list.Total != 2 {
+		t.Errorf("listed %d expenses after restoring, want 2", *list.Total)
+	}
+
+	events := decode[[]models.ExpenseEvent](t, h.do(http.MethodGet, "/expenses/"+bread.ID+"/history", nil, nil), http.StatusOK)
+	var actions, actors []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		actors = append(actors, event.Actor)
+	}
+	if strings.Join(actions, ",") != "create,update,delete,restore" || strings.Join(actors, ",") != "default,default,default,default" {
+		t.Fatalf("history = %v by %v", actions, actors)
+	}
+	if events[0].Before != nil {
+		t.Errorf("creation has a before: %s", events[0].Before)
+	}
+	var before, after models.Expense
+	json.Unmarshal(events[1].Before, &before)
+	json.Unmarshal(events[1].After, &after)
+	if before.UnitPrice != 4 || after.UnitPrice != 5 {
+		t.Errorf("update before %v, after %v, want 4 and 5", before.UnitPrice, after.UnitPrice)
+	}
+	json.Unmarshal(events[2].After, &after)
+	if after.DeletedAt == nil {
+		t.Error("deletion does not record deleted_at")
+	}
+}
+
+func TestVoiceDeletionCanBeRestored(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+
+	h.openai.QueueTranscription(spanish("borra el pan"))
+	h.openai.QueueChatCompletion(`{"intent": "delete", "target": {"description": "pan"}}`)
+	decode[models.VoiceCommandResult](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+bread.ID+"/restore", nil, nil), http.StatusOK)
+	if len(h.expenses.All()) != 1 {
+		t.Error("restored expense is not stored")
+	}
+}
+
+func TestHistoryOfUnknownExpense(t *testing.T) {
+	h := newHarness(t)
+	if rec := h.do(http.MethodGet, "/expenses/0d6c3f1e-9a55-4f43-9a0e-000000000000/history", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if rec := h.do(http.MethodGet, "/expenses/not-a-uuid/history", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
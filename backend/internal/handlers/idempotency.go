@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"upload-lambda/internal/services"
+)
+
+// Idempotency headers
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength bounds client-supplied idempotency keys
+const maxIdempotencyKeyLength = 255
+
+// recordingKey is the idempotency key derived from a client recording ID
+func recordingKey(clientRecordingID string) string {
+	return "recording:" + clientRecordingID
+}
+
+// batchRecordingKey is the idempotency key of one recording of a batch sent with an
+// Idempotency-Key header. It is hashed to stay within maxIdempotencyKeyLength.
+func batchRecordingKey(requestKey string, key string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s", requestKey, key)))
+	return "batch:" + hex.EncodeToString(hash[:])
+}
+
+// captureWriter passes a response through while keeping a copy to store for replays
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	// retryable is set for responses that must not be stored despite their status
+	retryable bool
+}
+
+// markRetryable keeps the response being written to w from being stored for replays
+func markRetryable(w http.ResponseWriter) {
+	if capture, ok := w.(*captureWriter); ok {
+		capture.retryable = true
+	}
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// storableStatus reports whether a response is final for its payload. Server errors
+// (including OpenAI outages) are not stored, so a retry gets processed again.
+func storableStatus(status int) bool {
+	return status < http.StatusInternalServerError
+}
+
+// uploadFingerprint hashes what identifies an upload: its fields and the content of its
+// files. Multipart boundaries and part order of fields differ between retries and are ignored.
+func uploadFingerprint(mode string, upload *multipartUpload) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", mode)
+
+	names := make([]string, 0, len(upload.Fields))
+	for name := range upload.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(hash, "field %q=%q\n", name, upload.Fields[name])
+	}
+
+	for _, f := range upload.Files {
+		sum, err := fileDigest(f.Path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "file %q=%s\n", f.Field, sum)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// recordingFingerprint hashes one recording of a batch: its purchase time and audio content
+func recordingFingerprint(purchasedAt string, f uploadedFile) (string, error) {
+	sum, err := fileDigest(f.Path)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("batch-recording\npurchased_at=%q\naudio=%s\n", purchasedAt, sum)))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// fileDigest returns the hex SHA-256 of a file
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash upload: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// idempotencyErrorStatus maps idempotency errors to HTTP status codes
+func idempotencyErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, fmt.Sprintf("Idempotency key reused: %v", err)
+	case errors.Is(err, services.ErrIdempotencyInProgress):
+		return http.StatusConflict, fmt.Sprintf("Retry later: %v", err)
+	}
+	return http.StatusInternalServerError, fmt.Sprintf("Failed to check idempotency key: %v", err)
+}
+
+// serveIdempotent replays the stored response for key, or runs serve and stores its response
+func serveIdempotent(w http.ResponseWriter, r *http.Request, idempotency services.IdempotencyService, key string, fingerprint string, serve func(w http.ResponseWriter)) {
+	userID := userIDFromRequest(r)
+
+	stored, err := idempotency.Begin(r.Context(), userID, key, fingerprint)
+	if err != nil {
+		status, message := idempotencyErrorStatus(err)
+		http.Error(w, message, status)
+		return
+	}
+	if stored != nil {
+		w.Header().Set("Content-Type", stored.ContentType)
+		w.Header().Set(idempotencyReplayedHeader, "true")
+		w.WriteHeader(stored.StatusCode)
+		w.Write(stored.Body)
+		return
+	}
+
+	capture := &captureWriter{ResponseWriter: w}
+	serve(capture)
+
+	// Store or release the key even if the client has gone away
+	ctx := context.WithoutCancel(r.Context())
+	if storableStatus(capture.status) && !capture.retryable {
+		idempotency.Complete(ctx, userID, key, capture.status, capture.Header().Get("Content-Type"), capture.body.Bytes())
+	} else {
+		idempotency.Abandon(ctx, userID, key)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/models"
+)
+
+func TestUploadReplaysResponseForSameIdempotencyKey(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	headers := func() map[string]string { return map[string]string{"Idempotency-Key": "retry-me"} }
+	fields := map[string]string{"purchased_at": "2026-02-21T08:15:00Z"}
+	first := decode[[]models.Expense](t, h.upload(fakeAudio, fields, headers()), http.StatusOK)
+
+	rec := h.upload(fakeAudio, fields, headers())
+	second := decode[[]models.Expense](t, rec, http.StatusOK)
+	if rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replayed response should carry Idempotent-Replayed: true")
+	}
+	if len(second) != 1 || second[0].ID != first[0].ID {
+		t.Fatalf("replayed expenses = %+v, want the original %+v", second, first)
+	}
+	if got := len(h.expenses.All()); got != 1 {
+		t.Errorf("stored expenses = %d, want 1", got)
+	}
+	if got := len(h.openai.RequestsTo(fakes.EndpointTranscriptions)); got != 1 {
+		t.Errorf("transcriptions = %d, want 1", got)
+	}
+}
+
+func TestUploadRejectsIdempotencyKeyReusedWithDifferentPayload(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	decode[[]models.Expense](t, h.upload(fakeAudio, nil, map[string]string{"Idempotency-Key": "k1"}), http.StatusOK)
+
+	other := append(append([]byte(nil), fakeAudio...), "another recording"...)
+	rec := h.upload(other, nil, map[string]string{"Idempotency-Key": "k1"})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUploadUsesRecordingIDAsIdempotencyKey(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	fields := map[string]string{"recording_id": "rec-42"}
+	decode[[]models.Expense](t, h.upload(fakeAudio, fields, nil), http.StatusOK)
+	decode[[]models.Expense](t, h.upload(fakeAudio, fields, nil), http.StatusOK)
+
+	if got := len(h.expenses.All()); got != 1 {
+		t.Errorf("stored expenses = %d, want 1", got)
+	}
+}
+
+func TestUploadDoesNotStoreServerErrors(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueError(fakes.EndpointTranscriptions, http.StatusServiceUnavailable, "")
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	headers := func() map[string]string { return map[string]string{"Idempotency-Key": "flaky"} }
+	if rec := h.upload(fakeAudio, nil, headers()); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first status = %d, want 503", rec.Code)
+	}
+	expenses := decode[[]models.Expense](t, h.upload(fakeAudio, nil, headers()), http.StatusOK)
+	if len(expenses) != 1 {
+		t.Fatalf("retry after an outage should be processed, got %+v", expenses)
+	}
+}
+
+func TestBatchUploadReplaysRetriedRecordings(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+	h.openai.QueueTranscription(spanish("un café"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 2, Quantity: 1, Unit: "u", Description: "café"}})
+
+	send := func(ids ...string) models.BatchUploadResponse {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for i, id := range ids {
+			key := string(rune('a' + i))
+			part, _ := writer.CreateFormFile("audio["+key+"]", id+".m4a")
+			part.Write(append(append([]byte(nil), fakeAudio...), id...))
+			writer.WriteField("recording_id["+key+"]", id)
+		}
+		writer.Close()
+		rec := h.do(http.MethodPost, "/upload", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+		return decode[models.BatchUploadResponse](t, rec, http.StatusOK)
+	}
+
+	first := send("rec-1")
+	second := send("rec-1", "rec-2")
+
+	if !second.Results[0].Replayed || second.Results[0].Expenses[0].ID != first.Results[0].Expenses[0].ID {
+		t.Errorf("rec-1 = %+v, want the replayed original result", second.Results[0])
+	}
+	if second.Results[1].Replayed || second.Results[1].Status != http.StatusOK {
+		t.Errorf("rec-2 = %+v, want a freshly processed result", second.Results[1])
+	}
+	if got := len(h.expenses.All()); got != 2 {
+		t.Errorf("stored expenses = %d, want 2", got)
+	}
+}
+
+func TestBatchUploadRetriesRecordingsThatHitServerErrors(t *testing.T) {
+	h := newHarness(t)
+	// One of the two recordings hits an outage; which one depends on scheduling
+	h.openai.QueueError(fakes.EndpointTranscriptions, http.StatusServiceUnavailable, "")
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	send := func() models.BatchUploadResponse {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for _, key := range []string{"a", "b"} {
+			part, _ := writer.CreateFormFile("audio["+key+"]", key+".m4a")
+			part.Write(append(append([]byte(nil), fakeAudio...), key...))
+		}
+		writer.Close()
+		headers := map[string]string{"Content-Type": writer.FormDataContentType(), "Idempotency-Key": "offline-queue"}
+		rec := h.do(http.MethodPost, "/upload", &body, headers)
+		if rec.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("a batch with a server error must not be replayed whole")
+		}
+		return decode[models.BatchUploadResponse](t, rec, rec.Code)
+	}
+
+	first := send()
+	if first.Succeeded != 1 || first.Failed != 1 {
+		t.Fatalf("first response = %+v, want 1 success and 1 outage", first)
+	}
+	succeeded, failed := first.Results[0], first.Results[1]
+	if failed.Status == http.StatusOK {
+		succeeded, failed = failed, succeeded
+	}
+	if failed.Status != http.StatusServiceUnavailable {
+		t.Fatalf("failed result = %+v, want 503", failed)
+	}
+
+	h.openai.QueueTranscription(spanish("un café"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 2, Quantity: 1, Unit: "u", Description: "café"}})
+	second := send()
+	if second.Succeeded != 2 {
+		t.Fatalf("retry = %+v, want both recordings to succeed", second)
+	}
+	for _, result := range second.Results {
+		switch result.Key {
+		case succeeded.Key:
+			if !result.Replayed || result.Expenses[0].ID != succeeded.Expenses[0].ID {
+				t.Errorf("result %s = %+v, want the replayed original result", result.Key, result)
+			}
+		case failed.Key:
+			if result.Replayed || result.Status != http.StatusOK {
+				t.Errorf("result %s = %+v, want the recording processed again", result.Key, result)
+			}
+		}
+	}
+	if got := len(h.expenses.All()); got != 2 {
+		t.Errorf("stored expenses = %d, want 2", got)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/services"
+	"upload-lambda/internal/statements"
+)
+
+// statementExtensions maps statement file extensions to formats
+var statementExtensions = map[string]string{
+	".csv": models.StatementFormatCSV,
+	".ofx": models.StatementFormatOFX,
+	".qfx": models.StatementFormatOFX,
+}
+
+// ImportHandler handles HTTP requests for bank statement imports
+type ImportHandler struct {
+	service services.ExpenseService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(service services.ExpenseService) *ImportHandler {
+	return &ImportHandler{
+		service: service,
+	}
+}
+
+// HandleImport handles the import of bank statements
+// @Summary Import expenses from a bank statement
+// @Description Creates one expense (source import, status confirmed) per charge of a CSV or OFX bank statement. Credits are skipped. Each transaction's bank ID (OFX FITID, or the mapped external_id column of a CSV) is stored as external_id and transactions already imported are skipped, so importing overlapping statements is safe; CSV rows without an ID column are identified by their date, amount, description and repetition. Rows that cannot be read are reported in errors without failing the import.
+// @Tags import
+// @Accept multipart/form-data
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param file formData file true "Bank statement (CSV or OFX, max 4 MB)"
+// @Param format formData string false "csv or ofx (default: from the file extension)"
+// @Param mapping formData string false "CSV column mapping as JSON, e.g. {\"date\":\"Fecha\",\"description\":\"Descripción\",\"amount\":\"Monto\",\"date_format\":\"DD/MM/YYYY\",\"decimal_separator\":\",\",\"delimiter\":\";\"} (required for CSV, see models.CSVColumnMapping)"
+// @Success 200 {object} models.ImportResult "Import report"
+// @Failure 400 {object} map[string]string "Missing file, unknown format, invalid mapping or unreadable statement"
+// @Failure 413 {object} map[string]string "Request body or statement too large"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /import [post]
+func (h *ImportHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	upload, err := readUpload(w, r)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	defer upload.Cleanup()
+
+	var files []uploadedFile
+	for _, f := range upload.Files {
+		if f.Field == "file" {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		http.Error(w, "No statement file provided", http.StatusBadRequest)
+		return
+	}
+	if len(files) > 1 {
+		http.Error(w, "Only one statement is allowed per import", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(upload.Fields["format"])
+	if format == "" {
+		format = statementExtensions[strings.ToLower(filepath.Ext(files[0].Filename))]
+	}
+	if format != models.StatementFormatCSV && format != models.StatementFormatOFX {
+		http.Error(w, "Unknown statement format (expected format csv or ofx, or a .csv, .ofx or .qfx file)", http.StatusBadRequest)
+		return
+	}
+
+	params := models.ImportStatementParams{
+		FilePath: files[0].Path,
+		Format:   format,
+		UserID:   userIDFromRequest(r),
+	}
+	if mapping := upload.Fields["mapping"]; mapping != "" {
+		params.Mapping = &models.CSVColumnMapping{}
+		if err := json.Unmarshal([]byte(mapping), params.Mapping); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid mapping: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.service.ImportStatement(r.Context(), params)
+	if errors.Is(err, statements.ErrInvalidStatement) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrStatementTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to import statement: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
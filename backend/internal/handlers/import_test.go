@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+// importStatement posts a statement file to /import
+func (h *harness) importStatement(filename string, statement string, fields map[string]string) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	part.Write([]byte(statement))
+	if err := writer.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+
+	headers := map[string]string{"Content-Type": writer.FormDataContentType(), "X-User-ID": "sam"}
+	return h.do(http.MethodPost, "/import", &body, headers)
+}
+
+const bankCSV = `Fecha;Descripción;Comercio;Monto;Referencia
+20/02/2026;Compra tarjeta;Tottus;-45,90;T-001
+21/02/2026;Abono sueldo;;3500,00;T-002
+22/02/2026;Compra tarjeta;Wong;-12,00;T-003
+23/02/2026;Compra tarjeta;Wong;doce;T-004
+`
+
+const bankMapping = `{"date": "Fecha", "description": "Descripción", "merchant": "Comercio", "amount": "Monto",
+	"external_id": "Referencia", "date_format": "DD/MM/YYYY", "decimal_separator": ",", "delimiter": ";"}`
+
+func TestImportCSV(t *testing.T) {
+	h := newHarness(t)
+
+	rec := h.importStatement("movimientos.csv", bankCSV, map[string]string{"mapping": bankMapping})
+	result := decode[models.ImportResult](t, rec, http.StatusOK)
+
+	if result.Imported != 2 || result.Skipped != 1 || result.Duplicates != 0 {
+		t.Errorf("imported %d, skipped %d, duplicates %d; want 2, 1, 0", result.Imported, result.Skipped, result.Duplicates)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 5 || result.Errors[0].ExternalID != "T-004" {
+		t.Errorf("errors = %+v, want the row with amount \"doce\"", result.Errors)
+	}
+
+	tottus := result.Expenses[0]
+	if tottus.UnitPrice != 45.9 || tottus.Quantity != 1 || tottus.Source != models.ExpenseSourceImport ||
+		tottus.ExternalID != "T-001" || tottus.Merchant != "Tottus" || tottus.Status != models.ExpenseStatusConfirmed {
+		t.Errorf("imported expense = %+v", tottus)
+	}
+
+	// Importing the same statement again creates nothing
+	rec = h.importStatement("movimientos.csv", bankCSV, map[string]string{"mapping": bankMapping})
+	again := decode[models.ImportResult](t, rec, http.StatusOK)
+	if again.Imported != 0 || again.Duplicates != 2 || len(again.Expenses) != 0 {
+		t.Errorf("re-import: imported %d, duplicates %d", again.Imported, again.Duplicates)
+	}
+}
+
+func TestImportOFX(t *testing.T) {
+	h := newHarness(t)
+	ofx := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20260220</DTPOSTED><TRNAMT>-18.40</TRNAMT><FITID>9001</FITID><NAME>Farmacia &amp; Botica</NAME></STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+	rec := h.importStatement("tarjeta.qfx", ofx, nil)
+	result := decode[models.ImportResult](t, rec, http.StatusOK)
+	if result.Format != models.StatementFormatOFX || result.Imported != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if expense := result.Expenses[0]; expense.Description != "Farmacia & Botica" || expense.ExternalID != "4111:9001" || expense.UnitPrice != 18.4 {
+		t.Errorf("imported expense = %+v", expense)
+	}
+}
+
+func TestImportRejectsUnreadableStatements(t *testing.T) {
+	h := newHarness(t)
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"unknown format":  h.importStatement("statement.pdf", "%PDF", nil),
+		"missing mapping": h.importStatement("movimientos.csv", bankCSV, nil),
+		"invalid mapping": h.importStatement("movimientos.csv", bankCSV, map[string]string{"mapping": "{"}),
+		"missing column":  h.importStatement("movimientos.csv", bankCSV, map[string]string{"mapping": `{"date": "Date", "description": "Descripción", "amount": "Monto"}`}),
+		"not OFX":         h.importStatement("statement.ofx", bankCSV, nil),
+	} {
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400 (%s)", name, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -19,9 +19,9 @@ type LambdaHandler struct {
 }
 
 // NewLambdaHandler creates a new Lambda handler that uses the HTTP router
-func NewLambdaHandler(service services.ExpenseService) *LambdaHandler {
+func NewLambdaHandler(service services.ExpenseService, settingsService services.SettingsService, idempotencyService services.IdempotencyService, merchantService services.MerchantService, reconciliationService services.ReconciliationService, askService services.AskService, reportService services.ReportService) *LambdaHandler {
 	return &LambdaHandler{
-		router: NewRouter(service),
+		router: NewRouter(service, settingsService, idempotencyService, merchantService, reconciliationService, askService, reportService),
 	}
 }
 
@@ -67,8 +67,11 @@ func (h *LambdaHandler) Handle(ctx context.Context, request events.APIGatewayV2H
 	// Create response recorder
 	recorder := httptest.NewRecorder()
 
-	// Delegate to router
-	h.router.ServeHTTP(recorder, httpReq)
+	// Delegate to router. The response is buffered, so one aborted halfway (a failed
+	// export) cannot be cut short like on a live connection: it becomes an error instead
+	if aborted := serve(h.router, recorder, httpReq); aborted {
+		return errorResponse(500, "Response aborted"), nil
+	}
 
 	// Convert HTTP response to API Gateway response
 	result := recorder.Result()
@@ -87,6 +90,16 @@ func (h *LambdaHandler) Handle(ctx context.Context, request events.APIGatewayV2H
 		}
 	}
 
+	// Binary bodies (receipt images, XLSX exports) must be base64 encoded for API Gateway
+	if !textualContentType(result.Header.Get("Content-Type")) {
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode:      result.StatusCode,
+			Headers:         headers,
+			Body:            base64.StdEncoding.EncodeToString(responseBody),
+			IsBase64Encoded: true,
+		}, nil
+	}
+
 	return events.APIGatewayV2HTTPResponse{
 		StatusCode: result.StatusCode,
 		Headers:    headers,
@@ -94,6 +107,34 @@ func (h *LambdaHandler) Handle(ctx context.Context, request events.APIGatewayV2H
 	}, nil
 }
 
+// serve runs handler, reporting whether it aborted the response with http.ErrAbortHandler;
+// other panics are not recovered
+func serve(handler http.Handler, w http.ResponseWriter, r *http.Request) (aborted bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			if v != http.ErrAbortHandler {
+				panic(v)
+			}
+			aborted = true
+		}
+	}()
+	handler.ServeHTTP(w, r)
+	return false
+}
+
+// textualContentType reports whether a response body can be returned as a string
+func textualContentType(contentType string) bool {
+	if contentType == "" || strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	for _, textual := range []string{"json", "xml", "javascript"} {
+		if strings.Contains(contentType, textual) {
+			return true
+		}
+	}
+	return false
+}
+
 func errorResponse(statusCode int, message string) events.APIGatewayV2HTTPResponse {
 	body := `{"error":"` + message + `"}`
 	return events.APIGatewayV2HTTPResponse{
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"upload-lambda/internal/models"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// invoke sends an API Gateway event through the Lambda handler
+func (h *harness) invoke(method, path string, body []byte, headers map[string]string) events.APIGatewayV2HTTPResponse {
+	h.t.Helper()
+	request := events.APIGatewayV2HTTPRequest{
+		RawPath: path,
+		Headers: headers,
+	}
+	request.RequestContext.HTTP.Method = method
+	if body != nil {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+	response, err := h.lambda.Handle(h.t.Context(), request)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return response
+}
+
+func TestLambdaEncodesBinaryBodies(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueChatCompletion("TOTTUS\nAGUA 2.50\nTOTAL 2.50")
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 2.5, Quantity: 1, Unit: "u", Description: "agua", Confidence: confidence(0.9)}})
+
+	// API Gateway delivers multipart bodies base64 encoded
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("image", "receipt.png")
+	part.Write(fakePNG)
+	writer.Close()
+	response := h.invoke(http.MethodPost, "/upload/receipt", body.Bytes(), map[string]string{"Content-Type": writer.FormDataContentType()})
+	if response.StatusCode != http.StatusOK || response.IsBase64Encoded {
+		t.Fatalf("upload: status = %d, base64 = %t, want a plain JSON body: %s", response.StatusCode, response.IsBase64Encoded, response.Body)
+	}
+	var expenses []models.Expense
+	if err := json.Unmarshal([]byte(response.Body), &expenses); err != nil || len(expenses) != 1 {
+		t.Fatalf("expenses = %s, %v", response.Body, err)
+	}
+
+	// Images go back base64 encoded, or API Gateway would mangle them
+	response = h.invoke(http.MethodGet, "/receipts/"+expenses[0].ReceiptID+"/image", nil, nil)
+	if response.StatusCode != http.StatusOK || !response.IsBase64Encoded {
+		t.Fatalf("image: status = %d, base64 = %t, want a base64 body", response.StatusCode, response.IsBase64Encoded)
+	}
+	if image, err := base64.StdEncoding.DecodeString(response.Body); err != nil || !bytes.Equal(image, fakePNG) {
+		t.Errorf("image = %q, %v, want the stored PNG", image, err)
+	}
+
+}
+
+func TestLambdaRejectsUndecodableBodies(t *testing.T) {
+	h := newHarness(t)
+	request := events.APIGatewayV2HTTPRequest{RawPath: "/upload/receipt", Body: "not base64!", IsBase64Encoded: true}
+	request.RequestContext.HTTP.Method = http.MethodPost
+	response, err := h.lambda.Handle(t.Context(), request)
+	if err != nil || response.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, %v, want 400", response.StatusCode, err)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+	"upload-lambda/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// MerchantHandler handles HTTP requests for the merchant directory
+type MerchantHandler struct {
+	service services.MerchantService
+}
+
+// NewMerchantHandler creates a new merchant handler
+func NewMerchantHandler(service services.MerchantService) *MerchantHandler {
+	return &MerchantHandler{
+		service: service,
+	}
+}
+
+// HandleList handles listing the user's merchants
+// @Summary List merchants
+// @Description Returns the merchants of the user identified by the X-User-ID header, with the aliases that resolve to each of them. Merchants are created when an expense mentions a store for the first time.
+// @Tags merchants
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Success 200 {array} models.Merchant "Merchants ordered by name"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /merchants [get]
+func (h *MerchantHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	merchants, err := h.service.ListMerchants(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list merchants: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if merchants == nil {
+		merchants = []*models.Merchant{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(merchants)
+}
+
+// HandleAddAlias handles adding an alias to a merchant
+// @Summary Add a merchant alias
+// @Description Makes another spelling of a merchant (e.g. "super tottus" for "Tottus") resolve to it in future expenses. Aliases are matched case- and accent-insensitively.
+// @Tags merchants
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param id path string true "Merchant ID"
+// @Param alias body models.AddMerchantAliasRequest true "Alias to add"
+// @Success 200 {object} models.Merchant "Merchant with its aliases"
+// @Failure 400 {object} map[string]string "Invalid merchant ID or alias"
+// @Failure 404 {object} map[string]string "Merchant not found"
+// @Failure 409 {object} map[string]string "Alias already belongs to another merchant"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /merchants/{id}/aliases [post]
+func (h *MerchantHandler) HandleAddAlias(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid merchant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AddMerchantAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	merchant, err := h.service.AddAlias(r.Context(), userIDFromRequest(r), id, req.Alias)
+	if errors.Is(err, services.ErrInvalidMerchantAlias) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repositories.ErrMerchantNotFound) {
+		http.Error(w, "Merchant not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repositories.ErrMerchantAliasTaken) {
+		http.Error(w, "Alias already belongs to another merchant", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to add merchant alias: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(merchant)
+}
@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+// uploadAt records one expense mentioning a merchant
+func uploadAt(t *testing.T, h *harness, merchant string, unitPrice float64) models.Expense {
+	t.Helper()
+	h.openai.QueueTranscription(spanish("compré arroz en " + merchant))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: unitPrice, Quantity: 1, Unit: "u", Description: "arroz", Merchant: merchant}})
+	expenses := decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-21T08:00:00Z"}, nil), http.StatusOK)
+	return expenses[0]
+}
+
+func TestUploadLinksExpensesToMerchants(t *testing.T) {
+	h := newHarness(t)
+
+	first := uploadAt(t, h, "Tottus", 3)
+	if first.MerchantID == nil || first.Merchant != "Tottus" {
+		t.Fatalf("merchant = %v %q, want a Tottus merchant", first.MerchantID, first.Merchant)
+	}
+	// Case, accents and leading articles don't create another merchant
+	second := uploadAt(t, h, "el TOTTUS", 5)
+	if second.MerchantID == nil || *second.MerchantID != *first.MerchantID {
+		t.Errorf("second merchant = %v, want %s", second.MerchantID, *first.MerchantID)
+	}
+	uploadAt(t, h, "Plaza Vea", 7)
+
+	merchants := decode[[]models.Merchant](t, h.do(http.MethodGet, "/merchants", nil, nil), http.StatusOK)
+	if len(merchants) != 2 {
+		t.Fatalf("merchants = %+v, want 2", merchants)
+	}
+
+	list := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses?merchant_id="+*first.MerchantID, nil, nil), http.StatusOK)
+	if *list.Total != 2 {
+		t.Errorf("expenses of Tottus = %d, want 2", *list.Total)
+	}
+}
+
+func TestMerchantAliasResolvesFutureMentions(t *testing.T) {
+	h := newHarness(t)
+	tottus := uploadAt(t, h, "Tottus", 3)
+
+	path := "/merchants/" + *tottus.MerchantID + "/aliases"
+	merchant := decode[models.Merchant](t, h.do(http.MethodPost, path, strings.NewReader(`{"alias":"Súper Tottus"}`), nil), http.StatusOK)
+	if len(merchant.Aliases) != 2 {
+		t.Errorf("aliases = %v, want tottus and super tottus", merchant.Aliases)
+	}
+
+	again := uploadAt(t, h, "super tottus", 4)
+	if again.MerchantID == nil || *again.MerchantID != *tottus.MerchantID {
+		t.Errorf("merchant = %v, want %s", again.MerchantID, *tottus.MerchantID)
+	}
+
+	other := uploadAt(t, h, "Wong", 2)
+	rec := h.do(http.MethodPost, "/merchants/"+*other.MerchantID+"/aliases", strings.NewReader(`{"alias":"tottus"}`), nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("alias of another merchant: status = %d, want 409", rec.Code)
+	}
+
+	rec = h.do(http.MethodPost, path, strings.NewReader(`{"alias":"tottus"}`), map[string]string{"X-User-ID": "someone-else"})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("another user's merchant: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestSummaryGroupsByMerchant(t *testing.T) {
+	h := newHarness(t)
+	tottus := uploadAt(t, h, "Tottus", 3)
+	uploadAt(t, h, "tottus", 5)
+	uploadAt(t, h, "Wong", 2)
+
+	summary := decode[models.ExpenseSummary](t, h.do(http.MethodGet, "/expenses/summary?group_by=merchant", nil, nil), http.StatusOK)
+	if summary.Total != 10 || summary.Count != 3 {
+		t.Errorf("total = %v over %d, want 10 over 3", summary.Total, summary.Count)
+	}
+	if len(summary.Groups) != 2 || summary.Groups[0].Key != *tottus.MerchantID || summary.Groups[0].Total != 8 {
+		t.Errorf("groups = %+v, want Tottus (8) first", summary.Groups)
+	}
+
+	if rec := h.do(http.MethodGet, "/expenses/summary?group_by=color", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown group_by: status = %d, want 400", rec.Code)
+	}
+}
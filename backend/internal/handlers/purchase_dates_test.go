@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/models"
+)
+
+func TestUploadResolvesSpokenPurchaseDates(t *testing.T) {
+	h := newHarness(t)
+	yesterday := 1
+	h.openai.QueueTranscription(spanish("ayer compré pan y hoy un café, y el 3 de febrero a las 8 de la noche un libro"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan", DaysAgo: &yesterday},
+		{UnitPrice: 2, Quantity: 1, Unit: "u", Description: "café"},
+		{UnitPrice: 30, Quantity: 1, Unit: "u", Description: "libro", PurchasedAt: "2026-02-03T20:00"},
+	})
+
+	// Recorded at 00:30 on Saturday 2026-02-21 in Lima, still the 21st in UTC
+	fields := map[string]string{"purchased_at": "2026-02-21T05:30:00Z", "timezone": "America/Lima"}
+	expenses := decode[[]models.Expense](t, h.upload(fakeAudio, fields, nil), http.StatusOK)
+
+	want := []string{"2026-02-20T05:30:00Z", "2026-02-21T05:30:00Z", "2026-02-04T01:00:00Z"}
+	for i, expense := range expenses {
+		if got := expense.PurchasedAt.UTC().Format(time.RFC3339); got != want[i] {
+			t.Errorf("%s purchased_at = %s, want %s", expense.Description, got, want[i])
+		}
+	}
+
+	system := h.openai.RequestsTo(fakes.EndpointChatCompletions)[0].Messages[0].Content
+	if !strings.Contains(system, "Saturday 2026-02-21 00:30 (America/Lima)") {
+		t.Errorf("prompt does not carry the local recording time:\n%s", system)
+	}
+}
+
+func TestUploadReviewsUnusablePurchaseDates(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("mañana compro pan y hoy compré leche"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan", PurchasedAt: "2026-02-22"},
+		{UnitPrice: 4, Quantity: 1, Unit: "u", Description: "leche"},
+	})
+
+	// A future date keeps the upload time and is left for review, without failing the other expense
+	expenses := decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-21T08:00:00Z"}, nil), http.StatusOK)
+	if len(expenses) != 2 {
+		t.Fatalf("expenses = %+v, want both", expenses)
+	}
+	bread, milk := expenses[0], expenses[1]
+	if !bread.PurchasedAt.Equal(time.Date(2026, 2, 21, 8, 0, 0, 0, time.UTC)) || bread.Status != models.ExpenseStatusNeedsReview {
+		t.Errorf("pan = %s, %s, want the upload time for review", bread.PurchasedAt, bread.Status)
+	}
+	if milk.Status != models.ExpenseStatusConfirmed {
+		t.Errorf("leche status = %s, want confirmed", milk.Status)
+	}
+}
+
+func TestUploadRejectsUnknownTimezone(t *testing.T) {
+	h := newHarness(t)
+	rec := h.upload(fakeAudio, map[string]string{"timezone": "Mars/Olympus"}, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
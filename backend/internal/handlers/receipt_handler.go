@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+	"upload-lambda/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ReceiptHandler handles HTTP requests for receipts
+type ReceiptHandler struct {
+	service services.ExpenseService
+}
+
+// NewReceiptHandler creates a new receipt handler
+func NewReceiptHandler(service services.ExpenseService) *ReceiptHandler {
+	return &ReceiptHandler{
+		service: service,
+	}
+}
+
+// HandleUpload handles the upload of receipt images
+// @Summary Upload a receipt photo and extract expenses
+// @Description Reads the text of a receipt photo with OCR (OpenAI vision or tesseract, see OCR_ENGINE), extracts one expense per item line with a receipt-specific prompt, and stores the image with the expenses (receipt_id)
+// @Tags receipts
+// @Accept multipart/form-data
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param image formData file true "Receipt photo (JPEG, PNG or WebP; detected from content, max 4 MB)"
+// @Param purchased_at formData string false "Purchase date/time in RFC3339 format (e.g., 2026-02-22T10:30:00Z)"
+// @Param language formData string false "Receipt language: es, en or pt (default: the user's language)"
+// @Success 200 {array} models.Expense "List of extracted expenses"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 413 {object} map[string]string "Request body or image too large"
+// @Failure 415 {object} map[string]string "Unsupported image format"
+// @Failure 422 {object} map[string]string "No text found on the image, or extracted data out of bounds"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 503 {object} map[string]string "OpenAI unavailable (code: openai_unavailable or openai_circuit_open)"
+// @Router /upload/receipt [post]
+func (h *ReceiptHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := readUpload(w, r)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	defer upload.Cleanup()
+
+	var images []uploadedFile
+	for _, f := range upload.Files {
+		if f.Field == "image" {
+			images = append(images, f)
+		}
+	}
+	if len(images) == 0 {
+		http.Error(w, "No image file provided", http.StatusBadRequest)
+		return
+	}
+	if len(images) > 1 {
+		http.Error(w, "Only one image is allowed per receipt", http.StatusBadRequest)
+		return
+	}
+
+	purchasedAt, err := parsePurchasedAt(upload.Fields["purchased_at"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	language := upload.Fields["language"]
+	if language != "" && !models.IsSupportedLanguage(language) {
+		http.Error(w, fmt.Sprintf("Unsupported language %q (expected one of %v)", language, models.SupportedLanguages), http.StatusBadRequest)
+		return
+	}
+
+	expenses, err := h.service.ProcessReceiptExpense(r.Context(), models.ProcessReceiptParams{
+		ImagePath:   images[0].Path,
+		PurchasedAt: purchasedAt,
+		UserID:      userIDFromRequest(r),
+		Language:    language,
+	})
+	if err != nil {
+		writeProcessingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(expenses)
+}
+
+// HandleImage handles serving a stored receipt image
+// @Summary Get a receipt image
+// @Description Returns the stored photo of a receipt of the user
+// @Tags receipts
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/webp
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param id path string true "Receipt ID"
+// @Success 200 {file} binary "Receipt image"
+// @Failure 400 {object} map[string]string "Invalid receipt ID"
+// @Failure 404 {object} map[string]string "Receipt not found or of another user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /receipts/{id}/image [get]
+func (h *ReceiptHandler) HandleImage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := h.service.GetReceipt(r.Context(), id, userIDFromRequest(r))
+	if errors.Is(err, repositories.ErrReceiptNotFound) {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get receipt: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", receipt.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(receipt.Image)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(receipt.Image)
+}
@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/models"
+)
+
+// fakePNG starts with the PNG signature so it passes content sniffing
+var fakePNG = append([]byte("\x89PNG\r\n\x1a\n"), []byte("fake-receipt-photo")...)
+
+// uploadReceipt posts a receipt image with extra fields
+func (h *harness) uploadReceipt(image []byte, fields map[string]string) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	part, _ := writer.CreateFormFile("image", "receipt.png")
+	part.Write(image)
+	writer.Close()
+
+	return h.do(http.MethodPost, "/upload/receipt", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+}
+
+func TestReceiptUploadExtractsItemsAndStoresImage(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueChatCompletion("TOTTUS\nARROZ COSTENO 1KG  2 x 4.50  9.00\nLECHE GLORIA  3.80\nTOTAL 12.80")
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 4.5, Quantity: 2, Unit: "u", Description: "arroz costeño 1kg", Confidence: confidence(0.9)},
+		{UnitPrice: 3.8, Quantity: 1, Unit: "u", Description: "leche gloria", Confidence: confidence(0.95)},
+	})
+
+	expenses := decode[[]models.Expense](t, h.uploadReceipt(fakePNG, map[string]string{"purchased_at": "2026-02-21T08:15:00Z"}), http.StatusOK)
+	if len(expenses) != 2 || expenses[0].ReceiptID == "" || expenses[0].ReceiptID != expenses[1].ReceiptID {
+		t.Fatalf("expenses = %+v, want 2 linked to the same receipt", expenses)
+	}
+	if !strings.HasPrefix(expenses[0].PromptVersion, "receipt@") {
+		t.Errorf("prompt_version = %q, want the receipt prompt", expenses[0].PromptVersion)
+	}
+
+	requests := h.openai.RequestsTo(fakes.EndpointChatCompletions)
+	if len(requests) != 2 {
+		t.Fatalf("chat completions = %d, want OCR then extraction", len(requests))
+	}
+	image := requests[0].Messages[1].MultiContent[0].ImageURL
+	if image == nil || !strings.HasPrefix(image.URL, "data:image/png;base64,") {
+		t.Errorf("OCR request image = %+v, want a PNG data URL", image)
+	}
+	if content := requests[1].Messages[1].Content; !strings.HasPrefix(content, "<receipt>") || !strings.Contains(content, "LECHE GLORIA") {
+		t.Errorf("extraction user message = %q, want the delimited OCR text", content)
+	}
+
+	rec := h.do(http.MethodGet, "/receipts/"+expenses[0].ReceiptID+"/image", nil, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || !bytes.Equal(rec.Body.Bytes(), fakePNG) {
+		t.Errorf("image: status = %d, content type = %q, want the stored PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestReceiptUploadRejectsNonImages(t *testing.T) {
+	h := newHarness(t)
+	rec := h.uploadReceipt([]byte("%PDF-1.4 not an image"), nil)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status = %d, want 415; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(h.openai.Requests()) != 0 {
+		t.Error("rejected images must not reach OpenAI")
+	}
+}
+
+func TestReceiptUploadWithoutTextIsUnprocessable(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueChatCompletion("")
+
+	rec := h.uploadReceipt(fakePNG, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRejectedReceiptExtractionStoresNoReceipt(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueChatCompletion("TOTTUS\nARROZ COSTENO 1KG  -4.50")
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: -4.5, Quantity: 1, Unit: "u", Description: "arroz"}})
+
+	rec := h.uploadReceipt(fakePNG, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422; body: %s", rec.Code, rec.Body.String())
+	}
+	if got := h.receipts.Count(); got != 0 {
+		t.Errorf("stored receipts = %d, want none for a rejected extraction", got)
+	}
+}
+
+func TestReceiptImageNotFound(t *testing.T) {
+	h := newHarness(t)
+	rec := h.do(http.MethodGet, "/receipts/7f1c6f6e-0000-4000-8000-000000000000/image", nil, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestReceiptImagesOfOtherUsersAreNotFound(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueChatCompletion("TOTTUS\nAGUA 2.50\nTOTAL 2.50")
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 2.5, Quantity: 1, Unit: "u", Description: "agua", Confidence: confidence(0.9)}})
+	expenses := decode[[]models.Expense](t, h.uploadReceipt(fakePNG, nil), http.StatusOK)
+
+	rec := h.do(http.MethodGet, "/receipts/"+expenses[0].ReceiptID+"/image", nil, map[string]string{"X-User-ID": "ana"})
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's receipt: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+	"upload-lambda/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ReconciliationHandler handles HTTP requests for reconciliation
+type ReconciliationHandler struct {
+	service services.ReconciliationService
+}
+
+// NewReconciliationHandler creates a new reconciliation handler
+func NewReconciliationHandler(service services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		service: service,
+	}
+}
+
+// HandleReconciliation handles reporting recorded expenses against imported bank transactions
+// @Summary Get the reconciliation report
+// @Description Reports, with the matches made so far by POST /reconciliation, the transactions imported for the X-User-ID user and the recorded expenses (voice notes and receipts) they paid for, the recorded expenses without a bank transaction and the bank transactions that were never recorded. Nothing is matched or stored: matching writes, so it is POST /reconciliation, and GET stays safe to repeat, prefetch and cache. Without from and to, the report covers the period of the transactions imported in the last 90 days.
+// @Tags import
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param from query string false "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: 90 days before to)"
+// @Param to query string false "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: now)"
+// @Success 200 {object} models.Reconciliation "Matched and unmatched expenses"
+// @Failure 400 {object} map[string]string "Invalid from or to"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reconciliation [get]
+func (h *ReconciliationHandler) HandleReconciliation(w http.ResponseWriter, r *http.Request) {
+	h.serveReconciliation(w, r, h.service.Report)
+}
+
+// HandleReconcile handles reconciling recorded expenses against imported bank transactions
+// @Summary Reconcile expenses against the bank
+// @Description Matches the recorded expenses (voice notes and receipts) of the X-User-ID user to imported bank transactions with the same amount (within 1%) posted within 3 days, stores the new matches and returns the report of GET /reconciliation. All the expenses of a recording or receipt can match one transaction together. Matches never change once made, unless deleted with DELETE /reconciliation/matches/{transaction_id}. Without from and to, the last 90 days are reconciled.
+// @Tags import
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param from query string false "Only expenses purchased on or after this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: 90 days before to)"
+// @Param to query string false "Only expenses purchased on or before this day (YYYY-MM-DD, in the user's timezone) or RFC3339 time (default: now)"
+// @Success 200 {object} models.Reconciliation "Matched and unmatched expenses"
+// @Failure 400 {object} map[string]string "Invalid from or to"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reconciliation [post]
+func (h *ReconciliationHandler) HandleReconcile(w http.ResponseWriter, r *http.Request) {
+	h.serveReconciliation(w, r, h.service.Reconcile)
+}
+
+// serveReconciliation answers with the reconciliation report of the request's user and dates
+func (h *ReconciliationHandler) serveReconciliation(w http.ResponseWriter, r *http.Request, reconcile func(context.Context, models.ReconciliationParams) (*models.Reconciliation, error)) {
+	dateRange, err := parseDateRange(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reconciliation, err := reconcile(r.Context(), models.ReconciliationParams{
+		UserID:    userIDFromRequest(r),
+		DateRange: dateRange,
+	})
+	if errors.Is(err, services.ErrInvalidDateRange) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reconcile expenses: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reconciliation)
+}
+
+// HandleDeleteMatch handles deleting the matches of a bank transaction
+// @Summary Delete a reconciliation match
+// @Description Unlinks an imported bank transaction from the recorded expenses it was matched to. Later reconciliations do not match them together again, but may match them elsewhere.
+// @Tags import
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param transaction_id path string true "ID of the imported transaction"
+// @Success 204 "Match deleted"
+// @Failure 400 {object} map[string]string "Invalid transaction ID"
+// @Failure 404 {object} map[string]string "Transaction has no match"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reconciliation/matches/{transaction_id} [delete]
+func (h *ReconciliationHandler) HandleDeleteMatch(w http.ResponseWriter, r *http.Request) {
+	transactionID := chi.URLParam(r, "transaction_id")
+	if _, err := uuid.Parse(transactionID); err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.Unmatch(r.Context(), userIDFromRequest(r), transactionID)
+	if errors.Is(err, repositories.ErrMatchNotFound) {
+		http.Error(w, "Transaction has no match", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete match: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+func TestReconciliation(t *testing.T) {
+	h := newHarness(t)
+	headers := map[string]string{"X-User-ID": "sam"}
+
+	// Groceries paid by card and a cash purchase, recorded by voice
+	h.openai.QueueTranscription(spanish("dos kilos de arroz a tres cincuenta y un litro de aceite a cuatro veinte"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 3.5, Quantity: 2, Unit: "kg", Description: "arroz"},
+		{UnitPrice: 4.2, Quantity: 1, Unit: "litro", Description: "aceite"},
+	})
+	decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-20T15:00:00Z"}, headers), http.StatusOK)
+	h.openai.QueueTranscription(spanish("un pasaje a cinco"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 5, Quantity: 1, Unit: "pasaje", Description: "pasaje"}})
+	decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-21T15:00:00Z"}, headers), http.StatusOK)
+
+	// The bank has the groceries and a charge that was never recorded
+	statement := "date,description,amount\n2026-02-21,TOTTUS,-11.20\n2026-02-22,NETFLIX,-30.00\n"
+	mapping := `{"date": "date", "description": "description", "amount": "amount"}`
+	decode[models.ImportResult](t, h.importStatement("bank.csv", statement, map[string]string{"mapping": mapping}), http.StatusOK)
+
+	// Until reconciled, the summary counts the groceries twice
+	summary := func() models.ExpenseSummary {
+		t.Helper()
+		return decode[models.ExpenseSummary](t, h.do(http.MethodGet, "/expenses/summary?from=2026-02-01&to=2026-02-28", nil, headers), http.StatusOK)
+	}
+	if before := summary(); before.Count != 5 || math.Abs(before.Total-57.4) > 1e-9 {
+		t.Errorf("summary before reconciling = %d expenses, total %v", before.Count, before.Total)
+	}
+
+	// Reading the report matches nothing
+	february := "/reconciliation?from=2026-02-01&to=2026-02-28"
+	report := decode[models.Reconciliation](t, h.do(http.MethodGet, february, nil, headers), http.StatusOK)
+	if len(report.Matched) != 0 || len(report.UnmatchedBank) != 2 || len(report.UnmatchedRecorded) != 3 {
+		t.Errorf("report before reconciling = %+v, want nothing matched", report)
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPost, http.MethodGet} {
+		report := decode[models.Reconciliation](t, h.do(method, february, nil, headers), http.StatusOK)
+
+		if len(report.Matched) != 1 || report.Matched[0].Transaction.Description != "TOTTUS" || len(report.Matched[0].Expenses) != 2 {
+			t.Fatalf("%s: matched = %+v, want TOTTUS paying for both groceries", method, report.Matched)
+		}
+		if report.Matched[0].Difference != 0 {
+			t.Errorf("difference = %v, want 0", report.Matched[0].Difference)
+		}
+		if len(report.UnmatchedBank) != 1 || report.UnmatchedBank[0].Description != "NETFLIX" {
+			t.Errorf("unmatched bank = %+v, want NETFLIX", report.UnmatchedBank)
+		}
+		if len(report.UnmatchedRecorded) != 1 || report.UnmatchedRecorded[0].Description != "pasaje" {
+			t.Errorf("unmatched recorded = %+v, want the cash fare", report.UnmatchedRecorded)
+		}
+	}
+
+	// The matched bank charge is the same purchase as the recorded groceries
+	if after := summary(); after.Count != 4 || math.Abs(after.Total-46.2) > 1e-9 {
+		t.Errorf("summary after reconciling = %d expenses, total %v, want the TOTTUS charge left out", after.Count, after.Total)
+	}
+
+	// Matches are stored once
+	if matches, _ := h.matches.ListMatches(t.Context(), "sam"); len(matches) != 2 {
+		t.Errorf("stored %d matches, want 2", len(matches))
+	}
+
+	// By default only the last 90 days are reconciled
+	if report := decode[models.Reconciliation](t, h.do(http.MethodPost, "/reconciliation", nil, headers), http.StatusOK); len(report.Matched)+len(report.UnmatchedBank)+len(report.UnmatchedRecorded) != 0 {
+		t.Errorf("default report = %+v, want February left out", report)
+	}
+
+	if rec := h.do(http.MethodGet, "/reconciliation?from=feb", nil, headers); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid from: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestDeleteReconciliationMatch(t *testing.T) {
+	h := newHarness(t)
+	headers := map[string]string{"X-User-ID": "sam"}
+	h.openai.QueueTranscription(spanish("un taxi a quince"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 15, Quantity: 1, Unit: "u", Description: "taxi"}})
+	decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-20T15:00:00Z"}, headers), http.StatusOK)
+	statement := "date,description,amount\n2026-02-20,CABIFY,-15.00\n"
+	mapping := `{"date": "date", "description": "description", "amount": "amount"}`
+	decode[models.ImportResult](t, h.importStatement("bank.csv", statement, map[string]string{"mapping": mapping}), http.StatusOK)
+
+	february := "/reconciliation?from=2026-02-01&to=2026-02-28"
+	report := decode[models.Reconciliation](t, h.do(http.MethodPost, february, nil, headers), http.StatusOK)
+	if len(report.Matched) != 1 {
+		t.Fatalf("matched = %+v, want the taxi", report.Matched)
+	}
+	transactionID := report.Matched[0].Transaction.ID
+
+	if rec := h.do(http.MethodDelete, "/reconciliation/matches/"+transactionID, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("deleting another user's match: status = %d, want 404", rec.Code)
+	}
+	if rec := h.do(http.MethodDelete, "/reconciliation/matches/"+transactionID, nil, headers); rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	if rec := h.do(http.MethodDelete, "/reconciliation/matches/"+transactionID, nil, headers); rec.Code != http.StatusNotFound {
+		t.Errorf("deleting twice: status = %d, want 404", rec.Code)
+	}
+	if rec := h.do(http.MethodDelete, "/reconciliation/matches/not-a-uuid", nil, headers); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID: status = %d, want 400", rec.Code)
+	}
+
+	// A deleted match is not made again
+	report = decode[models.Reconciliation](t, h.do(http.MethodPost, february, nil, headers), http.StatusOK)
+	if len(report.Matched) != 0 || len(report.UnmatchedBank) != 1 || len(report.UnmatchedRecorded) != 1 {
+		t.Errorf("report after deleting the match = %+v, want both unmatched", report)
+	}
+}
+
+func TestMatchesOfDeletedExpensesAreIgnoredUntilRestored(t *testing.T) {
+	h := newHarness(t)
+	headers := map[string]string{"X-User-ID": "sam"}
+	h.openai.QueueTranscription(spanish("un taxi a quince"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 15, Quantity: 1, Unit: "u", Description: "taxi"}})
+	taxi := decode[[]models.Expense](t, h.upload(fakeAudio, map[string]string{"purchased_at": "2026-02-20T15:00:00Z"}, headers), http.StatusOK)[0]
+	statement := "date,description,amount\n2026-02-20,CABIFY,-15.00\n"
+	mapping := `{"date": "date", "description": "description", "amount": "amount"}`
+	decode[models.ImportResult](t, h.importStatement("bank.csv", statement, map[string]string{"mapping": mapping}), http.StatusOK)
+
+	february := "/reconciliation?from=2026-02-01&to=2026-02-28"
+	if report := decode[models.Reconciliation](t, h.do(http.MethodPost, february, nil, headers), http.StatusOK); len(report.Matched) != 1 {
+		t.Fatalf("matched = %+v, want the taxi", report.Matched)
+	}
+
+	// Without the taxi, the bank charge is unmatched and counts in the summary
+	decode[models.Expense](t, h.do(http.MethodDelete, "/expenses/"+taxi.ID, nil, headers), http.StatusOK)
+	report := decode[models.Reconciliation](t, h.do(http.MethodGet, february, nil, headers), http.StatusOK)
+	if len(report.Matched) != 0 || len(report.UnmatchedBank) != 1 {
+		t.Errorf("report after deleting the taxi = %+v, want the charge unmatched", report)
+	}
+	summary := decode[models.ExpenseSummary](t, h.do(http.MethodGet, "/expenses/summary", nil, headers), http.StatusOK)
+	if summary.Count != 1 || summary.Total != 15 {
+		t.Errorf("summary after deleting the taxi = %d expenses, total %v", summary.Count, summary.Total)
+	}
+
+	decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+taxi.ID+"/restore", nil, headers), http.StatusOK)
+	report = decode[models.Reconciliation](t, h.do(http.MethodGet, february, nil, headers), http.StatusOK)
+	if len(report.Matched) != 1 || report.Matched[0].Expenses[0].ID != taxi.ID {
+		t.Errorf("report after restoring the taxi = %+v, want the match back", report)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"slices"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+// reportDateLayout formats dates in report documents
+const reportDateLayout = "2006-01-02"
+
+// reportDocument renders an expense report as a printable page. Receipt photos are embedded as
+// data URLs after the table, as the page is saved or printed and the receipt image endpoint
+// needs the user's header anyway. The server does not keep the audio of voice notes, so their
+// transcriptions are quoted instead.
+var reportDocument = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format(reportDateLayout) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Report.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.4em; text-align: left; vertical-align: top; }
+td.number, th.number { text-align: right; }
+blockquote { margin: 0.3em 0 0; color: #555; font-style: italic; }
+tfoot td { font-weight: bold; }
+figure { margin: 1em 0; page-break-inside: avoid; }
+figure img { max-width: 100%; max-height: 90vh; }
+</style>
+</head>
+<body>
+<h1>{{.Report.Title}}</h1>
+<p>Status: {{.Report.Status}}
+{{- with .Report.SubmittedAt}} · Submitted: {{date .}}{{end}}
+{{- with .Report.ReimbursedAt}} · Reimbursed: {{date .}}{{end}}</p>
+<table>
+<thead>
+<tr><th>Date</th><th>Description</th><th>Merchant</th><th class="number">Quantity</th><th>Unit</th><th class="number">Unit price</th><th class="number">Total</th><th>Receipt</th></tr>
+</thead>
+<tbody>
+{{- range .Items}}
+<tr>
+<td>{{date .Expense.PurchasedAt}}</td>
+<td>{{.Expense.Description}}{{with .Expense.Transcription}}<blockquote>“{{.}}”</blockquote>{{end}}</td>
+<td>{{.Expense.Merchant}}</td>
+<td class="number">{{.Quantity}}</td>
+<td>{{.Expense.Unit}}</td>
+<td class="number">{{.UnitPrice}}</td>
+<td class="number">{{.Total}}</td>
+<td>{{with .ReceiptID}}<a href="#receipt-{{.}}">Receipt</a>{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><td colspan="6">Total ({{.Report.Count}} expenses)</td><td class="number">{{.Total}}</td><td></td></tr>
+</tfoot>
+</table>
+{{- with .Receipts}}
+<h2>Receipts</h2>
+{{- range .}}
+<figure id="receipt-{{.ID}}"><img src="{{.Image}}" alt="Receipt {{.ID}}"></figure>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// reportDocumentItem is an expense of a report document with its amounts formatted
+type reportDocumentItem struct {
+	Expense   *models.Expense
+	Quantity  string
+	UnitPrice string
+	Total     string
+	// ReceiptID is set when the photo of the expense's receipt is in the document
+	ReceiptID string
+}
+
+// reportDocumentReceipt is a receipt photo embedded in a report document
+type reportDocumentReceipt struct {
+	ID    string
+	Image template.URL
+}
+
+// renderReportDocument renders a report with its expenses and the photos of their receipts,
+// by ID, as HTML
+func renderReportDocument(w io.Writer, report *models.ExpenseReport, receipts map[string]*models.Receipt, format numberFormat) error {
+	items := make([]reportDocumentItem, 0, len(report.Expenses))
+	var photos []reportDocumentReceipt
+	for _, expense := range report.Expenses {
+		item := reportDocumentItem{
+			Expense:   expense,
+			Quantity:  format.quantity(expense.Quantity),
+			UnitPrice: format.amount(expense.UnitPrice),
+			Total:     format.amount(expense.Total()),
+		}
+		if receipt := receipts[expense.ReceiptID]; receipt != nil {
+			item.ReceiptID = receipt.ID
+			// Expenses read from the same receipt share its photo
+			if !slices.ContainsFunc(photos, func(p reportDocumentReceipt) bool { return p.ID == receipt.ID }) {
+				// The content type was detected from the image, so the URL is safe to embed
+				image := template.URL("data:" + receipt.ContentType + ";base64," + base64.StdEncoding.EncodeToString(receipt.Image))
+				photos = append(photos, reportDocumentReceipt{ID: receipt.ID, Image: image})
+			}
+		}
+		items = append(items, item)
+	}
+
+	data := struct {
+		Report   *models.ExpenseReport
+		Items    []reportDocumentItem
+		Total    string
+		Receipts []reportDocumentReceipt
+	}{report, items, format.amount(report.Total), photos}
+	return reportDocument.Execute(w, data)
+}
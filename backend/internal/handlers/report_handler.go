@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+	"upload-lambda/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ReportHandler handles HTTP requests for expense reports
+type ReportHandler struct {
+	service services.ReportService
+}
+
+// NewReportHandler creates a new expense report handler
+func NewReportHandler(service services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		service: service,
+	}
+}
+
+// HandleCreate handles creating an expense report
+// @Summary Create an expense report
+// @Description Creates a report of the user identified by the X-User-ID header claiming reimbursable expenses. The expenses must be pending reimbursable expenses of the user that no other report claims; more can be added until the report is submitted.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param request body models.CreateReportRequest true "Report title and expenses"
+// @Success 201 {object} models.ExpenseReport "Created report with its expenses"
+// @Failure 400 {object} map[string]string "Invalid title or expense IDs"
+// @Failure 422 {object} map[string]string "An expense is not a pending reimbursable expense of the user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports [post]
+func (h *ReportHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var request models.CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.CreateReport(r.Context(), userIDFromRequest(r), request)
+	if err != nil {
+		writeReportError(w, err, "Failed to create report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleList handles listing the user's expense reports
+// @Summary List expense reports
+// @Description Returns the reports of the user identified by the X-User-ID header with their status and totals, without their expenses
+// @Tags reports
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Success 200 {array} models.ExpenseReport "Reports, latest first"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports [get]
+func (h *ReportHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.service.ListReports(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list reports: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if reports == nil {
+		reports = []*models.ExpenseReport{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+// HandleGet handles getting an expense report
+// @Summary Get an expense report
+// @Description Returns a report with its expenses, oldest purchase first, in the timezone of the report's user. Expenses read from voice notes include the transcription of their recording.
+// @Tags reports
+// @Produce json
+// @Param X-User-ID header string false "User the report belongs to (default: default)"
+// @Param id path string true "Report ID"
+// @Success 200 {object} models.ExpenseReport "Report with its expenses"
+// @Failure 400 {object} map[string]string "Invalid report ID"
+// @Failure 404 {object} map[string]string "Report not found or of another user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports/{id} [get]
+func (h *ReportHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := reportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetReport(r.Context(), id, userIDFromRequest(r))
+	if err != nil {
+		writeReportError(w, err, "Failed to get report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleAddExpenses handles adding expenses to an expense report
+// @Summary Add expenses to a report
+// @Description Adds pending reimbursable expenses of the report's user that no other report claims to a report that was not submitted. Either all the expenses are added or none.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User the report belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Report ID"
+// @Param request body models.ReportExpensesRequest true "Expenses to add"
+// @Success 200 {object} models.ExpenseReport "Report with its expenses"
+// @Failure 400 {object} map[string]string "Invalid report ID or expense IDs"
+// @Failure 404 {object} map[string]string "Report not found or of another user"
+// @Failure 409 {object} map[string]string "Report was submitted"
+// @Failure 422 {object} map[string]string "An expense is not a pending reimbursable expense of the report's user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports/{id}/expenses [post]
+func (h *ReportHandler) HandleAddExpenses(w http.ResponseWriter, r *http.Request) {
+	id, ok := reportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var request models.ReportExpensesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.AddExpenses(r.Context(), id, request.ExpenseIDs, userIDFromRequest(r))
+	if err != nil {
+		writeReportError(w, err, "Failed to add expenses to report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleRemoveExpense handles taking an expense out of an expense report
+// @Summary Remove an expense from a report
+// @Description Takes an expense out of a report that was not submitted. The expense stays reimbursable and pending, so it can be claimed in another report.
+// @Tags reports
+// @Produce json
+// @Param X-User-ID header string false "User the report belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Report ID"
+// @Param expense_id path string true "Expense ID"
+// @Success 200 {object} models.ExpenseReport "Report with its expenses"
+// @Failure 400 {object} map[string]string "Invalid report ID or expense ID"
+// @Failure 404 {object} map[string]string "Report not found or of another user, or expense not in the report"
+// @Failure 409 {object} map[string]string "Report was submitted"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports/{id}/expenses/{expense_id} [delete]
+func (h *ReportHandler) HandleRemoveExpense(w http.ResponseWriter, r *http.Request) {
+	id, ok := reportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	expenseID := chi.URLParam(r, "expense_id")
+	if _, err := uuid.Parse(expenseID); err != nil {
+		http.Error(w, "Invalid expense ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.RemoveExpense(r.Context(), id, expenseID, userIDFromRequest(r))
+	if err != nil {
+		writeReportError(w, err, "Failed to remove expense from report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleSubmit handles submitting an expense report
+// @Summary Submit an expense report
+// @Description Submits a report for reimbursement, recording the submission date. The report and its expenses move to submitted and can no longer be changed.
+// @Tags reports
+// @Produce json
+// @Param X-User-ID header string false "User the report belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Report ID"
+// @Success 200 {object} models.ExpenseReport "Submitted report"
+// @Failure 400 {object} map[string]string "Invalid report ID"
+// @Failure 404 {object} map[string]string "Report not found or of another user"
+// @Failure 409 {object} map[string]string "Report is empty or was already submitted"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports/{id}/submit [post]
+func (h *ReportHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	id, ok := reportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.SubmitReport(r.Context(), id, userIDFromRequest(r))
+	if err != nil {
+		writeReportError(w, err, "Failed to submit report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleReimburse handles marking an expense report as reimbursed
+// @Summary Mark an expense report as reimbursed
+// @Description Records that a submitted report was paid. The report and its expenses move to reimbursed.
+// @Tags reports
+// @Produce json
+// @Param X-User-ID header string false "User the report belongs to, recorded in the expense history (default: default)"
+// @Param id path string true "Report ID"
+// @Success 200 {object} models.ExpenseReport "Reimbursed report"
+// @Failure 400 {object} map[string]string "Invalid report ID"
+// @Failure 404 {object} map[string]string "Report not found or of another user"
+// @Failure 409 {object} map[string]string "Report was not submitted or was already reimbursed"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports/{id}/reimburse [post]
+func (h *ReportHandler) HandleReimburse(w http.ResponseWriter, r *http.Request) {
+	id, ok := reportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.MarkReimbursed(r.Context(), id, userIDFromRequest(r))
+	if err != nil {
+		writeReportError(w, err, "Failed to mark report as reimbursed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleDocument handles rendering an expense report as a document
+// @Summary Get an expense report document
+// @Description Renders a report as a printable HTML page (print it to PDF to attach it to a claim) listing its expenses with their totals and the transcriptions of their voice notes, the report's grand total, and the photos of their receipts, embedded in the page.
+// @Tags reports
+// @Produce html
+// @Param X-User-ID header string false "User the report belongs to (default: default)"
+// @Param id path string true "Report ID"
+// @Param locale query string false "BCP 47 tag formatting the amounts, e.g. es-PE (default: en)"
+// @Success 200 {string} string "HTML document"
+// @Failure 400 {object} map[string]string "Invalid report ID or locale"
+// @Failure 404 {object} map[string]string "Report not found or of another user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /reports/{id}/document [get]
+func (h *ReportHandler) HandleDocument(w http.ResponseWriter, r *http.Request) {
+	id, ok := reportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+	format, err := parseNumberFormat(r.URL.Query().Get("locale"), true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetReport(r.Context(), id, userIDFromRequest(r))
+	if err != nil {
+		writeReportError(w, err, "Failed to get report")
+		return
+	}
+
+	receipts, err := h.service.ReportReceipts(r.Context(), report)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get receipts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var document bytes.Buffer
+	if err := renderReportDocument(&document, report, receipts, format); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to render report: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document.Bytes())
+}
+
+// reportIDFromRequest reads the report ID path parameter, writing 400 if it is invalid
+func reportIDFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+// writeReportError maps report service errors to responses
+func writeReportError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrReportNotFound):
+		http.Error(w, "Report not found", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrExpenseNotInReport):
+		http.Error(w, "Expense is not in the report", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrReportStatus):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repositories.ErrExpenseNotReportable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,251 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+// markReimbursable flags an expense as reimbursable
+func markReimbursable(h *harness, expenseID string) models.Expense {
+	h.t.Helper()
+	return decode[models.Expense](h.t, h.do(http.MethodPut, "/expenses/"+expenseID+"/reimbursable", strings.NewReader(`{"reimbursable": true}`), nil), http.StatusOK)
+}
+
+func TestReimbursableFlag(t *testing.T) {
+	h := newHarness(t)
+	taxi := recordExpense(h, "taxi", 25)
+	recordExpense(h, "pan", 4)
+
+	flagged := markReimbursable(h, taxi.ID)
+	if !flagged.Reimbursable || flagged.ReimbursementStatus != models.ReimbursementPending {
+		t.Fatalf("flagged = %+v", flagged)
+	}
+	pending := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses?reimbursement_status=pending", nil, nil), http.StatusOK)
+	if *pending.Total != 1 || pending.Data[0].ID != taxi.ID {
+		t.Errorf("pending = %+v, want only the taxi", pending.Data)
+	}
+	if rec := h.do(http.MethodGet, "/expenses?reimbursement_status=paid", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown reimbursement_status: status = %d, want 400", rec.Code)
+	}
+
+	unflagged := decode[models.Expense](t, h.do(http.MethodPut, "/expenses/"+taxi.ID+"/reimbursable", strings.NewReader(`{"reimbursable": false}`), nil), http.StatusOK)
+	if unflagged.Reimbursable || unflagged.ReimbursementStatus != "" {
+		t.Errorf("unflagged = %+v", unflagged)
+	}
+	if rec := h.do(http.MethodPut, "/expenses/7f1c6f6e-0000-4000-8000-000000000000/reimbursable", strings.NewReader(`{"reimbursable": true}`), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown expense: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestExpenseReportLifecycle(t *testing.T) {
+	h := newHarness(t)
+	taxi := recordExpense(h, "taxi al aeropuerto", 25)
+	lunch := recordExpense(h, "almuerzo con cliente", 40)
+	bread := recordExpense(h, "pan", 4)
+	markReimbursable(h, taxi.ID)
+	markReimbursable(h, lunch.ID)
+	ana := map[string]string{"X-User-ID": "ana"}
+
+	// Only pending reimbursable expenses of the user can be claimed
+	if rec := h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje", "expense_ids": [%q]}`, bread.ID)), nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("claiming a non-reimbursable expense: status = %d, want 422", rec.Code)
+	}
+	if rec := h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje", "expense_ids": [%q]}`, taxi.ID)), ana); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("claiming another user's expense: status = %d, want 422", rec.Code)
+	}
+	if rec := h.do(http.MethodPost, "/reports", strings.NewReader(`{"title": "  "}`), nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty title: status = %d, want 400", rec.Code)
+	}
+
+	report := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje a Lima", "expense_ids": [%q]}`, taxi.ID)), nil), http.StatusCreated)
+	if report.Status != models.ReimbursementPending || report.Count != 1 || report.Total != 25 || len(report.Expenses) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if rec := h.do(http.MethodPost, "/reports/"+report.ID+"/reimburse", nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("reimbursing before submitting: status = %d, want 409", rec.Code)
+	}
+	report = decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports/"+report.ID+"/expenses", strings.NewReader(fmt.Sprintf(`{"expense_ids": [%q]}`, lunch.ID)), nil), http.StatusOK)
+	if report.Count != 2 || report.Total != 65 {
+		t.Errorf("after adding the lunch: count %d, total %v", report.Count, report.Total)
+	}
+	if rec := h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Otro", "expense_ids": [%q]}`, lunch.ID)), nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("claiming an expense twice: status = %d, want 422", rec.Code)
+	}
+
+	// Other users do not see the report
+	for _, rec := range []*httptest.ResponseRecorder{
+		h.do(http.MethodGet, "/reports/"+report.ID, nil, ana),
+		h.do(http.MethodGet, "/reports/"+report.ID+"/document", nil, ana),
+		h.do(http.MethodPost, "/reports/"+report.ID+"/expenses", strings.NewReader(fmt.Sprintf(`{"expense_ids": [%q]}`, lunch.ID)), ana),
+		h.do(http.MethodDelete, "/reports/"+report.ID+"/expenses/"+lunch.ID, nil, ana),
+		h.do(http.MethodPost, "/reports/"+report.ID+"/submit", nil, ana),
+		h.do(http.MethodPost, "/reports/"+report.ID+"/reimburse", nil, ana),
+		h.do(http.MethodPut, "/expenses/"+taxi.ID+"/reimbursable", strings.NewReader(`{"reimbursable": false}`), ana),
+	} {
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("another user's report: status = %d, want 404", rec.Code)
+		}
+	}
+
+	submitted := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports/"+report.ID+"/submit", nil, nil), http.StatusOK)
+	if submitted.Status != models.ReimbursementSubmitted || submitted.SubmittedAt == nil {
+		t.Fatalf("submitted = %+v", submitted)
+	}
+	for _, expense := range submitted.Expenses {
+		if expense.ReimbursementStatus != models.ReimbursementSubmitted || expense.ReportID == nil || *expense.ReportID != report.ID {
+			t.Errorf("expense of the submitted report = %+v", expense)
+		}
+	}
+	for _, rec := range []*httptest.ResponseRecorder{
+		h.do(http.MethodPost, "/reports/"+report.ID+"/submit", nil, nil),
+		h.do(http.MethodDelete, "/reports/"+report.ID+"/expenses/"+lunch.ID, nil, nil),
+		h.do(http.MethodPut, "/expenses/"+taxi.ID+"/reimbursable", strings.NewReader(`{"reimbursable": false}`), nil),
+		h.do(http.MethodPatch, "/expenses/"+taxi.ID, strings.NewReader(`{"unit_price": 100}`), nil),
+		h.do(http.MethodDelete, "/expenses/"+taxi.ID, nil, nil),
+	} {
+		if rec.Code != http.StatusConflict {
+			t.Errorf("change of a submitted report: status = %d, want 409", rec.Code)
+		}
+	}
+
+	reimbursed := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports/"+report.ID+"/reimburse", nil, nil), http.StatusOK)
+	if reimbursed.Status != models.ReimbursementReimbursed || reimbursed.ReimbursedAt == nil {
+		t.Fatalf("reimbursed = %+v", reimbursed)
+	}
+	paid := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses?reimbursement_status=reimbursed", nil, nil), http.StatusOK)
+	if *paid.Total != 2 {
+		t.Errorf("reimbursed expenses = %d, want 2", *paid.Total)
+	}
+
+	events := decode[[]models.ExpenseEvent](t, h.do(http.MethodGet, "/expenses/"+taxi.ID+"/history", nil, nil), http.StatusOK)
+	var actors []string
+	for _, event := range events {
+		actors = append(actors, event.Actor)
+	}
+	// create, flag, claim, submit, reimburse
+	if strings.Join(actors, ",") != "default,default,default,default,default" {
+		t.Errorf("history actors = %v", actors)
+	}
+
+	reports := decode[[]models.ExpenseReport](t, h.do(http.MethodGet, "/reports", nil, nil), http.StatusOK)
+	if len(reports) != 1 || reports[0].ID != report.ID || reports[0].Total != 65 || len(reports[0].Expenses) != 0 {
+		t.Errorf("reports = %+v", reports)
+	}
+	if reports := decode[[]models.ExpenseReport](t, h.do(http.MethodGet, "/reports", nil, ana), http.StatusOK); len(reports) != 0 {
+		t.Errorf("ana's reports = %+v", reports)
+	}
+}
+
+func TestReportExpensesCanBeRemovedBeforeSubmitting(t *testing.T) {
+	h := newHarness(t)
+	taxi := recordExpense(h, "taxi", 25)
+	markReimbursable(h, taxi.ID)
+
+	report := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje", "expense_ids": [%q]}`, taxi.ID)), nil), http.StatusCreated)
+	report = decode[models.ExpenseReport](t, h.do(http.MethodDelete, "/reports/"+report.ID+"/expenses/"+taxi.ID, nil, nil), http.StatusOK)
+	if report.Count != 0 || report.Total != 0 {
+		t.Errorf("after removing the taxi: count %d, total %v", report.Count, report.Total)
+	}
+	if rec := h.do(http.MethodDelete, "/reports/"+report.ID+"/expenses/"+taxi.ID, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("removing twice: status = %d, want 404", rec.Code)
+	}
+	if rec := h.do(http.MethodPost, "/reports/"+report.ID+"/submit", nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("submitting an empty report: status = %d, want 409", rec.Code)
+	}
+
+	expense, _ := h.expenses.FindByID(t.Context(), taxi.ID)
+	if expense.ReportID != nil || expense.ReimbursementStatus != models.ReimbursementPending {
+		t.Errorf("removed expense = %+v, want pending without report", expense)
+	}
+}
+
+func TestDeletedExpensesLeaveTheirReport(t *testing.T) {
+	h := newHarness(t)
+	taxi := recordExpense(h, "taxi", 25)
+	lunch := recordExpense(h, "almuerzo", 40)
+	markReimbursable(h, taxi.ID)
+	markReimbursable(h, lunch.ID)
+	report := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje", "expense_ids": [%q, %q]}`, taxi.ID, lunch.ID)), nil), http.StatusCreated)
+
+	decode[models.Expense](t, h.do(http.MethodDelete, "/expenses/"+taxi.ID, nil, nil), http.StatusOK)
+	submitted := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports/"+report.ID+"/submit", nil, nil), http.StatusOK)
+	if submitted.Count != 1 || submitted.Total != 40 {
+		t.Errorf("submitted report: count %d, total %v, want only the lunch", submitted.Count, submitted.Total)
+	}
+
+	restored := decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+taxi.ID+"/restore", nil, nil), http.StatusOK)
+	if restored.ReportID != nil || restored.ReimbursementStatus != models.ReimbursementPending {
+		t.Errorf("restored = %+v, want pending without report", restored)
+	}
+	if report := decode[models.ExpenseReport](t, h.do(http.MethodGet, "/reports/"+report.ID, nil, nil), http.StatusOK); report.Count != 1 {
+		t.Errorf("report after the restore: count %d, want 1", report.Count)
+	}
+	// Restored, the expense can be claimed by another report
+	decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Otro", "expense_ids": [%q]}`, taxi.ID)), nil), http.StatusCreated)
+}
+
+func TestReportDocument(t *testing.T) {
+	h := newHarness(t)
+	taxi := recordExpense(h, "taxi al aeropuerto", 1250.5)
+	h.openai.QueueChatCompletion("TOTTUS\nAGUA 2.50\nTOTAL 2.50")
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 2.5, Quantity: 1, Unit: "u", Description: "agua <sin gas>", Confidence: confidence(0.9)}})
+	water := decode[[]models.Expense](t, h.uploadReceipt(fakePNG, nil), http.StatusOK)[0]
+	markReimbursable(h, taxi.ID)
+	markReimbursable(h, water.ID)
+
+	report := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje a Lima", "expense_ids": [%q, %q]}`, taxi.ID, water.ID)), nil), http.StatusCreated)
+	rec := h.do(http.MethodGet, "/reports/"+report.ID+"/document?locale=es", nil, nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("status = %d, content type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	document := rec.Body.String()
+	for _, want := range []string{
+		"<h1>Viaje a Lima</h1>",
+		"Status: pending",
+		"taxi al aeropuerto<blockquote>“taxi al aeropuerto”</blockquote>",
+		"agua &lt;sin gas&gt;",
+		`<a href="#receipt-` + water.ReceiptID + `">`,
+		`<figure id="receipt-` + water.ReceiptID + `"><img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(fakePNG) + `"`,
+		"1.250,50",
+		"1.253,00",
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("document does not contain %q:\n%s", want, document)
+		}
+	}
+
+	if rec := h.do(http.MethodGet, "/reports/"+report.ID+"/document?locale=!!", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid locale: status = %d, want 400", rec.Code)
+	}
+	if rec := h.do(http.MethodGet, "/reports/7f1c6f6e-0000-4000-8000-000000000000/document", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown report: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestVoiceCommandsCannotChangeClaimedExpenses(t *testing.T) {
+	h := newHarness(t)
+	taxi := recordExpense(h, "taxi", 25)
+	markReimbursable(h, taxi.ID)
+	report := decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports", strings.NewReader(fmt.Sprintf(`{"title": "Viaje", "expense_ids": [%q]}`, taxi.ID)), nil), http.StatusCreated)
+	decode[models.ExpenseReport](t, h.do(http.MethodPost, "/reports/"+report.ID+"/submit", nil, nil), http.StatusOK)
+
+	h.openai.QueueTranscription(spanish("el taxi costó treinta"))
+	h.openai.QueueChatCompletion(`{"intent": "correct", "target": {"description": "taxi"}, "changes": {"total": 30}}`)
+	if rec := h.upload(fakeAudio, nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("correction: status = %d, want 409: %s", rec.Code, rec.Body.String())
+	}
+	h.openai.QueueTranscription(spanish("borra el taxi"))
+	h.openai.QueueChatCompletion(`{"intent": "delete", "target": {"description": "taxi"}}`)
+	if rec := h.upload(fakeAudio, nil, nil); rec.Code != http.StatusConflict {
+		t.Errorf("deletion: status = %d, want 409: %s", rec.Code, rec.Body.String())
+	}
+
+	if stored, err := h.expenses.FindByID(t.Context(), taxi.ID); err != nil || stored.UnitPrice != 25 {
+		t.Errorf("stored = %+v, %v, want the taxi as claimed", stored, err)
+	}
+}
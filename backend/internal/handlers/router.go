@@ -12,7 +12,7 @@ import (
 )
 
 // NewRouter creates and configures the HTTP router
-func NewRouter(service services.ExpenseService) http.Handler {
+func NewRouter(service services.ExpenseService, settingsService services.SettingsService, idempotencyService services.IdempotencyService, merchantService services.MerchantService, reconciliationService services.ReconciliationService, askService services.AskService, reportService services.ReportService) http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware
@@ -20,12 +20,51 @@ func NewRouter(service services.ExpenseService) http.Handler {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RealIP)
 
-	// Create expense handler
-	expenseHandler := NewExpenseHandler(service)
+	// Create handlers
+	expenseHandler := NewExpenseHandler(service, idempotencyService)
+	settingsHandler := NewSettingsHandler(settingsService)
+	receiptHandler := NewReceiptHandler(service)
+	merchantHandler := NewMerchantHandler(merchantService)
+	importHandler := NewImportHandler(service)
+	reconciliationHandler := NewReconciliationHandler(reconciliationService)
+	askHandler := NewAskHandler(askService)
+	reportHandler := NewReportHandler(reportService)
 
 	// Routes
 	r.Post("/upload", expenseHandler.HandleUpload)
+	r.Post("/upload/receipt", receiptHandler.HandleUpload)
+	r.Get("/receipts/{id}/image", receiptHandler.HandleImage)
+	r.Post("/import", importHandler.HandleImport)
+	r.Get("/reconciliation", reconciliationHandler.HandleReconciliation)
+	r.Post("/reconciliation", reconciliationHandler.HandleReconcile)
+	r.Delete("/reconciliation/matches/{transaction_id}", reconciliationHandler.HandleDeleteMatch)
+	r.Post("/ask", askHandler.HandleAsk)
 	r.Get("/expenses", expenseHandler.HandleList)
+	r.Get("/expenses/summary", expenseHandler.HandleSummary)
+	r.Get("/expenses/export", expenseHandler.HandleExport)
+	r.Patch("/expenses/{id}", expenseHandler.HandleUpdate)
+	r.Delete("/expenses/{id}", expenseHandler.HandleDelete)
+	r.Post("/expenses/{id}/restore", expenseHandler.HandleRestore)
+	r.Get("/expenses/{id}/history", expenseHandler.HandleHistory)
+	r.Post("/expenses/{id}/tags", expenseHandler.HandleTag)
+	r.Delete("/expenses/{id}/tags/{tag}", expenseHandler.HandleUntag)
+	r.Put("/expenses/{id}/reimbursable", expenseHandler.HandleReimbursable)
+	r.Post("/expenses/{id}/confirm", expenseHandler.HandleConfirm)
+	r.Post("/expenses/{id}/duplicate/merge", expenseHandler.HandleMergeDuplicate)
+	r.Post("/expenses/{id}/duplicate/dismiss", expenseHandler.HandleDismissDuplicate)
+	r.Get("/review", expenseHandler.HandleReview)
+	r.Get("/reports", reportHandler.HandleList)
+	r.Post("/reports", reportHandler.HandleCreate)
+	r.Get("/reports/{id}", reportHandler.HandleGet)
+	r.Get("/reports/{id}/document", reportHandler.HandleDocument)
+	r.Post("/reports/{id}/expenses", reportHandler.HandleAddExpenses)
+	r.Delete("/reports/{id}/expenses/{expense_id}", reportHandler.HandleRemoveExpense)
+	r.Post("/reports/{id}/submit", reportHandler.HandleSubmit)
+	r.Post("/reports/{id}/reimburse", reportHandler.HandleReimburse)
+	r.Get("/merchants", merchantHandler.HandleList)
+	r.Post("/merchants/{id}/aliases", merchantHandler.HandleAddAlias)
+	r.Get("/settings", settingsHandler.HandleGet)
+	r.Put("/settings", settingsHandler.HandleUpdate)
 
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+func TestSearchExpenses(t *testing.T) {
+	h := newHarness(t)
+
+	h.openai.QueueTranscription(spanish("Compré pilas para el control"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 8, Quantity: 4, Unit: "u", Description: "pilas AA"}})
+	decode[[]models.Expense](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	h.openai.QueueTranscription(spanish("Un café"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 5, Quantity: 1, Unit: "u", Description: "café"}})
+	decode[[]models.Expense](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	h.openai.QueueTranscription(spanish("Linterna para el viaje, ya tenía las pilas"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 25, Quantity: 1, Unit: "u", Description: "linterna"}})
+	decode[[]models.Expense](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	search := func(q string) models.PaginatedExpenses {
+		t.Helper()
+		rec := h.do(http.MethodGet, "/expenses?q="+url.QueryEscape(q), nil, nil)
+		return decode[models.PaginatedExpenses](t, rec, http.StatusOK)
+	}
+
+	// Matches in the description rank above matches only in the transcription
+	batteries := search("pila")
+	if len(batteries.Data) != 2 || batteries.Data[0].Description != "pilas AA" || batteries.Data[1].Description != "linterna" {
+		t.Fatalf("results for pila = %+v", batteries.Data)
+	}
+	best, other := batteries.Data[0].Match, batteries.Data[1].Match
+	if best == nil || other == nil || best.Rank <= other.Rank {
+		t.Fatalf("matches = %+v, %+v", best, other)
+	}
+	if best.Description != "<mark>pilas</mark> AA" {
+		t.Errorf("description excerpt = %q", best.Description)
+	}
+	if !strings.Contains(other.Transcription, "las <mark>pilas</mark>") || other.Description != "linterna" {
+		t.Errorf("linterna match = %+v", other)
+	}
+
+	// Accents are ignored
+	if coffee := search("cafe"); len(coffee.Data) != 1 || coffee.Data[0].Description != "café" {
+		t.Errorf("results for cafe = %+v", coffee.Data)
+	}
+	if none := search("pilas arroz"); len(none.Data) != 0 || *none.Total != 0 {
+		t.Errorf("results for pilas arroz = %+v", none.Data)
+	}
+
+	// Without q there are no matches
+	if list := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses", nil, nil), http.StatusOK); list.Data[0].Match != nil {
+		t.Errorf("unsearched expense has a match: %+v", list.Data[0].Match)
+	}
+}
+
+func TestSearchExpensesWithCursor(t *testing.T) {
+	h := newHarness(t)
+	for _, description := range []string{"pan", "pan integral", "pan de molde"} {
+		h.openai.QueueTranscription(spanish("compré " + description))
+		h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: description}})
+		decode[[]models.Expense](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+	}
+
+	seen := make(map[string]bool)
+	path := "/expenses?q=pan&per_page=2"
+	for page := 0; page < 3; page++ {
+		result := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, path, nil, nil), http.StatusOK)
+		for _, expense := range result.Data {
+			if seen[expense.ID] {
+				t.Errorf("%s listed twice", expense.Description)
+			}
+			seen[expense.ID] = true
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		path = "/expenses?q=pan&per_page=2&cursor=" + result.NextCursor
+	}
+	if len(seen) != 3 {
+		t.Errorf("paged through %d results, want 3", len(seen))
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/services"
+)
+
+// SettingsHandler handles HTTP requests for user settings
+type SettingsHandler struct {
+	service services.SettingsService
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(service services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{
+		service: service,
+	}
+}
+
+// HandleGet handles fetching the user's settings
+// @Summary Get user settings
+// @Description Returns the settings of the user identified by the X-User-ID header (defaults are returned if none are stored)
+// @Tags settings
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Success 200 {object} models.UserSettings "User settings"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /settings [get]
+func (h *SettingsHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetSettings(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get settings: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+// HandleUpdate handles updating the user's settings
+// @Summary Update user settings
+// @Description Updates the default language, used when the language of a recording cannot be detected, and the IANA timezone used to resolve spoken dates and to tell which day an expense falls on in list filters and summaries. Omitted fields keep their current value.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User identifier (default: default)"
+// @Param settings body models.UpdateSettingsRequest true "Settings to update (language: es, en or pt; timezone: IANA name such as America/Lima)"
+// @Success 200 {object} models.UserSettings "Updated settings"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /settings [put]
+func (h *SettingsHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), userIDFromRequest(r), req)
+	if errors.Is(err, services.ErrUnsupportedLanguage) || errors.Is(err, services.ErrInvalidTimezone) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update settings: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
@@ -0,0 +1,146 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+func TestUploadPicksUpSpokenTags(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("pasaje a cusco cien soles y almuerzo veinte, etiqueta viaje"))
+	h.openai.QueueExpenses([]expenseJSON{
+		{UnitPrice: 100, Quantity: 1, Unit: "pasaje", Description: "pasaje a cusco", Tags: []string{"viaje", "#Trabajo Cusco"}},
+		{UnitPrice: 20, Quantity: 1, Unit: "u", Description: "almuerzo", Tags: []string{"Viaje", "viaje"}},
+	})
+	expenses := decode[[]models.Expense](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	if got := strings.Join(expenses[0].Tags, ","); got != "trabajo-cusco,viaje" {
+		t.Errorf("ticket tags = %s, want trabajo-cusco,viaje", got)
+	}
+	if got := strings.Join(expenses[1].Tags, ","); got != "viaje" {
+		t.Errorf("lunch tags = %s, want viaje", got)
+	}
+
+	stored, err := h.expenses.FindByID(t.Context(), expenses[0].ID)
+	if err != nil || len(stored.Tags) != 2 {
+		t.Errorf("stored = %+v, %v", stored, err)
+	}
+}
+
+func TestUploadRejectsTooManySpokenTags(t *testing.T) {
+	h := newHarness(t)
+	var tags []string
+	for i := range 21 {
+		tags = append(tags, strings.Repeat("x", i+1))
+	}
+	h.openai.QueueTranscription(spanish("pan cuatro soles"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 4, Quantity: 1, Unit: "u", Description: "pan", Tags: tags}})
+
+	if rec := h.upload(fakeAudio, nil, nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+}
+
+func TestTagAndUntagExpense(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+
+	tagged := decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+bread.ID+"/tags", strings.NewReader(`{"tags": ["Trip Cusco", "reembolsable"]}`), nil), http.StatusOK)
+	if got := strings.Join(tagged.Tags, ","); got != "reembolsable,trip-cusco" {
+		t.Errorf("tags = %s", got)
+	}
+	// Tags the expense already has change nothing
+	decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+bread.ID+"/tags", strings.NewReader(`{"tags": ["trip-cusco"]}`), nil), http.StatusOK)
+
+	untagged := decode[models.Expense](t, h.do(http.MethodDelete, "/expenses/"+bread.ID+"/tags/Trip%20Cusco", nil, nil), http.StatusOK)
+	if got := strings.Join(untagged.Tags, ","); got != "reembolsable" {
+		t.Errorf("tags after removing = %s", got)
+	}
+	if rec := h.do(http.MethodDelete, "/expenses/"+bread.ID+"/tags/trip-cusco", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("removing a missing tag: status = %d, want 404", rec.Code)
+	}
+
+	// Tag changes are audited
+	events := decode[[]models.ExpenseEvent](t, h.do(http.MethodGet, "/expenses/"+bread.ID+"/history", nil, nil), http.StatusOK)
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action+" by "+event.Actor)
+	}
+	if got := strings.Join(actions, ", "); got != "create by default, update by default, update by default" {
+		t.Errorf("history = %s", got)
+	}
+
+	for _, body := range []string{`{"tags": []}`, `{"tags": ["#"]}`, `{"tags": ["` + strings.Repeat("a", 51) + `"]}`} {
+		if rec := h.do(http.MethodPost, "/expenses/"+bread.ID+"/tags", strings.NewReader(body), nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("tagging with %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := h.do(http.MethodPost, "/expenses/0d6c3f1e-9a55-4f43-9a0e-000000000000/tags", strings.NewReader(`{"tags": ["viaje"]}`), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("tagging an unknown expense: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestListAndSummarizeByTag(t *testing.T) {
+	h := newHarness(t)
+	ticket := recordExpense(h, "pasaje", 100)
+	lunch := recordExpense(h, "almuerzo", 20)
+	recordExpense(h, "pan", 4)
+	tag := func(expense models.Expense, tags string) {
+		t.Helper()
+		body := `{"tags": ["` + strings.Join(strings.Split(tags, ","), `", "`) + `"]}`
+		decode[models.Expense](t, h.do(http.MethodPost, "/expenses/"+expense.ID+"/tags", strings.NewReader(body), nil), http.StatusOK)
+	}
+	tag(ticket, "viaje,trabajo")
+	tag(lunch, "viaje")
+
+	descriptions := func(path string) []string {
+		t.Helper()
+		list := decode[models.PaginatedExpenses](t, h.do(http.MethodGet, path, nil, nil), http.StatusOK)
+		var found []string
+		for _, expense := range list.Data {
+			found = append(found, expense.Description)
+		}
+		slices.Sort(found)
+		return found
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/expenses?tags=viaje", []string{"almuerzo", "pasaje"}},
+		{"/expenses?tags=trabajo,viaje", []string{"almuerzo", "pasaje"}},
+		{"/expenses?tags=trabajo,viaje&tag_match=all", []string{"pasaje"}},
+		{"/expenses?tags=Viaje&tags=Trabajo&tag_match=all", []string{"pasaje"}},
+		{"/expenses?tags=otro", nil},
+	}
+	for _, tt := range tests {
+		if got := descriptions(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if rec := h.do(http.MethodGet, "/expenses?tags=viaje&tag_match=some", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid tag_match: status = %d, want 400", rec.Code)
+	}
+
+	// Expenses count in the group of each of their tags, but once in the totals
+	summary := decode[models.ExpenseSummary](t, h.do(http.MethodGet, "/expenses/summary?group_by=tag", nil, nil), http.StatusOK)
+	if summary.Total != 124 || summary.Count != 3 {
+		t.Errorf("summary total = %v over %d, want 124 over 3", summary.Total, summary.Count)
+	}
+	var groups []string
+	for _, group := range summary.Groups {
+		groups = append(groups, fmt.Sprintf("%s=%g", group.Key, group.Total))
+	}
+	if got := strings.Join(groups, " "); got != "viaje=120 trabajo=100 =4" {
+		t.Errorf("groups = %s", got)
+	}
+
+	filtered := decode[models.ExpenseSummary](t, h.do(http.MethodGet, "/expenses/summary?tags=viaje", nil, nil), http.StatusOK)
+	if filtered.Total != 120 || filtered.Count != 2 {
+		t.Errorf("viaje total = %v over %d, want 120 over 2", filtered.Total, filtered.Count)
+	}
+}
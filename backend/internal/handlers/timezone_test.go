@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/models"
+)
+
+// setTimezone stores the timezone setting of a user
+func setTimezone(t *testing.T, h *harness, userID string, timezone string) {
+	t.Helper()
+	body := strings.NewReader(`{"timezone": "` + timezone + `"}`)
+	settings := decode[models.UserSettings](t, h.do(http.MethodPut, "/settings", body, map[string]string{"X-User-ID": userID}), http.StatusOK)
+	if settings.Timezone != timezone {
+		t.Fatalf("timezone = %q, want %q", settings.Timezone, timezone)
+	}
+}
+
+// uploadLateNight records a 10 pm Lima purchase (03:00 UTC the next day) and a morning one
+func uploadLateNight(t *testing.T, h *harness, userID string) {
+	t.Helper()
+	for _, purchase := range []struct{ description, at string }{
+		{"cena", "2026-02-21T03:00:00Z"},
+		{"desayuno", "2026-02-21T13:00:00Z"},
+	} {
+		h.openai.QueueTranscription(spanish(purchase.description))
+		h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 10, Quantity: 1, Unit: "u", Description: purchase.description}})
+		fields := map[string]string{"purchased_at": purchase.at}
+		decode[[]models.Expense](t, h.upload(fakeAudio, fields, map[string]string{"X-User-ID": userID}), http.StatusOK)
+	}
+}
+
+func TestSettingsTimezone(t *testing.T) {
+	h := newHarness(t)
+
+	defaults := decode[models.UserSettings](t, h.do(http.MethodGet, "/settings", nil, nil), http.StatusOK)
+	if defaults.Timezone != models.DefaultTimezone {
+		t.Errorf("default timezone = %q, want %q", defaults.Timezone, models.DefaultTimezone)
+	}
+
+	setTimezone(t, h, "sam", "America/Lima")
+	// Updating the language alone keeps the timezone
+	rec := h.do(http.MethodPut, "/settings", strings.NewReader(`{"language": "en"}`), map[string]string{"X-User-ID": "sam"})
+	if settings := decode[models.UserSettings](t, rec, http.StatusOK); settings.Timezone != "America/Lima" {
+		t.Errorf("timezone after language update = %q", settings.Timezone)
+	}
+
+	if rec := h.do(http.MethodPut, "/settings", strings.NewReader(`{"timezone": "Lima"}`), nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid timezone: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestSummaryBucketsDaysInUserTimezone(t *testing.T) {
+	h := newHarness(t)
+	setTimezone(t, h, "sam", "America/Lima")
+	uploadLateNight(t, h, "sam")
+
+	rec := h.do(http.MethodGet, "/expenses/summary?group_by=day", nil, map[string]string{"X-User-ID": "sam"})
+	summary := decode[models.ExpenseSummary](t, rec, http.StatusOK)
+	if len(summary.Groups) != 2 || summary.Groups[0].Key != "2026-02-20" || summary.Groups[1].Key != "2026-02-21" {
+		t.Errorf("groups = %+v, want one on 2026-02-20 and one on 2026-02-21", summary.Groups)
+	}
+
+	rec = h.do(http.MethodGet, "/expenses/summary?from=2026-02-21&to=2026-02-21", nil, map[string]string{"X-User-ID": "sam"})
+	if day := decode[models.ExpenseSummary](t, rec, http.StatusOK); day.Count != 1 || day.Total != 10 {
+		t.Errorf("summary of 2026-02-21 = %d expenses totalling %v, want only breakfast", day.Count, day.Total)
+	}
+}
+
+func TestListFiltersDaysInUserTimezone(t *testing.T) {
+	h := newHarness(t)
+	setTimezone(t, h, "sam", "America/Lima")
+	uploadLateNight(t, h, "sam")
+
+	rec := h.do(http.MethodGet, "/expenses?from=2026-02-20&to=2026-02-20", nil, map[string]string{"X-User-ID": "sam"})
+	list := decode[models.PaginatedExpenses](t, rec, http.StatusOK)
+	if *list.Total != 1 || list.Data[0].Description != "cena" {
+		t.Errorf("expenses of 2026-02-20 in Lima = %+v, want only dinner", list.Data)
+	}
+
+	// In UTC both purchases fall on the 21st
+	setTimezone(t, h, "sam", "UTC")
+	list = decode[models.PaginatedExpenses](t, h.do(http.MethodGet, "/expenses?from=2026-02-21&to=2026-02-21", nil, map[string]string{"X-User-ID": "sam"}), http.StatusOK)
+	if *list.Total != 2 {
+		t.Errorf("expenses of 2026-02-21 in UTC = %d, want 2", *list.Total)
+	}
+
+	if rec := h.do(http.MethodGet, "/expenses?from=yesterday", nil, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid from: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestUploadUsesUserTimezoneForSpokenDates(t *testing.T) {
+	h := newHarness(t)
+	setTimezone(t, h, "sam", "America/Lima")
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	fields := map[string]string{"purchased_at": "2026-02-21T03:00:00Z"}
+	decode[[]models.Expense](t, h.upload(fakeAudio, fields, map[string]string{"X-User-ID": "sam"}), http.StatusOK)
+
+	system := h.openai.RequestsTo(fakes.EndpointChatCompletions)[0].Messages[0].Content
+	if !strings.Contains(system, "Friday 2026-02-20 22:00 (America/Lima)") {
+		t.Errorf("prompt does not carry the recording time in the user's timezone:\n%s", system)
+	}
+}
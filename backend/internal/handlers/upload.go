@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maxUploadBytes bounds the whole multipart body, and so each file in it, batches included.
+// The Lambda deployment takes requests of up to 6 MB, and API Gateway base64-encodes binary
+// bodies, so about 4.5 MB of body get through; a larger one would get the gateway's own 413
+// instead of ours.
+const maxUploadBytes = 4 << 20
+
+// maxFieldBytes bounds each non-file form field
+const maxFieldBytes = 4 << 10
+
+// maxBatchRecordings bounds the number of audio[<key>] parts in a batch upload
+const maxBatchRecordings = 10
+
+// uploadedFile is a file part streamed to a temp file
+type uploadedFile struct {
+	Field    string
+	Filename string
+	Path     string
+	Size     int64
+}
+
+// multipartUpload is a streamed multipart body
+type multipartUpload struct {
+	Fields map[string]string
+	Files  []uploadedFile
+}
+
+// Cleanup removes the temp files
+func (u *multipartUpload) Cleanup() {
+	for _, f := range u.Files {
+		os.Remove(f.Path)
+	}
+}
+
+// uploadError is a client-facing error while receiving an upload
+type uploadError struct {
+	status  int
+	message string
+}
+
+func (e *uploadError) Error() string {
+	return e.message
+}
+
+// readUpload streams a multipart body part by part: file parts go straight to temp
+// files, other parts become fields, in whatever order they arrive. The whole body is
+// capped with http.MaxBytesReader. On error the temp files are already removed.
+func readUpload(w http.ResponseWriter, r *http.Request) (*multipartUpload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, &uploadError{http.StatusBadRequest, fmt.Sprintf("Expected a multipart/form-data body: %v", err)}
+	}
+
+	upload := &multipartUpload{Fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return upload, nil
+		}
+		if err != nil {
+			upload.Cleanup()
+			return nil, bodyError(err, "Malformed multipart body", 0)
+		}
+
+		if part.FileName() != "" {
+			file, err := saveFilePart(part)
+			part.Close()
+			if err != nil {
+				upload.Cleanup()
+				return nil, err
+			}
+			upload.Files = append(upload.Files, *file)
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes+1))
+		part.Close()
+		if err != nil {
+			upload.Cleanup()
+			return nil, bodyError(err, fmt.Sprintf("Failed to read field %q", part.FormName()), 0)
+		}
+		if len(value) > maxFieldBytes {
+			upload.Cleanup()
+			return nil, &uploadError{http.StatusBadRequest, fmt.Sprintf("Field %q exceeds %d bytes", part.FormName(), maxFieldBytes)}
+		}
+		upload.Fields[part.FormName()] = string(value)
+	}
+}
+
+// saveFilePart streams a file part to a temp file; its size is bounded by maxUploadBytes
+func saveFilePart(part *multipart.Part) (*uploadedFile, error) {
+	// The extension is detected from the content later; never trust the file name
+	tmpFile, err := os.CreateTemp("", "audio-*")
+	if err != nil {
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to create temp file"}
+	}
+	defer tmpFile.Close()
+
+	written, err := io.Copy(tmpFile, part)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, bodyError(err, fmt.Sprintf("Failed to receive file %q", part.FileName()), written)
+	}
+
+	return &uploadedFile{
+		Field:    part.FormName(),
+		Filename: part.FileName(),
+		Path:     tmpFile.Name(),
+		Size:     written,
+	}, nil
+}
+
+// bodyError describes an error reading the request body, distinguishing an
+// oversized body from one that was cut short
+func bodyError(err error, context string, received int64) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("%s: request body exceeds %d bytes", context, maxBytesErr.Limit)}
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return &uploadError{http.StatusBadRequest, fmt.Sprintf("%s: upload interrupted after %d bytes, the body ended before the closing boundary", context, received)}
+	}
+	return &uploadError{http.StatusBadRequest, fmt.Sprintf("%s after %d bytes: %v", context, received, err)}
+}
+
+// batchKey returns the key of a batch field such as "audio[2]" for base "audio"
+func batchKey(field string, base string) (string, bool) {
+	if !strings.HasPrefix(field, base+"[") || !strings.HasSuffix(field, "]") {
+		return "", false
+	}
+	key := field[len(base)+1 : len(field)-1]
+	return key, key != ""
+}
+
+// writeUploadError writes an error returned by readUpload
+func writeUploadError(w http.ResponseWriter, err error) {
+	var uploadErr *uploadError
+	if errors.As(err, &uploadErr) {
+		http.Error(w, uploadErr.message, uploadErr.status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
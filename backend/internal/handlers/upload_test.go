@@ -0,0 +1,195 @@
+package handlers_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"upload-lambda/internal/fakes"
+	"upload-lambda/internal/models"
+)
+
+// zeros is an endless reader of zero bytes
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestUploadReadsPurchasedAtAfterAudio(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("audio", "note.m4a")
+	part.Write(fakeAudio)
+	writer.WriteField("purchased_at", "2026-02-21T08:15:00Z")
+	writer.Close()
+
+	rec := h.do(http.MethodPost, "/upload", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+	expenses := decode[[]models.Expense](t, rec, http.StatusOK)
+	if !expenses[0].PurchasedAt.Equal(time.Date(2026, 2, 21, 8, 15, 0, 0, time.UTC)) {
+		t.Fatalf("purchased_at = %v, want the field sent after the audio", expenses[0].PurchasedAt)
+	}
+}
+
+func TestUploadReportsInterruptedBody(t *testing.T) {
+	h := newHarness(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("audio", "note.m4a")
+	part.Write(fakeAudio)
+	// No closing boundary: the client went away mid-upload
+	truncated := body.Bytes()
+
+	rec := h.do(http.MethodPost, "/upload", bytes.NewReader(truncated), map[string]string{"Content-Type": writer.FormDataContentType()})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "interrupted") {
+		t.Fatalf("status = %d, body = %q; want 400 reporting the interrupted upload", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUploadRejectsOversizedBody(t *testing.T) {
+	h := newHarness(t)
+
+	boundary := "oversized"
+	head := "--" + boundary + "\r\nContent-Disposition: form-data; name=\"audio\"; filename=\"big.m4a\"\r\n\r\n"
+	body := io.MultiReader(strings.NewReader(head), io.LimitReader(zeros{}, 30<<20))
+
+	rec := h.do(http.MethodPost, "/upload", body, map[string]string{"Content-Type": "multipart/form-data; boundary=" + boundary})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(h.openai.Requests()) != 0 {
+		t.Error("oversized uploads must not reach OpenAI")
+	}
+}
+
+func TestUploadRejectsNonMultipartBody(t *testing.T) {
+	h := newHarness(t)
+	rec := h.do(http.MethodPost, "/upload", strings.NewReader(`{"audio": "..."}`), map[string]string{"Content-Type": "application/json"})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestBatchUploadReportsEachRecording(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("un pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 1, Quantity: 1, Unit: "u", Description: "pan"}})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, key := range []string{"a", "b", "c"} {
+		data := fakeAudio
+		if key == "c" {
+			data = []byte("definitely not audio")
+		}
+		part, _ := writer.CreateFormFile("audio["+key+"]", key+".m4a")
+		part.Write(data)
+		writer.WriteField("recording_id["+key+"]", "rec-"+key)
+	}
+	writer.WriteField("purchased_at[a]", "2026-02-21T08:15:00Z")
+	writer.WriteField("purchased_at[b]", "yesterday")
+	writer.Close()
+
+	rec := h.do(http.MethodPost, "/upload", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+	response := decode[models.BatchUploadResponse](t, rec, http.StatusMultiStatus)
+	if response.Succeeded != 1 || response.Failed != 2 || len(response.Results) != 3 {
+		t.Fatalf("response = %+v, want 1 success and 2 failures", response)
+	}
+
+	a, b, c := response.Results[0], response.Results[1], response.Results[2]
+	if a.Key != "a" || a.Status != http.StatusOK || a.ClientRecordingID != "rec-a" || len(a.Expenses) != 1 {
+		t.Fatalf("result a = %+v, want the extracted expense", a)
+	}
+	if !a.Expenses[0].PurchasedAt.Equal(time.Date(2026, 2, 21, 8, 15, 0, 0, time.UTC)) {
+		t.Errorf("purchased_at = %v, want the one sent for key a", a.Expenses[0].PurchasedAt)
+	}
+	if recording := h.recordings.Get(a.Expenses[0].RecordingID); recording == nil || recording.ClientRecordingID != "rec-a" {
+		t.Errorf("recording = %+v, want client recording id rec-a", recording)
+	}
+	if b.Key != "b" || b.Status != http.StatusBadRequest || !strings.Contains(b.Error, "purchased_at") {
+		t.Errorf("result b = %+v, want 400 for its purchased_at", b)
+	}
+	if c.Key != "c" || c.Status != http.StatusUnsupportedMediaType {
+		t.Errorf("result c = %+v, want 415", c)
+	}
+	if got := len(h.openai.RequestsTo(fakes.EndpointTranscriptions)); got != 1 {
+		t.Errorf("transcriptions = %d, want only the valid recording transcribed", got)
+	}
+}
+
+func TestBatchUploadOfFailedRecordings(t *testing.T) {
+	h := newHarness(t)
+	send := func(parts map[string][]byte, fields map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for key, data := range parts {
+			part, _ := writer.CreateFormFile("audio["+key+"]", key+".m4a")
+			part.Write(data)
+		}
+		for name, value := range fields {
+			writer.WriteField(name, value)
+		}
+		writer.Close()
+		return h.do(http.MethodPost, "/upload", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+	}
+	notAudio := []byte("definitely not audio")
+
+	// Failures of the same kind answer with their status
+	response := decode[models.BatchUploadResponse](t, send(map[string][]byte{"a": notAudio, "b": notAudio}, nil), http.StatusUnsupportedMediaType)
+	if response.Succeeded != 0 || response.Failed != 2 {
+		t.Errorf("response = %+v, want 2 failures", response)
+	}
+	// Different failures answer with the highest status
+	response = decode[models.BatchUploadResponse](t, send(map[string][]byte{"a": notAudio, "b": fakeAudio}, map[string]string{"purchased_at[b]": "yesterday"}), http.StatusUnsupportedMediaType)
+	if response.Failed != 2 {
+		t.Errorf("response = %+v, want 2 failures", response)
+	}
+}
+
+func TestBatchUploadRejectsBodiesOverTheRequestLimit(t *testing.T) {
+	h := newHarness(t)
+
+	// Two recordings of 2.5 MB each fit one by one, but not together in a single request
+	boundary := "batch"
+	var parts []io.Reader
+	for _, key := range []string{"a", "b"} {
+		head := "--" + boundary + "\r\nContent-Disposition: form-data; name=\"audio[" + key + "]\"; filename=\"" + key + ".m4a\"\r\n\r\n"
+		parts = append(parts, strings.NewReader(head), io.LimitReader(zeros{}, 5<<20/2), strings.NewReader("\r\n"))
+	}
+	parts = append(parts, strings.NewReader("--"+boundary+"--\r\n"))
+
+	rec := h.do(http.MethodPost, "/upload", io.MultiReader(parts...), map[string]string{"Content-Type": "multipart/form-data; boundary=" + boundary})
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "request body exceeds") {
+		t.Fatalf("status = %d, want 413 for the batch size; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(h.openai.Requests()) != 0 {
+		t.Error("oversized batches must not reach OpenAI")
+	}
+}
+
+func TestUploadRejectsMixedSingleAndBatchParts(t *testing.T) {
+	h := newHarness(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, field := range []string{"audio", "audio[1]"} {
+		part, _ := writer.CreateFormFile(field, "note.m4a")
+		part.Write(fakeAudio)
+	}
+	writer.Close()
+
+	rec := h.do(http.MethodPost, "/upload", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400; body: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"upload-lambda/internal/models"
+)
+
+// userIDHeader carries the identifier of the user making the request
+const userIDHeader = "X-User-ID"
+
+// userIDFromRequest returns the user ID from the request headers, or the default user
+func userIDFromRequest(r *http.Request) string {
+	userID := strings.TrimSpace(r.Header.Get(userIDHeader))
+	if userID == "" {
+		return models.DefaultUserID
+	}
+	return userID
+}
@@ -0,0 +1,191 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+)
+
+// recordExpense uploads a recording of a single expense
+func recordExpense(h *harness, description string, unitPrice float64) models.Expense {
+	h.t.Helper()
+	h.openai.QueueTranscription(spanish(description))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: unitPrice, Quantity: 1, Unit: "u", Description: description}})
+	return decode[[]models.Expense](h.t, h.upload(fakeAudio, nil, nil), http.StatusOK)[0]
+}
+
+func TestVoiceCorrection(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+	recordExpense(h, "leche", 3)
+
+	h.openai.QueueTranscription(spanish("el pan costó cinco, no cuatro"))
+	h.openai.QueueChatCompletion(`{"intent": "correct", "target": {"description": "panes"}, "changes": {"total": 5}}`)
+	result := decode[models.VoiceCommandResult](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	if result.Intent != models.VoiceIntentCorrect || result.Transcription != "el pan costó cinco, no cuatro" || len(result.Changes) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	change := result.Changes[0]
+	if change.Action != models.ExpenseChangeUpdated || change.Before.ID != bread.ID || change.Before.UnitPrice != 4 || change.After.UnitPrice != 5 {
+		t.Fatalf("change = %+v, before %+v, after %+v", change, change.Before, change.After)
+	}
+	if len(h.expenses.All()) != 2 {
+		t.Errorf("expenses = %d, want the correction not to record a new one", len(h.expenses.All()))
+	}
+	if stored, _ := h.expenses.FindByID(context.Background(), bread.ID); stored.UnitPrice != 5 {
+		t.Errorf("stored unit price = %v, want 5", stored.UnitPrice)
+	}
+
+	// Undo with the before values
+	rec := h.do(http.MethodPatch, "/expenses/"+bread.ID, strings.NewReader(`{"unit_price": 4}`), nil)
+	if undone := decode[models.Expense](t, rec, http.StatusOK); undone.UnitPrice != 4 || undone.Description != "pan" {
+		t.Errorf("undone expense = %+v", undone)
+	}
+}
+
+func TestVoiceDeletion(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+	milk := recordExpense(h, "leche", 3)
+
+	h.openai.QueueTranscription(spanish("borra el último gasto"))
+	h.openai.QueueChatCompletion(`{"intent": "delete", "target": {"description": ""}}`)
+	result := decode[models.VoiceCommandResult](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	if len(result.Changes) != 1 || result.Changes[0].Action != models.ExpenseChangeDeleted || result.Changes[0].Before.ID != milk.ID || result.Changes[0].After != nil {
+		t.Fatalf("result = %+v", result)
+	}
+	if _, err := h.expenses.FindByID(context.Background(), milk.ID); err != repositories.ErrExpenseNotFound {
+		t.Errorf("deleted expense still found: %v", err)
+	}
+	if _, err := h.expenses.FindByID(context.Background(), bread.ID); err != nil {
+		t.Errorf("other expense: %v", err)
+	}
+}
+
+func TestVoiceDeletionSkipsImportedTransactions(t *testing.T) {
+	h := newHarness(t)
+	sam := map[string]string{"X-User-ID": "sam"}
+	h.openai.QueueTranscription(spanish("pan"))
+	h.openai.QueueExpenses([]expenseJSON{{UnitPrice: 4, Quantity: 1, Unit: "u", Description: "pan"}})
+	bread := decode[[]models.Expense](t, h.upload(fakeAudio, nil, sam), http.StatusOK)[0]
+	decode[models.ImportResult](t, h.importStatement("bank.csv", bankCSV, map[string]string{"mapping": bankMapping}), http.StatusOK)
+
+	// The bank transactions were imported after the bread, but were not recorded by the user
+	h.openai.QueueTranscription(spanish("borra el último gasto"))
+	h.openai.QueueChatCompletion(`{"intent": "delete", "target": {"description": ""}}`)
+	result := decode[models.VoiceCommandResult](t, h.upload(fakeAudio, nil, sam), http.StatusOK)
+	if len(result.Changes) != 1 || result.Changes[0].Before.ID != bread.ID {
+		t.Fatalf("result = %+v, want the bread deleted", result)
+	}
+}
+
+func TestVoiceCommandWithoutMatchingExpense(t *testing.T) {
+	h := newHarness(t)
+	recordExpense(h, "pan", 4)
+
+	// Expenses of other users are never matched
+	h.openai.QueueTranscription(spanish("borra el pan"))
+	h.openai.QueueChatCompletion(`{"intent": "delete", "target": {"description": "pan"}}`)
+	if rec := h.upload(fakeAudio, nil, map[string]string{"X-User-ID": "someone-else"}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422: %s", rec.Code, rec.Body.String())
+	}
+
+	h.openai.QueueTranscription(spanish("el arroz costó tres"))
+	h.openai.QueueChatCompletion(`{"intent": "correct", "target": {"description": "arroz"}, "changes": {"unit_price": 3}}`)
+	if rec := h.upload(fakeAudio, nil, nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422: %s", rec.Code, rec.Body.String())
+	}
+
+	h.openai.QueueTranscription(spanish("el pan costó menos cinco"))
+	h.openai.QueueChatCompletion(`{"intent": "correct", "target": {"description": "pan"}, "changes": {"unit_price": -5}}`)
+	if rec := h.upload(fakeAudio, nil, nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422: %s", rec.Code, rec.Body.String())
+	}
+
+	if expenses := h.expenses.All(); len(expenses) != 1 || expenses[0].UnitPrice != 4 {
+		t.Errorf("expenses = %+v, want the bread unchanged", expenses)
+	}
+}
+
+func TestVoiceQuery(t *testing.T) {
+	h := newHarness(t)
+	h.openai.QueueTranscription(spanish("¿cuánto gasté hoy?"))
+	h.openai.QueueChatCompletion(`{"intent": "query"}`)
+	result := decode[models.VoiceCommandResult](t, h.upload(fakeAudio, nil, nil), http.StatusOK)
+
+	if result.Intent != models.VoiceIntentQuery || result.Transcription != "¿cuánto gasté hoy?" || len(result.Changes) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(h.expenses.All()) != 0 {
+		t.Error("question recorded an expense")
+	}
+}
+
+func TestBatchVoiceCommand(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+
+	h.openai.QueueTranscription(spanish("elimina el pan"))
+	h.openai.QueueChatCompletion(`{"intent": "delete", "target": {"description": "pan"}}`)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("audio[a]", "a.m4a")
+	part.Write(fakeAudio)
+	writer.Close()
+
+	rec := h.do(http.MethodPost, "/upload", &body, map[string]string{"Content-Type": writer.FormDataContentType()})
+	response := decode[models.BatchUploadResponse](t, rec, http.StatusOK)
+	result := response.Results[0]
+	if result.Status != http.StatusOK || result.Intent != models.VoiceIntentDelete || len(result.Changes) != 1 || result.Changes[0].Before.ID != bread.ID {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestUpdateExpenseValidation(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+
+	for _, body := range []string{`{}`, `{"quantity": 0}`, `{"unit": "barril"}`, `{"description": " "}`} {
+		if rec := h.do(http.MethodPatch, "/expenses/"+bread.ID, strings.NewReader(body), nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := h.do(http.MethodPatch, "/expenses/0d6c3f1e-9a55-4f43-9a0e-000000000000", strings.NewReader(`{"unit_price": 1}`), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+
+	rec := h.do(http.MethodPatch, "/expenses/"+bread.ID, strings.NewReader(`{"quantity": 2, "unit": "kg", "description": "pan integral"}`), nil)
+	if updated := decode[models.Expense](t, rec, http.StatusOK); updated.Quantity != 2 || updated.Unit != "kg" || updated.Description != "pan integral" || updated.UnitPrice != 4 {
+		t.Errorf("updated = %+v", updated)
+	}
+}
+
+func TestChangesToExpensesOfOtherUsersAreNotFound(t *testing.T) {
+	h := newHarness(t)
+	bread := recordExpense(h, "pan", 4)
+	h.do(http.MethodPost, "/expenses/"+bread.ID+"/tags", strings.NewReader(`{"tags": ["casa"]}`), nil)
+	ana := map[string]string{"X-User-ID": "ana"}
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"update":  h.do(http.MethodPatch, "/expenses/"+bread.ID, strings.NewReader(`{"unit_price": 1}`), ana),
+		"confirm": h.do(http.MethodPost, "/expenses/"+bread.ID+"/confirm", nil, ana),
+		"tag":     h.do(http.MethodPost, "/expenses/"+bread.ID+"/tags", strings.NewReader(`{"tags": ["viaje"]}`), ana),
+		"untag":   h.do(http.MethodDelete, "/expenses/"+bread.ID+"/tags/casa", nil, ana),
+	} {
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", name, rec.Code)
+		}
+	}
+	if stored, _ := h.expenses.FindByID(context.Background(), bread.ID); stored.UnitPrice != 4 || len(stored.Tags) != 1 {
+		t.Errorf("stored = %+v, want it unchanged", stored)
+	}
+}
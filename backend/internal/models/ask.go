@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Ask aggregations
+const (
+	AskAggregationSum     = "sum"
+	AskAggregationCount   = "count"
+	AskAggregationAverage = "average"
+	AskAggregationMax     = "max"
+	AskAggregationMin     = "min"
+)
+
+// AskAggregations lists the aggregations a question can be answered with
+var AskAggregations = []string{AskAggregationSum, AskAggregationCount, AskAggregationAverage, AskAggregationMax, AskAggregationMin}
+
+// AskQuery is the constrained query a question is translated into: filters over the user's
+// expenses and how to aggregate them. It is validated before it reaches the repository.
+type AskQuery struct {
+	// Aggregation is one of AskAggregations, over expense totals (count counts expenses)
+	Aggregation string `json:"aggregation"`
+	// From and To are whole days (YYYY-MM-DD) in the user's timezone, both optional
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Search is a full-text search over descriptions and transcriptions (optional), not a
+	// semantic one: the model expands topics into the words to look for
+	Search string `json:"search,omitempty"`
+	// Merchant is the name of one of the user's merchants (optional)
+	Merchant string `json:"merchant,omitempty"`
+	// Units limits the expenses to these units of measurement (optional)
+	Units []string `json:"units,omitempty"`
+	// GroupBy is one of SummaryGroupings (optional); the answer is then the top group
+	GroupBy string `json:"group_by,omitempty"`
+}
+
+// AskContext is what the model is told to translate a question
+type AskContext struct {
+	Language string
+	// Now is the current time in the user's timezone, to resolve "este mes" or "ayer"
+	Now time.Time
+	// Merchants are the names of the user's merchants
+	Merchants []string
+}
+
+// AskTranslation is the model's translation of a question
+type AskTranslation struct {
+	Query AskQuery `json:"query"`
+	// Reply is a short answer in the question's language with an {answer} placeholder for the
+	// number, and a {group} placeholder for the top group of grouped queries
+	Reply         string `json:"reply"`
+	PromptVersion string `json:"-"`
+}
+
+// AskParams represents a question about the user's expenses, asked in text or by voice
+type AskParams struct {
+	UserID   string
+	Question string
+	// AudioPath is a recorded question, transcribed when Question is empty
+	AudioPath string
+}
+
+// AskAnswer is the answer to a question
+type AskAnswer struct {
+	// Question is the question asked, transcribed if it was recorded
+	Question string   `json:"question"`
+	Query    AskQuery `json:"query"`
+	// Answer is the aggregated value: an amount, or the number of expenses for count
+	Answer float64 `json:"answer"`
+	// Count is the number of expenses the answer is computed from
+	Count int `json:"count"`
+	// Group is the top group of a grouped query (the largest value, or the smallest for min)
+	Group *SummaryGroup `json:"group,omitempty"`
+	// Reply is the answer in words
+	Reply         string `json:"reply"`
+	PromptVersion string `json:"prompt_version"`
+}
+
+// AskRequest represents a typed question
+type AskRequest struct {
+	Question string `json:"question"`
+}
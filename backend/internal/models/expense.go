@@ -2,15 +2,70 @@ package models
 
 import "time"
 
+// Expense review statuses
+const (
+	ExpenseStatusConfirmed   = "confirmed"
+	ExpenseStatusNeedsReview = "needs_review"
+)
+
+// Expense sources
+const (
+	ExpenseSourceVoice   = "voice"
+	ExpenseSourceReceipt = "receipt"
+	ExpenseSourceImport  = "import"
+)
+
 // Expense represents an expense record
 type Expense struct {
 	ID          string    `json:"id"`
+	UserID      string    `json:"user_id,omitempty"`
 	UnitPrice   float64   `json:"unit_price"`
 	Quantity    float64   `json:"quantity"`
 	Unit        string    `json:"unit"`
 	Description string    `json:"description"`
 	PurchasedAt time.Time `json:"purchased_at"`
-	CreatedAt   time.Time `json:"created_at"`
+	RecordingID string    `json:"recording_id,omitempty"`
+	ReceiptID   string    `json:"receipt_id,omitempty"`
+	MerchantID  *string   `json:"merchant_id,omitempty"`
+	// Merchant is the name of the linked merchant (read-only)
+	Merchant string `json:"merchant,omitempty"`
+	// Source is what the expense was read from: voice, receipt or import
+	Source string `json:"source"`
+	// ExternalID is the bank's transaction ID of an imported expense
+	ExternalID    string   `json:"external_id,omitempty"`
+	PromptVersion string   `json:"prompt_version,omitempty"`
+	Confidence    *float64 `json:"confidence,omitempty"`
+	Status        string   `json:"status"`
+	// PossibleDuplicateOf is the ID of an earlier expense this one likely duplicates, until merged or dismissed
+	PossibleDuplicateOf *string `json:"possible_duplicate_of,omitempty"`
+	// Tags are the expense's normalized tags, in name order
+	Tags []string `json:"tags,omitempty"`
+	// Reimbursable expenses were paid personally for work; ReimbursementStatus tracks their claim
+	Reimbursable        bool   `json:"reimbursable,omitempty"`
+	ReimbursementStatus string `json:"reimbursement_status,omitempty"`
+	// ReportID is the expense report claiming the expense
+	ReportID  *string   `json:"report_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// DeletedAt is when the expense was deleted; deleted expenses are only seen in their history
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Match is how the expense matched a full-text search, only set in search results
+	Match *ExpenseMatch `json:"match,omitempty"`
+	// Transcription is the text of the recording the expense was read from, only set in reports
+	Transcription string `json:"transcription,omitempty"`
+}
+
+// ExpenseMatch describes how an expense matched a full-text search. The excerpts wrap the
+// matching words in <mark> tags.
+type ExpenseMatch struct {
+	Rank        float64 `json:"rank"`
+	Description string  `json:"description"`
+	// Transcription is an excerpt of the recording, empty if it did not match
+	Transcription string `json:"transcription,omitempty"`
+}
+
+// Total returns the amount paid for the expense
+func (e *Expense) Total() float64 {
+	return e.UnitPrice * e.Quantity
 }
 
 // ExpenseData represents the data extracted from audio transcription
@@ -19,21 +74,77 @@ type ExpenseData struct {
 	Quantity    float64 `json:"quantity"`
 	Unit        string  `json:"unit"`
 	Description string  `json:"description"`
+	// Merchant is where it was bought, as mentioned; empty if not mentioned
+	Merchant string `json:"merchant,omitempty"`
+	// DaysAgo is a spoken relative day ("ayer" is 1), counted back from the recording date
+	DaysAgo *int `json:"days_ago,omitempty"`
+	// PurchasedAt is a spoken calendar date, "YYYY-MM-DD" or "YYYY-MM-DDTHH:MM" in the user's timezone
+	PurchasedAt string `json:"purchased_at,omitempty"`
+	// Confidence is the model's confidence in this expense (0-1), nil if the prompt does not ask for it
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Tags are the labels spoken for this expense ("etiqueta viaje" gives "viaje"), since extract@v7
+	Tags []string `json:"tags,omitempty"`
+
+	// PromptVersion identifies the prompt template that produced this data (not part of the model output)
+	PromptVersion string `json:"-"`
+}
+
+// ExpenseUpdate represents a partial update of an expense; nil fields are left unchanged
+type ExpenseUpdate struct {
+	UnitPrice   *float64 `json:"unit_price,omitempty"`
+	Quantity    *float64 `json:"quantity,omitempty"`
+	Unit        *string  `json:"unit,omitempty"`
+	Description *string  `json:"description,omitempty"`
 }
 
 // ListExpensesParams represents the parameters for listing expenses
 type ListExpensesParams struct {
-	Page    int
-	PerPage int
-	OrderBy string // "purchased_at" or "created_at"
+	Page     int
+	PerPage  int
+	OrderBy  string // "purchased_at" or "created_at"
 	OrderDir string // "asc" or "desc"
+	Status   string // optional: "confirmed" or "needs_review"
+	// Cursor is the next_cursor of the previous page; when set, Page is ignored
+	Cursor string
+	// IncludeTotal counts the matching expenses into Total and TotalPages
+	IncludeTotal bool
+	// Query is a full-text search over descriptions and transcriptions (optional); matches
+	// are listed best first instead of in OrderBy order
+	Query string
+	// PossibleDuplicate limits the list to expenses flagged as likely duplicates
+	PossibleDuplicate bool
+	// MerchantID limits the list to the expenses of a merchant (optional)
+	MerchantID string
+	// ReimbursementStatus limits the list to reimbursable expenses in that status (optional)
+	ReimbursementStatus string
+	// TagFilter limits the list to tagged expenses (optional)
+	TagFilter
+	// DateRange limits the list to expenses purchased within it (optional)
+	DateRange
+	// UserID is the user whose expenses are listed (default: the default user); their
+	// timezone resolves the days of DateRange
+	UserID string
+}
+
+// DateRange bounds purchased_at, both ends inclusive and optional
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+	// FromDay and ToDay (YYYY-MM-DD) are whole days in the user's timezone,
+	// resolved into From and To by the service
+	FromDay string
+	ToDay   string
 }
 
 // PaginatedExpenses represents a paginated response of expenses
 type PaginatedExpenses struct {
-	Data       []*Expense `json:"data"`
-	Page       int        `json:"page"`
-	PerPage    int        `json:"per_page"`
-	Total      int        `json:"total"`
-	TotalPages int        `json:"total_pages"`
+	Data []*Expense `json:"data"`
+	// Page is omitted when the page was requested with a cursor
+	Page    int `json:"page,omitempty"`
+	PerPage int `json:"per_page"`
+	// NextCursor continues the list after this page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total and TotalPages are only counted when asked for
+	Total      *int `json:"total,omitempty"`
+	TotalPages *int `json:"total_pages,omitempty"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Expense event actions
+const (
+	ExpenseEventCreate  = "create"
+	ExpenseEventUpdate  = "update"
+	ExpenseEventDelete  = "delete"
+	ExpenseEventRestore = "restore"
+)
+
+// ExpenseEvent is an entry of the audit history of an expense
+type ExpenseEvent struct {
+	ID        int64  `json:"id"`
+	ExpenseID string `json:"expense_id"`
+	// Action is create, update, delete or restore
+	Action string `json:"action"`
+	// Actor is the user who made the change
+	Actor string `json:"actor"`
+	// Before and After are the expense as it was stored before and after the change; Before is
+	// omitted for creations
+	Before    json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After     json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
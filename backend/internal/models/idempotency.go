@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is a request made with an idempotency key and, once completed, its response
+type IdempotencyRecord struct {
+	UserID string
+	Key    string
+	// Fingerprint identifies the request payload, so a reused key with a different payload can be rejected
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	// CompletedAt is nil while the original request is still being processed
+	CompletedAt *time.Time
+}
+
+// Completed reports whether the response has been stored
+func (r *IdempotencyRecord) Completed() bool {
+	return r.CompletedAt != nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Merchant is a store, market or business where expenses are made
+type Merchant struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// Name is the display name, as first mentioned
+	Name string `json:"name"`
+	// NormalizedName is the lowercase, accent-folded name used to match mentions
+	NormalizedName string `json:"normalized_name"`
+	// Aliases are the normalized mentions that resolve to this merchant
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddMerchantAliasRequest represents a request to add an alias to a merchant
+type AddMerchantAliasRequest struct {
+	Alias string `json:"alias"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// OCR engines
+const (
+	OCREngineOpenAI    = "openai"
+	OCREngineTesseract = "tesseract"
+)
+
+// Receipt represents a photographed receipt and the text read from it
+type Receipt struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	ContentType string `json:"content_type"`
+	// Image is only loaded when serving the image itself
+	Image    []byte `json:"-"`
+	Language string `json:"language"`
+	OCRText  string `json:"ocr_text"`
+	// OCREngine is the engine that read the text (openai or tesseract)
+	OCREngine     string    `json:"ocr_engine"`
+	OCRConfidence *float64  `json:"ocr_confidence,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// OCRResult is the text read from an image
+type OCRResult struct {
+	Text   string
+	Engine string
+	// Confidence is the engine's mean word confidence (0-1), nil if the engine does not report one
+	Confidence *float64
+}
+
+// ProcessReceiptParams represents the parameters for processing a receipt image
+type ProcessReceiptParams struct {
+	ImagePath   string
+	PurchasedAt time.Time
+	UserID      string
+	// Language of the receipt (optional, defaults to the user's language)
+	Language string
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// ReconciliationMatch links a recorded expense to the imported bank transaction that paid for it.
+// Several expenses of one recording or receipt can match the same transaction.
+type ReconciliationMatch struct {
+	ExpenseID     string    `json:"expense_id"`
+	TransactionID string    `json:"transaction_id"`
+	MatchedAt     time.Time `json:"matched_at"`
+}
+
+// ReconciliationRejection is a match the user deleted, which reconciliation does not make again
+type ReconciliationRejection struct {
+	ExpenseID     string    `json:"expense_id"`
+	TransactionID string    `json:"transaction_id"`
+	RejectedAt    time.Time `json:"rejected_at"`
+}
+
+// ReconciliationParams represents the parameters of a reconciliation
+type ReconciliationParams struct {
+	UserID string
+	// DateRange limits the report (optional, default: the period of the imported transactions
+	// of the last 90 days)
+	DateRange
+}
+
+// ReconciledTransaction is an imported bank transaction with the recorded expenses it paid for
+type ReconciledTransaction struct {
+	Transaction *Expense   `json:"transaction"`
+	Expenses    []*Expense `json:"expenses"`
+	// Difference is the transaction amount minus the total of the recorded expenses
+	Difference float64 `json:"difference"`
+}
+
+// Reconciliation compares the recorded expenses of a user with the imported bank transactions
+type Reconciliation struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	// Matched are the transactions with recorded expenses, by posting date
+	Matched []ReconciledTransaction `json:"matched"`
+	// UnmatchedRecorded are recorded expenses without a bank transaction (e.g. paid in cash)
+	UnmatchedRecorded []*Expense `json:"unmatched_recorded"`
+	// UnmatchedBank are bank transactions that were never recorded
+	UnmatchedBank []*Expense `json:"unmatched_bank"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Supported recording languages (ISO 639-1 codes)
+const (
+	LanguageSpanish    = "es"
+	LanguageEnglish    = "en"
+	LanguagePortuguese = "pt"
+)
+
+// DefaultLanguage is used when neither Whisper nor the user settings provide a supported language
+const DefaultLanguage = LanguageSpanish
+
+// SupportedLanguages lists the languages with extraction prompts and unit vocabularies
+var SupportedLanguages = []string{LanguageSpanish, LanguageEnglish, LanguagePortuguese}
+
+// IsSupportedLanguage reports whether the given ISO 639-1 code is supported
+func IsSupportedLanguage(language string) bool {
+	for _, l := range SupportedLanguages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// Recording represents a processed audio recording
+type Recording struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// ClientRecordingID is the identifier the app assigned to the recording, if any
+	ClientRecordingID string `json:"client_recording_id,omitempty"`
+	Language          string `json:"language"`
+	Transcription     string `json:"transcription"`
+	// TranscriptionConfidence is derived from Whisper's segment log-probabilities
+	TranscriptionConfidence *float64  `json:"transcription_confidence,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// Transcription represents the result of transcribing an audio file
+type Transcription struct {
+	Text     string
+	Language string // ISO 639-1 code, empty if Whisper did not detect a known language
+	// Confidence is the duration-weighted mean of exp(avg_logprob) over the segments, nil without segments
+	Confidence *float64
+}
+
+// ProcessAudioParams represents the parameters for processing an audio recording
+type ProcessAudioParams struct {
+	AudioPath string
+	// PurchasedAt is when the recording was made; spoken dates are resolved relative to it
+	PurchasedAt time.Time
+	// Location is the device timezone, nil to use the user's timezone setting
+	Location *time.Location
+	UserID   string
+	// ClientRecordingID is the app's identifier for the recording (optional)
+	ClientRecordingID string
+}
+
+// UploadResult is the outcome of one recording in a batch upload
+type UploadResult struct {
+	Key               string     `json:"key"`
+	ClientRecordingID string     `json:"recording_id,omitempty"`
+	Status            int        `json:"status"`
+	Error             string     `json:"error,omitempty"`
+	Code              string     `json:"code,omitempty"`
+	Expenses          []*Expense `json:"expenses,omitempty"`
+	// Intent and Changes are set for recordings that corrected or deleted an expense
+	// instead of recording new ones (see VoiceCommandResult)
+	Intent  string          `json:"intent,omitempty"`
+	Changes []ExpenseChange `json:"changes,omitempty"`
+	// Replayed is set when the recording was already processed and its stored result is returned
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// BatchUploadResponse is the response of a batch upload
+type BatchUploadResponse struct {
+	Results   []UploadResult `json:"results"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+}
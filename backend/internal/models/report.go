@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Reimbursement statuses of reimbursable expenses, and of the reports claiming them
+const (
+	ReimbursementPending    = "pending"
+	ReimbursementSubmitted  = "submitted"
+	ReimbursementReimbursed = "reimbursed"
+)
+
+// ReimbursementStatuses lists the reimbursement statuses in the order expenses go through them
+var ReimbursementStatuses = []string{ReimbursementPending, ReimbursementSubmitted, ReimbursementReimbursed}
+
+// ExpenseReport groups reimbursable expenses claimed together
+type ExpenseReport struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Title  string `json:"title"`
+	// Status is pending until the report is submitted, then submitted and finally reimbursed
+	Status string `json:"status"`
+	// SubmittedAt is when the report was submitted, nil while it can still be changed
+	SubmittedAt  *time.Time `json:"submitted_at,omitempty"`
+	ReimbursedAt *time.Time `json:"reimbursed_at,omitempty"`
+	// Total and Count cover the report's expenses that are not deleted
+	Total     float64   `json:"total"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+	// Expenses are only loaded for a single report, oldest purchase first
+	Expenses []*Expense `json:"expenses,omitempty"`
+}
+
+// ReimbursableRequest represents a request to flag or unflag an expense as reimbursable
+type ReimbursableRequest struct {
+	Reimbursable bool `json:"reimbursable"`
+}
+
+// CreateReportRequest represents a request to create an expense report
+type CreateReportRequest struct {
+	Title string `json:"title"`
+	// ExpenseIDs are pending reimbursable expenses to include (optional)
+	ExpenseIDs []string `json:"expense_ids"`
+}
+
+// ReportExpensesRequest represents a request to add expenses to a report
+type ReportExpensesRequest struct {
+	ExpenseIDs []string `json:"expense_ids"`
+}
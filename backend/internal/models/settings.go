@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DefaultUserID identifies requests that do not carry an X-User-ID header
+const DefaultUserID = "default"
+
+// DefaultTimezone is the timezone of users who have not set one
+const DefaultTimezone = "UTC"
+
+// UserSettings represents the per-user preferences
+type UserSettings struct {
+	UserID   string `json:"user_id"`
+	Language string `json:"language"`
+	// Timezone is an IANA name (e.g. America/Lima) used to tell which day an expense falls on
+	Timezone  string    `json:"timezone"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Location returns the user's timezone, or UTC if it is not a valid IANA name
+func (s *UserSettings) Location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// UpdateSettingsRequest represents the body of a settings update. Empty fields keep their current value.
+type UpdateSettingsRequest struct {
+	Language string `json:"language,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
@@ -0,0 +1,62 @@
+package models
+
+// Bank statement formats accepted by POST /import
+const (
+	StatementFormatCSV = "csv"
+	StatementFormatOFX = "ofx"
+)
+
+// CSVColumnMapping tells how to read the transactions of a bank CSV. Columns are
+// header names, matched case-insensitively.
+type CSVColumnMapping struct {
+	// Date is the posting date column (required)
+	Date string `json:"date"`
+	// Description is the transaction description column (required)
+	Description string `json:"description"`
+	// Amount is a signed amount column; either Amount or Debit is required
+	Amount string `json:"amount,omitempty"`
+	// Debit is a column holding only charges, as positive numbers
+	Debit string `json:"debit,omitempty"`
+	// ExternalID is the bank's transaction ID column (optional, derived from the row if missing)
+	ExternalID string `json:"external_id,omitempty"`
+	// Merchant is a column naming the merchant (optional)
+	Merchant string `json:"merchant,omitempty"`
+	// DateFormat uses YYYY, YY, MM, DD, HH, mm and ss, e.g. "DD/MM/YYYY" (default: YYYY-MM-DD)
+	DateFormat string `json:"date_format,omitempty"`
+	// DecimalSeparator is "." (default) or ","
+	DecimalSeparator string `json:"decimal_separator,omitempty"`
+	// Delimiter separates fields: "," (default), ";" or "\t"
+	Delimiter string `json:"delimiter,omitempty"`
+	// ChargesPositive means charges are positive in the Amount column (default: negative)
+	ChargesPositive bool `json:"charges_positive,omitempty"`
+}
+
+// ImportStatementParams represents the parameters for importing a bank statement
+type ImportStatementParams struct {
+	FilePath string
+	Format   string
+	// Mapping is required for CSV statements
+	Mapping *CSVColumnMapping
+	UserID  string
+}
+
+// ImportRowError is a transaction of a statement that could not be imported
+type ImportRowError struct {
+	// Row is the line of a CSV row or the position of an OFX transaction (1-based)
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ImportResult reports what an import did with each transaction of a statement
+type ImportResult struct {
+	Format string `json:"format"`
+	// Imported counts the new expenses, returned in Expenses
+	Imported int `json:"imported"`
+	// Duplicates counts the transactions whose external ID was already imported
+	Duplicates int `json:"duplicates"`
+	// Skipped counts the credits (deposits, refunds), which are not expenses
+	Skipped  int              `json:"skipped"`
+	Expenses []*Expense       `json:"expenses"`
+	Errors   []ImportRowError `json:"errors"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Summary groupings
+const (
+	SummaryGroupByMerchant = "merchant"
+	// SummaryGroupByTag puts an expense in the group of each of its tags, so groups may overlap
+	SummaryGroupByTag = "tag"
+	// SummaryGroupByDay groups by purchase day in the user's timezone
+	SummaryGroupByDay = "day"
+)
+
+// SummaryGroupings lists the supported summary groupings
+var SummaryGroupings = []string{SummaryGroupByMerchant, SummaryGroupByTag, SummaryGroupByDay}
+
+// SummaryParams represents the parameters for summarizing expenses
+type SummaryParams struct {
+	UserID  string
+	GroupBy string // optional, one of SummaryGroupings
+	// DateRange bounds purchased_at (optional)
+	DateRange
+	// MerchantID, Query (a full-text search) and Units filter the expenses (optional)
+	MerchantID string
+	Query      string
+	Units      []string
+	// TagFilter limits the summary to tagged expenses (optional)
+	TagFilter
+	// Timezone is the IANA timezone days are bucketed in, set by the service from the user's settings
+	Timezone string
+}
+
+// SummaryGroup is the total of the expenses sharing a group key
+type SummaryGroup struct {
+	// Key identifies the group (the merchant ID, the tag, or the day as YYYY-MM-DD), empty for expenses without one
+	Key   string  `json:"key"`
+	Label string  `json:"label"`
+	Total float64 `json:"total"`
+	Count int     `json:"count"`
+	// Min and Max are the smallest and largest expense totals
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// ExpenseSummary represents totals of a user's expenses, optionally grouped
+type ExpenseSummary struct {
+	GroupBy  string         `json:"group_by,omitempty"`
+	Timezone string         `json:"timezone,omitempty"`
+	From     *time.Time     `json:"from,omitempty"`
+	To       *time.Time     `json:"to,omitempty"`
+	Total    float64        `json:"total"`
+	Count    int            `json:"count"`
+	Min      float64        `json:"min"`
+	Max      float64        `json:"max"`
+	Groups   []SummaryGroup `json:"groups,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// Tag filter matches
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// TagFilter selects the expenses tagged with any (the default) or all of Tags
+type TagFilter struct {
+	Tags []string
+	// TagMatch is TagMatchAny or TagMatchAll
+	TagMatch string
+}
+
+// TagExpenseRequest represents a request to add tags to an expense
+type TagExpenseRequest struct {
+	Tags []string `json:"tags"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// DefaultUnit is used when no unit is spoken
+const DefaultUnit = "u"
+
+// LanguageUnits lists the units of measurement accepted for each language
+var LanguageUnits = map[string][]string{
+	LanguageSpanish:    {"kg", "g", "litro", "ml", "pasaje", "docena", "u"},
+	LanguageEnglish:    {"kg", "g", "lb", "liter", "ml", "ticket", "dozen", "u"},
+	LanguagePortuguese: {"kg", "g", "litro", "ml", "passagem", "dúzia", "u"},
+}
+
+// IsKnownUnit reports whether unit belongs to the vocabulary of the given language
+func IsKnownUnit(language string, unit string) bool {
+	for _, u := range LanguageUnits[language] {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
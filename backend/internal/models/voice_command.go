@@ -0,0 +1,72 @@
+package models
+
+// Voice command intents
+const (
+	// VoiceIntentAdd records the expenses described in the recording
+	VoiceIntentAdd = "add"
+	// VoiceIntentCorrect changes one of the user's recent expenses ("el pan costó cinco, no cuatro")
+	VoiceIntentCorrect = "correct"
+	// VoiceIntentDelete deletes one of the user's recent expenses ("borra el último gasto")
+	VoiceIntentDelete = "delete"
+	// VoiceIntentQuery is a question about expenses, answered by POST /ask
+	VoiceIntentQuery = "query"
+)
+
+// VoiceIntents lists the intents a recording can be classified as
+var VoiceIntents = []string{VoiceIntentAdd, VoiceIntentCorrect, VoiceIntentDelete, VoiceIntentQuery}
+
+// Expense change actions
+const (
+	ExpenseChangeUpdated = "updated"
+	ExpenseChangeDeleted = "deleted"
+)
+
+// VoiceCommand is the model's interpretation of a transcription
+type VoiceCommand struct {
+	// Intent is one of VoiceIntents
+	Intent string `json:"intent"`
+	// Expenses are the expenses to record, for the add intent
+	Expenses []ExpenseData `json:"expenses,omitempty"`
+	// Target identifies the expense to correct or delete
+	Target *VoiceTarget `json:"target,omitempty"`
+	// Changes are the corrected fields, for the correct intent
+	Changes *VoiceCorrection `json:"changes,omitempty"`
+
+	// PromptVersion identifies the prompt template that produced this command (not part of the model output)
+	PromptVersion string `json:"-"`
+}
+
+// VoiceTarget identifies one of the user's recent expenses
+type VoiceTarget struct {
+	// Description is the product as mentioned ("el pan" gives "pan"); empty for the latest expense
+	Description string `json:"description"`
+}
+
+// VoiceCorrection is a spoken correction of an expense
+type VoiceCorrection struct {
+	ExpenseUpdate
+	// Total is a corrected amount paid, spread over the (corrected) quantity
+	Total *float64 `json:"total,omitempty"`
+}
+
+// ExpenseChange is a change made to an expense by a voice command, with the expense before
+// the change so it can be undone
+type ExpenseChange struct {
+	// Action is updated or deleted
+	Action string   `json:"action"`
+	Before *Expense `json:"before"`
+	// After is the updated expense, omitted for deletions
+	After *Expense `json:"after,omitempty"`
+}
+
+// VoiceCommandResult is the outcome of processing a recording
+type VoiceCommandResult struct {
+	Intent        string `json:"intent"`
+	Transcription string `json:"transcription"`
+	RecordingID   string `json:"recording_id"`
+	// Expenses are the expenses recorded by the add intent
+	Expenses []*Expense `json:"expenses,omitempty"`
+	// Changes are what the correct and delete intents changed
+	Changes       []ExpenseChange `json:"changes,omitempty"`
+	PromptVersion string          `json:"prompt_version,omitempty"`
+}
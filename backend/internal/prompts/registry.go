@@ -0,0 +1,214 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Prompt names
+const (
+	ExtractExpenses = "extract"
+	ExtractReceipt  = "receipt"
+	AskQuestion     = "ask"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Template is a versioned prompt template
+type Template struct {
+	Name    string
+	Version string
+	tmpl    *template.Template
+}
+
+// ID identifies the template and version, e.g. "extract@v1"
+func (t *Template) ID() string {
+	return t.Name + "@" + t.Version
+}
+
+// Execute renders the template with the given data
+func (t *Template) Execute(data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", t.ID(), err)
+	}
+	return buf.String(), nil
+}
+
+type weightedVersion struct {
+	version string
+	weight  int
+}
+
+// Registry holds the prompt templates and the A/B weights used to select a version
+type Registry struct {
+	templates map[string]map[string]*Template
+	weights   map[string][]weightedVersion
+}
+
+// Load reads the templates from dir, or the embedded templates if dir is empty.
+// Templates are laid out as <name>/<version>.tmpl.
+func Load(dir string) (*Registry, error) {
+	if dir == "" {
+		sub, err := fs.Sub(embedded, "templates")
+		if err != nil {
+			return nil, err
+		}
+		return LoadFS(sub)
+	}
+	return LoadFS(os.DirFS(dir))
+}
+
+// LoadFS reads the templates from a file system laid out as <name>/<version>.tmpl
+func LoadFS(fsys fs.FS) (*Registry, error) {
+	r := &Registry{
+		templates: make(map[string]map[string]*Template),
+		weights:   make(map[string][]weightedVersion),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".tmpl" {
+			return nil
+		}
+
+		name := path.Dir(p)
+		version := strings.TrimSuffix(path.Base(p), ".tmpl")
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt %s: %w", p, err)
+		}
+		tmpl, err := template.New(p).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse prompt %s: %w", p, err)
+		}
+
+		if r.templates[name] == nil {
+			r.templates[name] = make(map[string]*Template)
+		}
+		r.templates[name][version] = &Template{Name: name, Version: version, tmpl: tmpl}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetWeights configures the percentage of requests that use each version of a prompt.
+// Weights need not add up to 100; they are relative to their sum.
+func (r *Registry) SetWeights(name string, weights map[string]int) error {
+	versions, ok := r.templates[name]
+	if !ok {
+		return fmt.Errorf("unknown prompt %q", name)
+	}
+
+	var weighted []weightedVersion
+	for version, weight := range weights {
+		if _, ok := versions[version]; !ok {
+			return fmt.Errorf("unknown version %q for prompt %q", version, name)
+		}
+		if weight < 0 {
+			return fmt.Errorf("negative weight for %s@%s", name, version)
+		}
+		if weight > 0 {
+			weighted = append(weighted, weightedVersion{version: version, weight: weight})
+		}
+	}
+	if len(weighted) == 0 {
+		return fmt.Errorf("no positive weights for prompt %q", name)
+	}
+
+	// Sort for deterministic selection given the same random number
+	sort.Slice(weighted, func(i, j int) bool { return versionLess(weighted[i].version, weighted[j].version) })
+	r.weights[name] = weighted
+	return nil
+}
+
+// Select picks a version of the prompt according to the configured weights.
+// Without weights, the latest version is used.
+func (r *Registry) Select(name string) (*Template, error) {
+	versions, ok := r.templates[name]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("unknown prompt %q", name)
+	}
+
+	weighted := r.weights[name]
+	if len(weighted) == 0 {
+		return versions[latestVersion(versions)], nil
+	}
+
+	total := 0
+	for _, w := range weighted {
+		total += w.weight
+	}
+	return versions[pickWeighted(weighted, rand.IntN(total))], nil
+}
+
+// pickWeighted returns the version that n, drawn from [0, sum of weights), falls on
+func pickWeighted(weighted []weightedVersion, n int) string {
+	for _, w := range weighted {
+		if n < w.weight {
+			return w.version
+		}
+		n -= w.weight
+	}
+	return weighted[len(weighted)-1].version
+}
+
+// ParseWeights parses a weight specification like "v1:90,v2:10"
+func ParseWeights(spec string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		version, weightStr, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid prompt weight %q (expected version:percentage)", part)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt weight %q: %w", part, err)
+		}
+		weights[strings.TrimSpace(version)] = weight
+	}
+	return weights, nil
+}
+
+// latestVersion returns the highest version, comparing "v<N>" numerically
+func latestVersion(versions map[string]*Template) string {
+	var latest string
+	for version := range versions {
+		if latest == "" || versionLess(latest, version) {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// versionLess orders versions by number, so "v10" comes after "v9"; names that are not
+// "v<N>" fall back to string order
+func versionLess(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
@@ -0,0 +1,116 @@
+package prompts
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+// testRegistry loads a registry with the given versions of the extract prompt
+func testRegistry(t *testing.T, versions ...string) *Registry {
+	t.Helper()
+	fsys := fstest.MapFS{}
+	for _, version := range versions {
+		fsys["extract/"+version+".tmpl"] = &fstest.MapFile{Data: []byte("prompt " + version + " in {{.Language}}")}
+	}
+	registry, err := LoadFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestLoadAndExecute(t *testing.T) {
+	registry := testRegistry(t, "v1")
+	tmpl, err := registry.Select(ExtractExpenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ID() != "extract@v1" {
+		t.Errorf("ID = %q", tmpl.ID())
+	}
+	prompt, err := tmpl.Execute(map[string]string{"Language": "es"})
+	if err != nil || prompt != "prompt v1 in es" {
+		t.Errorf("prompt = %q, %v", prompt, err)
+	}
+	if _, err := tmpl.Execute(map[string]string{}); err == nil {
+		t.Error("missing template data did not fail")
+	}
+	if _, err := registry.Select("unknown"); err == nil {
+		t.Error("unknown prompt was selected")
+	}
+}
+
+func TestLoadRejectsInvalidTemplates(t *testing.T) {
+	fsys := fstest.MapFS{"extract/v1.tmpl": &fstest.MapFile{Data: []byte("{{.Language")}}
+	if _, err := LoadFS(fsys); err == nil {
+		t.Error("unparseable template was loaded")
+	}
+}
+
+func TestSelectDefaultsToLatestVersion(t *testing.T) {
+	registry := testRegistry(t, "v1", "v2", "v9", "v10")
+	tmpl, err := registry.Select(ExtractExpenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Version != "v10" {
+		t.Errorf("default version = %s, want v10", tmpl.Version)
+	}
+}
+
+func TestSetWeights(t *testing.T) {
+	registry := testRegistry(t, "v1", "v2")
+	for name, weights := range map[string]map[string]int{
+		"negative weight": {"v1": -1, "v2": 10},
+		"all zero":        {"v1": 0, "v2": 0},
+		"no weights":      {},
+		"unknown version": {"v3": 10},
+	} {
+		if err := registry.SetWeights(ExtractExpenses, weights); err == nil {
+			t.Errorf("%s: weights %v were accepted", name, weights)
+		}
+	}
+	if err := registry.SetWeights("unknown", map[string]int{"v1": 1}); err == nil {
+		t.Error("weights of an unknown prompt were accepted")
+	}
+
+	// A zero weight disables a version
+	if err := registry.SetWeights(ExtractExpenses, map[string]int{"v1": 1, "v2": 0}); err != nil {
+		t.Fatal(err)
+	}
+	for range 20 {
+		if tmpl, _ := registry.Select(ExtractExpenses); tmpl.Version != "v1" {
+			t.Fatalf("selected %s with weight 0", tmpl.Version)
+		}
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	registry := testRegistry(t, "v2", "v9", "v10")
+	if err := registry.SetWeights(ExtractExpenses, map[string]int{"v10": 10, "v2": 60, "v9": 30}); err != nil {
+		t.Fatal(err)
+	}
+	weighted := registry.weights[ExtractExpenses]
+
+	// Versions are laid out in version order: v2 gets [0, 60), v9 [60, 90) and v10 [90, 100)
+	for n, want := range map[int]string{0: "v2", 59: "v2", 60: "v9", 89: "v9", 90: "v10", 99: "v10"} {
+		if got := pickWeighted(weighted, n); got != want {
+			t.Errorf("pick(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights(" v1:90, v2:10 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weights) != 2 || weights["v1"] != 90 || weights["v2"] != 10 {
+		t.Errorf("weights = %v", weights)
+	}
+	for _, spec := range []string{"v1", "v1:ninety"} {
+		if _, err := ParseWeights(spec); err == nil {
+			t.Errorf("%q was parsed", spec)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"strings"
+	"upload-lambda/internal/models"
+)
+
+// askPromptData is the data passed to the ask prompt template
+type askPromptData struct {
+	LanguageName string
+	Now          string
+	Aggregations string
+	Units        string
+	Merchants    string
+}
+
+// askTemplateData builds the template data of the ask prompt
+func askTemplateData(askContext models.AskContext) askPromptData {
+	merchants := "(the user has no merchants)"
+	if len(askContext.Merchants) > 0 {
+		merchants = quoteAll(askContext.Merchants)
+	}
+	p := promptForLanguage(askContext.Language)
+	return askPromptData{
+		LanguageName: p.LanguageName,
+		Now:          formatReferenceTime(askContext.Now),
+		Aggregations: quoteAll(models.AskAggregations),
+		Units:        p.quotedUnits(),
+		Merchants:    merchants,
+	}
+}
+
+// quoteAll formats values as a comma-separated list of quoted strings
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = `"` + strings.ReplaceAll(value, `"`, `'`) + `"`
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Delimiters around the question in the user message
+const (
+	questionOpenTag  = "<question>"
+	questionCloseTag = "</question>"
+)
+
+// delimitQuestion wraps a question in tags, removing any tags typed (or spoken) inside it
+func delimitQuestion(question string) string {
+	cleaned := strings.NewReplacer(questionOpenTag, "", questionCloseTag, "").Replace(question)
+	return questionOpenTag + "\n" + strings.TrimSpace(cleaned) + "\n" + questionCloseTag
+}
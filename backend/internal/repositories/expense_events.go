@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"upload-lambda/internal/models"
+)
+
+// change runs apply in a transaction over the row of an expense and records the expense
+// before and after it as an event of the given action. Deleted expenses are only changed
+// by restore, which requires them to be deleted.
+func (r *postgresRepo) change(ctx context.Context, id string, action string, actor string, apply func(tx *sql.Tx) error) (*models.Expense, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	after, err := changeInTx(ctx, tx, id, action, actor, apply)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit expense change: %w", err)
+	}
+
+	return after, nil
+}
+
+// changeInTx is change within an open transaction
+func changeInTx(ctx context.Context, tx *sql.Tx, id string, action string, actor string, apply func(tx *sql.Tx) error) (*models.Expense, error) {
+	before, err := lockExpense(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action == models.ExpenseEventRestore && before.DeletedAt == nil {
+		return nil, ErrExpenseNotDeleted
+	}
+	if action != models.ExpenseEventRestore && before.DeletedAt != nil {
+		return nil, ErrExpenseNotFound
+	}
+
+	if err := apply(tx); err != nil {
+		return nil, err
+	}
+
+	after, err := lockExpense(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordEvent(ctx, tx, id, action, actor, before, after); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// lockExpense reads an expense, deleted or not, locking its row until the transaction ends
+func lockExpense(ctx context.Context, tx *sql.Tx, id string) (*models.Expense, error) {
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE id = $1
+		FOR UPDATE OF expenses
+	`
+
+	expense, err := scanExpense(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrExpenseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense: %w", err)
+	}
+	return expense, nil
+}
+
+// recordCreation records the creation of an expense inserted in the transaction, with the
+// expense's user as actor
+func recordCreation(ctx context.Context, tx *sql.Tx, id string) error {
+	created, err := lockExpense(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	return recordEvent(ctx, tx, id, models.ExpenseEventCreate, created.UserID, nil, created)
+}
+
+// recordEvent appends an event to the audit history of an expense
+func recordEvent(ctx context.Context, tx *sql.Tx, id string, action string, actor string, before *models.Expense, after *models.Expense) error {
+	snapshots := make([]any, 2)
+	for i, expense := range []*models.Expense{before, after} {
+		if expense == nil {
+			continue
+		}
+		snapshot, err := json.Marshal(expense)
+		if err != nil {
+			return fmt.Errorf("failed to encode expense event: %w", err)
+		}
+		snapshots[i] = string(snapshot)
+	}
+
+	query := `
+		INSERT INTO expense_events (expense_id, action, actor, before, after)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, id, action, actor, snapshots[0], snapshots[1]); err != nil {
+		return fmt.Errorf("failed to insert expense event: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepo) History(ctx context.Context, id string) ([]*models.ExpenseEvent, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM expenses WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to query expense: %w", err)
+	}
+	if !exists {
+		return nil, ErrExpenseNotFound
+	}
+
+	query := `
+		SELECT id, expense_id, action, actor, before, after, created_at
+		FROM expense_events
+		WHERE expense_id = $1
+		ORDER BY id
+	`
+
+	rows, err := db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.ExpenseEvent{}
+	for rows.Next() {
+		var event models.ExpenseEvent
+		var before, after []byte
+		if err := rows.Scan(&event.ID, &event.ExpenseID, &event.Action, &event.Actor, &before, &after, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan expense event: %w", err)
+		}
+		event.Before, event.After = before, after
+		event.CreatedAt = event.CreatedAt.UTC()
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expense events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"upload-lambda/internal/models"
+
+	"github.com/lib/pq"
+)
+
+func (r *postgresRepo) AddTags(ctx context.Context, id string, tags []string, actor string) (*models.Expense, error) {
+	return r.change(ctx, id, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+		return insertTags(ctx, tx, id, tags)
+	})
+}
+
+func (r *postgresRepo) RemoveTag(ctx context.Context, id string, tag string, actor string) (*models.Expense, error) {
+	query := `
+		DELETE FROM expense_tags et
+		USING tags t
+		WHERE et.tag_id = t.id AND et.expense_id = $1 AND t.name = $2
+	`
+
+	return r.change(ctx, id, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, id, tag); err != nil {
+			return fmt.Errorf("failed to remove tag: %w", err)
+		}
+		return nil
+	})
+}
+
+// insertTags tags an expense, creating the tags its user does not have yet. Tags the expense
+// already has are skipped.
+func insertTags(ctx context.Context, tx *sql.Tx, expenseID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO tags (user_id, name)
+		SELECT e.user_id, name FROM expenses e, unnest($2::text[]) AS name
+		WHERE e.id = $1
+		ON CONFLICT (user_id, name) DO NOTHING
+	`, expenseID, pq.Array(tags))
+	if err != nil {
+		return fmt.Errorf("failed to insert tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO expense_tags (expense_id, tag_id)
+		SELECT e.id, t.id FROM expenses e JOIN tags t ON t.user_id = e.user_id
+		WHERE e.id = $1 AND t.name = ANY($2)
+		ON CONFLICT (expense_id, tag_id) DO NOTHING
+	`, expenseID, pq.Array(tags))
+	if err != nil {
+		return fmt.Errorf("failed to tag expense: %w", err)
+	}
+
+	return nil
+}
+
+// tagCondition returns the condition selecting the expenses, identified by idColumn, tagged
+// with any or all of the filter's tags, and the arguments with its own appended
+func tagCondition(idColumn string, filter models.TagFilter, args []any) (string, []any) {
+	args = append(args, pq.Array(filter.Tags))
+	tagged := fmt.Sprintf(`FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+		WHERE et.expense_id = %s AND t.name = ANY($%d)`, idColumn, len(args))
+
+	if filter.TagMatch == models.TagMatchAll {
+		// The tags are distinct, so the expense has them all when it has as many of them
+		args = append(args, len(filter.Tags))
+		return fmt.Sprintf("(SELECT COUNT(*) %s) = $%d", tagged, len(args)), args
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 %s)", tagged), args
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+// extractionPrompt holds the language-specific data of the extraction prompt template
+type extractionPrompt struct {
+	Language     string
+	LanguageName string
+	Hints        string
+	// TagWords are the spoken words that introduce a tag, and TagExample shows one
+	TagWords   string
+	TagExample string
+}
+
+// extractionPrompts maps ISO 639-1 codes to their extraction prompt
+var extractionPrompts = map[string]extractionPrompt{
+	models.LanguageSpanish: {
+		Language:     models.LanguageSpanish,
+		LanguageName: "Spanish",
+		Hints:        `"medio kilo" means quantity 0.5 with unit "kg"; "un cuarto" means 0.25; prices like "tres cincuenta" mean 3.50`,
+		TagWords:     `"etiqueta", "hashtag" or "numeral"`,
+		TagExample:   `"etiqueta viaje" gives "viaje", "hashtag trabajo cusco" gives "trabajo cusco"`,
+	},
+	models.LanguageEnglish: {
+		Language:     models.LanguageEnglish,
+		LanguageName: "English",
+		Hints:        `"half a pound" means quantity 0.5 with unit "lb"; "a couple" means 2; prices like "three fifty" mean 3.50`,
+		TagWords:     `"tag", "label" or "hashtag"`,
+		TagExample:   `"tag travel" gives "travel", "hashtag work trip" gives "work trip"`,
+	},
+	models.LanguagePortuguese: {
+		Language:     models.LanguagePortuguese,
+		LanguageName: "Portuguese",
+		Hints:        `"meio quilo" means quantity 0.5 with unit "kg"; "um par" means 2; prices like "três e cinquenta" mean 3.50`,
+		TagWords:     `"etiqueta", "marcador" or "hashtag"`,
+		TagExample:   `"etiqueta viagem" gives "viagem"`,
+	},
+}
+
+// promptForLanguage returns the extraction prompt for a language, falling back to the default language
+func promptForLanguage(language string) extractionPrompt {
+	if p, ok := extractionPrompts[language]; ok {
+		return p
+	}
+	return extractionPrompts[models.DefaultLanguage]
+}
+
+// quotedUnits formats the unit vocabulary for the prompt
+func (p extractionPrompt) quotedUnits() string {
+	return quoteAll(models.LanguageUnits[p.Language])
+}
+
+// extractionPromptData is the data passed to the extraction prompt template. The
+// transcription is not part of it: it is only sent as a separate, delimited user message.
+type extractionPromptData struct {
+	LanguageName string
+	Units        string
+	DefaultUnit  string
+	Hints        string
+	// ReferenceTime is when the voice note was recorded, used since extract@v5
+	ReferenceTime string
+	// TagWords and TagExample describe spoken tags, used since extract@v7
+	TagWords   string
+	TagExample string
+}
+
+// templateData builds the template data for the language
+func (p extractionPrompt) templateData() extractionPromptData {
+	return extractionPromptData{
+		LanguageName: p.LanguageName,
+		Units:        p.quotedUnits(),
+		DefaultUnit:  models.DefaultUnit,
+		Hints:        p.Hints,
+		TagWords:     p.TagWords,
+		TagExample:   p.TagExample,
+	}
+}
+
+// formatReferenceTime describes a recording time for the prompt, with the weekday so the
+// model can count back to named days, e.g. "Saturday 2026-02-21 08:15 (America/Lima)"
+func formatReferenceTime(reference time.Time) string {
+	zone := reference.Location().String()
+	if zone == "" || zone == "Local" {
+		zone, _ = reference.Zone()
+	}
+	return reference.Format("Monday 2006-01-02 15:04") + " (" + zone + ")"
+}
+
+// Delimiters around the transcription in the user message
+const (
+	transcriptionOpenTag  = "<transcription>"
+	transcriptionCloseTag = "</transcription>"
+)
+
+// delimitTranscription wraps the transcription in tags, removing any tags spoken
+// (or injected) inside it so the text cannot close the delimiter early
+func delimitTranscription(transcription string) string {
+	cleaned := strings.NewReplacer(transcriptionOpenTag, "", transcriptionCloseTag, "").Replace(transcription)
+	return transcriptionOpenTag + "\n" + strings.TrimSpace(cleaned) + "\n" + transcriptionCloseTag
+}
+
+// Delimiters around the receipt text in the user message
+const (
+	receiptOpenTag  = "<receipt>"
+	receiptCloseTag = "</receipt>"
+)
+
+// delimitReceipt wraps OCR text in tags, removing any tags printed (or injected) inside it
+func delimitReceipt(text string) string {
+	cleaned := strings.NewReplacer(receiptOpenTag, "", receiptCloseTag, "").Replace(text)
+	return receiptOpenTag + "\n" + strings.TrimSpace(cleaned) + "\n" + receiptCloseTag
+}
+
+// whisperLanguages maps the language names returned by Whisper's verbose_json to ISO 639-1 codes
+var whisperLanguages = map[string]string{
+	"spanish":    models.LanguageSpanish,
+	"english":    models.LanguageEnglish,
+	"portuguese": models.LanguagePortuguese,
+}
+
+// normalizeWhisperLanguage converts a Whisper language name (or code) to a supported ISO 639-1 code.
+// Returns an empty string for unsupported languages.
+func normalizeWhisperLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := whisperLanguages[language]; ok {
+		return code
+	}
+	if models.IsSupportedLanguage(language) {
+		return language
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+// IdempotencyRepository defines the interface for idempotency key data operations
+type IdempotencyRepository interface {
+	// Claim stores a pending record for the key. If the key is already taken it returns
+	// the existing record and false, unless that record was created before reclaimBefore
+	// (or is still pending and was created before abandonedBefore), in which case it is replaced.
+	Claim(ctx context.Context, record *models.IdempotencyRecord, reclaimBefore time.Time, abandonedBefore time.Time) (*models.IdempotencyRecord, bool, error)
+	// Complete stores the response of a claimed key
+	Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, body []byte) error
+	// Delete releases a key so the request can be retried
+	Delete(ctx context.Context, userID string, key string) error
+}
+
+type postgresIdempotencyRepo struct {
+	dbURL string
+}
+
+// NewPostgresIdempotencyRepository creates a new PostgreSQL idempotency key repository
+func NewPostgresIdempotencyRepository(dbURL string) IdempotencyRepository {
+	return &postgresIdempotencyRepo{
+		dbURL: dbURL,
+	}
+}
+
+func (r *postgresIdempotencyRepo) Claim(ctx context.Context, record *models.IdempotencyRecord, reclaimBefore time.Time, abandonedBefore time.Time) (*models.IdempotencyRecord, bool, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Insert, or take over an expired or abandoned key, atomically
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, created_at = EXCLUDED.created_at,
+			status_code = NULL, content_type = NULL, response_body = NULL, completed_at = NULL
+		WHERE idempotency_keys.created_at < $5
+			OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < $6)
+		RETURNING user_id
+	`
+
+	var claimedUserID string
+	err = db.QueryRowContext(ctx, query,
+		record.UserID,
+		record.Key,
+		record.Fingerprint,
+		record.CreatedAt,
+		reclaimBefore,
+		abandonedBefore,
+	).Scan(&claimedUserID)
+	if err == nil {
+		return nil, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	query = `
+		SELECT user_id, key, fingerprint, status_code, content_type, response_body, created_at, completed_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	var existing models.IdempotencyRecord
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	var completedAt sql.NullTime
+	err = db.QueryRowContext(ctx, query, record.UserID, record.Key).Scan(
+		&existing.UserID,
+		&existing.Key,
+		&existing.Fingerprint,
+		&statusCode,
+		&contentType,
+		&existing.Body,
+		&existing.CreatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query idempotency key: %w", err)
+	}
+
+	existing.StatusCode = int(statusCode.Int64)
+	existing.ContentType = contentType.String
+	if completedAt.Valid {
+		existing.CompletedAt = &completedAt.Time
+	}
+
+	return &existing, false, nil
+}
+
+func (r *postgresIdempotencyRepo) Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, body []byte) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5, completed_at = $6
+		WHERE user_id = $1 AND key = $2
+	`
+
+	_, err = db.ExecContext(ctx, query, userID, key, statusCode, contentType, body, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresIdempotencyRepo) Delete(ctx context.Context, userID string, key string) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+// ErrInvalidCursor is returned when a list cursor is malformed or was issued for another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderByRank is the list order of search results, best match first
+const OrderByRank = "rank"
+
+// ListPosition is the sort key of the last expense of a page: Value for date orders, Rank
+// for search results, and the ID breaking ties
+type ListPosition struct {
+	Value time.Time
+	Rank  float64
+	ID    string
+}
+
+// listCursor is a ListPosition plus the order it was issued for. It travels base64-encoded
+// so clients treat it as opaque.
+type listCursor struct {
+	OrderBy  string    `json:"b"`
+	OrderDir string    `json:"d"`
+	Value    time.Time `json:"v"`
+	Rank     float64   `json:"r,omitempty"`
+	ID       string    `json:"i"`
+}
+
+// EncodeListCursor returns the cursor that continues a list after expense
+func EncodeListCursor(expense *models.Expense, orderBy string, orderDir string) string {
+	cursor := listCursor{OrderBy: orderBy, OrderDir: orderDir, ID: expense.ID}
+	switch orderBy {
+	case "purchased_at":
+		cursor.Value = expense.PurchasedAt.UTC()
+	case OrderByRank:
+		if expense.Match != nil {
+			cursor.Rank = expense.Match.Rank
+		}
+	default:
+		cursor.Value = expense.CreatedAt.UTC()
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeListCursor returns the position after which a list continues, checking the cursor
+// was issued for the given order
+func DecodeListCursor(cursor string, orderBy string, orderDir string) (*ListPosition, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var decoded listCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if decoded.OrderBy != orderBy || decoded.OrderDir != orderDir {
+		return nil, fmt.Errorf("%w: issued for order %s %s, not %s %s",
+			ErrInvalidCursor, decoded.OrderBy, decoded.OrderDir, orderBy, orderDir)
+	}
+	if orderBy != OrderByRank && decoded.Value.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &ListPosition{Value: decoded.Value, Rank: decoded.Rank, ID: decoded.ID}, nil
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"upload-lambda/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// MerchantRepository defines the interface for merchant data operations
+type MerchantRepository interface {
+	// FindOrCreate returns the user's merchant whose normalized name or alias matches
+	// merchant.NormalizedName, or creates merchant (aliased by its normalized name) if none does
+	FindOrCreate(ctx context.Context, merchant *models.Merchant) (*models.Merchant, error)
+	FindByID(ctx context.Context, id string) (*models.Merchant, error)
+	// List returns a user's merchants with their aliases, ordered by name
+	List(ctx context.Context, userID string) ([]*models.Merchant, error)
+	// AddAlias makes a normalized alias resolve to a merchant. Adding an alias the merchant
+	// already has is a no-op; an alias of another merchant returns ErrMerchantAliasTaken.
+	AddAlias(ctx context.Context, merchantID string, userID string, alias string) error
+}
+
+// Merchant errors
+var (
+	ErrMerchantNotFound   = errors.New("merchant not found")
+	ErrMerchantAliasTaken = errors.New("alias already belongs to another merchant")
+)
+
+// merchantColumns lists the columns read by scanMerchant, in order
+const merchantColumns = `m.id, m.user_id, m.name, m.normalized_name, m.created_at,
+	ARRAY(SELECT a.alias FROM merchant_aliases a WHERE a.merchant_id = m.id ORDER BY a.alias)`
+
+// scanMerchant scans a row selected with merchantColumns
+func scanMerchant(row rowScanner) (*models.Merchant, error) {
+	var merchant models.Merchant
+	var aliases pq.StringArray
+	if err := row.Scan(
+		&merchant.ID,
+		&merchant.UserID,
+		&merchant.Name,
+		&merchant.NormalizedName,
+		&merchant.CreatedAt,
+		&aliases,
+	); err != nil {
+		return nil, err
+	}
+	merchant.Aliases = []string(aliases)
+	return &merchant, nil
+}
+
+type postgresMerchantRepo struct {
+	dbURL string
+}
+
+// NewPostgresMerchantRepository creates a new PostgreSQL merchant repository
+func NewPostgresMerchantRepository(dbURL string) MerchantRepository {
+	return &postgresMerchantRepo{
+		dbURL: dbURL,
+	}
+}
+
+func (r *postgresMerchantRepo) FindOrCreate(ctx context.Context, merchant *models.Merchant) (*models.Merchant, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Concurrent uploads may create the same merchant: the unique constraints make the
+	// loser's inserts no-ops and the final lookup returns the winner's row
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO merchants (id, user_id, name, normalized_name, created_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM merchant_aliases WHERE user_id = $2 AND alias = $4)
+		ON CONFLICT (user_id, normalized_name) DO NOTHING
+	`, merchant.ID, merchant.UserID, merchant.Name, merchant.NormalizedName, merchant.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert merchant: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO merchant_aliases (user_id, alias, merchant_id, created_at)
+		SELECT user_id, normalized_name, id, created_at FROM merchants WHERE user_id = $1 AND normalized_name = $2
+		ON CONFLICT (user_id, alias) DO NOTHING
+	`, merchant.UserID, merchant.NormalizedName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert merchant alias: %w", err)
+	}
+
+	query := `
+		SELECT ` + merchantColumns + `
+		FROM merchants m
+		JOIN merchant_aliases a ON a.merchant_id = m.id
+		WHERE a.user_id = $1 AND a.alias = $2
+	`
+	found, err := scanMerchant(tx.QueryRowContext(ctx, query, merchant.UserID, merchant.NormalizedName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchant: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merchant: %w", err)
+	}
+
+	return found, nil
+}
+
+func (r *postgresMerchantRepo) FindByID(ctx context.Context, id string) (*models.Merchant, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT ` + merchantColumns + `
+		FROM merchants m
+		WHERE m.id = $1
+	`
+
+	merchant, err := scanMerchant(db.QueryRowContext(ctx, query, id))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrMerchantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchant: %w", err)
+	}
+
+	return merchant, nil
+}
+
+func (r *postgresMerchantRepo) List(ctx context.Context, userID string) ([]*models.Merchant, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT ` + merchantColumns + `
+		FROM merchants m
+		WHERE m.user_id = $1
+		ORDER BY m.name
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchants: %w", err)
+	}
+	defer rows.Close()
+
+	var merchants []*models.Merchant
+	for rows.Next() {
+		merchant, err := scanMerchant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merchant: %w", err)
+		}
+		merchants = append(merchants, merchant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating merchants: %w", err)
+	}
+
+	return merchants, nil
+}
+
+func (r *postgresMerchantRepo) AddAlias(ctx context.Context, merchantID string, userID string, alias string) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO merchant_aliases (user_id, alias, merchant_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, alias) DO NOTHING
+	`, userID, alias, merchantID)
+	if err != nil {
+		return fmt.Errorf("failed to insert merchant alias: %w", err)
+	}
+
+	var owner string
+	err = db.QueryRowContext(ctx, `SELECT merchant_id FROM merchant_aliases WHERE user_id = $1 AND alias = $2`, userID, alias).Scan(&owner)
+	if err != nil {
+		return fmt.Errorf("failed to query merchant alias: %w", err)
+	}
+	if owner != merchantID {
+		return ErrMerchantAliasTaken
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseTesseractTSV(t *testing.T) {
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t600\t800\t-1\t\n" +
+		"4\t1\t1\t1\t1\t0\t10\t10\t300\t20\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t10\t10\t80\t20\t96.5\tARROZ\n" +
+		"5\t1\t1\t1\t1\t2\t100\t10\t60\t20\t90.5\t4.50\n" +
+		"5\t1\t1\t1\t2\t1\t10\t40\t80\t20\t83\tLECHE\n" +
+		"5\t1\t1\t1\t2\t2\t100\t40\t60\t20\t-1\t \n"
+
+	text, confidence := parseTesseractTSV(tsv)
+	if text != "ARROZ 4.50\nLECHE" {
+		t.Errorf("text = %q", text)
+	}
+	if confidence == nil || math.Abs(*confidence-0.9) > 1e-9 {
+		t.Errorf("confidence = %v, want 0.9", confidence)
+	}
+}
+
+func TestParseTesseractTSVWithoutWords(t *testing.T) {
+	text, confidence := parseTesseractTSV("level\tpage_num\n1\t1\n")
+	if text != "" || confidence != nil {
+		t.Errorf("text = %q, confidence = %v; want nothing", text, confidence)
+	}
+}
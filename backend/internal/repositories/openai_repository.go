@@ -4,100 +4,230 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 	"upload-lambda/internal/models"
+	"upload-lambda/internal/prompts"
 
 	openai "github.com/sashabaranov/go-openai"
 )
 
 // OpenAIRepository defines the interface for OpenAI operations
 type OpenAIRepository interface {
-	TranscribeAudio(ctx context.Context, audioPath string) (string, error)
-	ExtractExpenseData(ctx context.Context, transcription string) ([]models.ExpenseData, error)
+	TranscribeAudio(ctx context.Context, audioPath string) (*models.Transcription, error)
+	// InterpretTranscription classifies a transcription as adding, correcting or deleting expenses
+	// or asking about them, and extracts the expenses or the change. reference is when the voice
+	// note was recorded, in the user's timezone, so the model can resolve spoken dates like "ayer".
+	// The command is not validated.
+	InterpretTranscription(ctx context.Context, transcription string, language string, reference time.Time) (*models.VoiceCommand, error)
+	// ExtractReceiptData extracts the expenses of a receipt from its OCR text
+	ExtractReceiptData(ctx context.Context, receiptText string, language string) ([]models.ExpenseData, error)
+	// TranslateQuestion translates a question about the user's expenses into a query and a reply
+	// template. The query is not validated.
+	TranslateQuestion(ctx context.Context, question string, askContext models.AskContext) (*models.AskTranslation, error)
 }
 
 type openAIRepo struct {
-	client *openai.Client
+	client  *openai.Client
+	prompts *prompts.Registry
+}
+
+// OpenAIConfig configures the OpenAI client
+type OpenAIConfig struct {
+	APIKey     string
+	BaseURL    string // optional, defaults to the OpenAI API
+	Resilience ResilienceConfig
 }
 
 // NewOpenAIRepository creates a new OpenAI repository
-func NewOpenAIRepository(apiKey string) OpenAIRepository {
+func NewOpenAIRepository(config OpenAIConfig, promptRegistry *prompts.Registry) OpenAIRepository {
 	return &openAIRepo{
-		client: openai.NewClient(apiKey),
+		client:  newOpenAIClient(config),
+		prompts: promptRegistry,
+	}
+}
+
+// newOpenAIClient creates a go-openai client that sends requests through the resilience layer
+func newOpenAIClient(config OpenAIConfig) *openai.Client {
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	if config.BaseURL != "" {
+		clientConfig.BaseURL = config.BaseURL
 	}
+	clientConfig.HTTPClient = newResilientDoer(&http.Client{}, config.Resilience)
+	return openai.NewClientWithConfig(clientConfig)
 }
 
-func (r *openAIRepo) TranscribeAudio(ctx context.Context, audioPath string) (string, error) {
+func (r *openAIRepo) TranscribeAudio(ctx context.Context, audioPath string) (*models.Transcription, error) {
 	file, err := os.Open(audioPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %w", err)
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
 	defer file.Close()
 
+	// verbose_json includes the detected language
 	req := openai.AudioRequest{
 		Model:    openai.Whisper1,
 		FilePath: audioPath,
 		Reader:   file,
+		Format:   openai.AudioResponseFormatVerboseJSON,
 	}
 
 	resp, err := r.client.CreateTranscription(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("OpenAI transcription error: %w", err)
+		return nil, fmt.Errorf("OpenAI transcription error: %w", err)
 	}
 
-	return resp.Text, nil
+	return &models.Transcription{
+		Text:       resp.Text,
+		Language:   normalizeWhisperLanguage(resp.Language),
+		Confidence: transcriptionConfidence(resp),
+	}, nil
 }
 
-func (r *openAIRepo) ExtractExpenseData(ctx context.Context, transcription string) ([]models.ExpenseData, error) {
-	prompt := fmt.Sprintf(`You are an expense parser. Extract ALL expenses from the Spanish text. There may be one or multiple expenses.
+// transcriptionConfidence averages exp(avg_logprob) over the segments, weighted by duration
+func transcriptionConfidence(resp openai.AudioResponse) *float64 {
+	var weighted, total float64
+	for _, segment := range resp.Segments {
+		duration := segment.End - segment.Start
+		if duration <= 0 {
+			continue
+		}
+		weighted += math.Exp(segment.AvgLogprob) * duration
+		total += duration
+	}
+	if total == 0 {
+		return nil
+	}
+	confidence := weighted / total
+	return &confidence
+}
 
-For EACH expense, extract:
-- unit_price: the price per unit (decimal number)
-- quantity: the quantity purchased (decimal number, use 1.0 if not specified)
-- unit: the unit of measurement (string: "kg", "litro", "pasaje", "u" for generic units). Default to "u" if not specified
-- description: short product description (string)
+func (r *openAIRepo) InterpretTranscription(ctx context.Context, transcription string, language string, reference time.Time) (*models.VoiceCommand, error) {
+	data := promptForLanguage(language).templateData()
+	data.ReferenceTime = formatReferenceTime(reference)
 
-Text: "%s"
+	tmpl, err := r.prompts.Select(prompts.ExtractExpenses)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Execute(data)
+	if err != nil {
+		return nil, err
+	}
 
-Respond ONLY with a valid JSON array of expenses in this exact format:
-[
-  {"unit_price": 0.0, "quantity": 0.0, "unit": "u", "description": ""},
-  {"unit_price": 0.0, "quantity": 0.0, "unit": "kg", "description": ""}
-]
+	content, err := r.complete(ctx, prompt, delimitTranscription(transcription))
+	if err != nil {
+		return nil, err
+	}
 
-If there's only one expense, still return an array with one element.
-Return json only with json quotes`, transcription)
+	// Up to extract@v5 the answer is the array of expenses to add
+	var command models.VoiceCommand
+	if strings.HasPrefix(strings.TrimSpace(content), "[") {
+		command.Intent = models.VoiceIntentAdd
+		err = json.Unmarshal([]byte(content), &command.Expenses)
+	} else {
+		err = json.Unmarshal([]byte(content), &command)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GPT response: %w, response: %s", err, content)
+	}
 
+	if command.Intent == models.VoiceIntentAdd && len(command.Expenses) == 0 {
+		return nil, fmt.Errorf("no expenses found in transcription")
+	}
+
+	command.PromptVersion = tmpl.ID()
+	for i := range command.Expenses {
+		command.Expenses[i].PromptVersion = tmpl.ID()
+	}
+
+	return &command, nil
+}
+
+func (r *openAIRepo) ExtractReceiptData(ctx context.Context, receiptText string, language string) ([]models.ExpenseData, error) {
+	tmpl, err := r.prompts.Select(prompts.ExtractReceipt)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Execute(promptForLanguage(language).templateData())
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := r.complete(ctx, prompt, delimitReceipt(receiptText))
+	if err != nil {
+		return nil, err
+	}
+
+	var expensesData []models.ExpenseData
+	if err := json.Unmarshal([]byte(content), &expensesData); err != nil {
+		return nil, fmt.Errorf("failed to parse GPT response: %w, response: %s", err, content)
+	}
+
+	if len(expensesData) == 0 {
+		return nil, fmt.Errorf("no expenses found in receipt")
+	}
+
+	for i := range expensesData {
+		expensesData[i].PromptVersion = tmpl.ID()
+	}
+
+	return expensesData, nil
+}
+
+func (r *openAIRepo) TranslateQuestion(ctx context.Context, question string, askContext models.AskContext) (*models.AskTranslation, error) {
+	tmpl, err := r.prompts.Select(prompts.AskQuestion)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Execute(askTemplateData(askContext))
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := r.complete(ctx, prompt, delimitQuestion(question))
+	if err != nil {
+		return nil, err
+	}
+
+	var translation models.AskTranslation
+	if err := json.Unmarshal([]byte(content), &translation); err != nil {
+		return nil, fmt.Errorf("failed to parse GPT response: %w, response: %s", err, content)
+	}
+	translation.PromptVersion = tmpl.ID()
+	return &translation, nil
+}
+
+// complete sends a system prompt and delimited user content to the chat model and returns
+// its answer. Instructions go in the system message; transcriptions, receipt text and
+// questions are only ever sent as delimited user content so they cannot override them.
+func (r *openAIRepo) complete(ctx context.Context, prompt string, delimited string) (string, error) {
 	req := openai.ChatCompletionRequest{
 		Model: openai.GPT4,
 		Messages: []openai.ChatCompletionMessage{
 			{
-				Role:    openai.ChatMessageRoleUser,
+				Role:    openai.ChatMessageRoleSystem,
 				Content: prompt,
 			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: delimited,
+			},
 		},
 		Temperature: 0.1,
 	}
 
 	resp, err := r.client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI completion error: %w", err)
+		return "", fmt.Errorf("OpenAI completion error: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from GPT")
-	}
-
-	content := resp.Choices[0].Message.Content
-
-	var expensesData []models.ExpenseData
-	if err := json.Unmarshal([]byte(content), &expensesData); err != nil {
-		return nil, fmt.Errorf("failed to parse GPT response: %w, response: %s", err, content)
+		return "", fmt.Errorf("no response from GPT")
 	}
 
-	if len(expensesData) == 0 {
-		return nil, fmt.Errorf("no expenses found in transcription")
-	}
-
-	return expensesData, nil
+	return resp.Choices[0].Message.Content, nil
 }
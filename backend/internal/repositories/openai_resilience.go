@@ -0,0 +1,278 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrOpenAIUnavailable is returned when OpenAI keeps failing with retryable errors
+var ErrOpenAIUnavailable = errors.New("OpenAI unavailable")
+
+// ErrCircuitOpen is returned without calling OpenAI while the circuit breaker is open
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrOpenAIUnavailable)
+
+// ResilienceConfig configures retries, timeouts and circuit breaking for OpenAI calls
+type ResilienceConfig struct {
+	MaxRetries       int           // retries after the first attempt
+	BaseDelay        time.Duration // first backoff delay, doubled on each retry
+	MaxDelay         time.Duration // cap for backoff and Retry-After delays
+	AttemptTimeout   time.Duration // per-attempt timeout, shortened to the request deadline
+	BreakerThreshold int           // consecutive failed calls that open the circuit
+	BreakerCooldown  time.Duration // time the circuit stays open before a trial call
+}
+
+// DefaultResilienceConfig returns the settings used in production
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         10 * time.Second,
+		AttemptTimeout:   45 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// resilientDoer is an HTTP client for go-openai that retries retryable failures
+// with exponential backoff, honors Retry-After and fails fast while the circuit is open
+type resilientDoer struct {
+	client  *http.Client
+	config  ResilienceConfig
+	breaker *circuitBreaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func newResilientDoer(client *http.Client, config ResilienceConfig) *resilientDoer {
+	return &resilientDoer{
+		client:  client,
+		config:  config,
+		breaker: newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown, time.Now),
+		sleep:   sleepContext,
+	}
+}
+
+func (d *resilientDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if !d.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		resp, err := d.attempt(req, attempt)
+
+		// The caller gave up: not an upstream failure
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			d.breaker.release()
+			return nil, ctx.Err()
+		}
+
+		if !isRetryable(resp, err) {
+			d.breaker.success()
+			return resp, err
+		}
+
+		var wait time.Duration
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("status %d: %s", resp.StatusCode, readSnippet(resp.Body))
+			wait = retryAfter(resp.Header, time.Now())
+			resp.Body.Close()
+		}
+
+		if attempt >= d.config.MaxRetries || (attempt > 0 && req.GetBody == nil) {
+			break
+		}
+
+		if wait == 0 {
+			wait = d.backoff(attempt)
+		}
+		if wait > d.config.MaxDelay {
+			wait = d.config.MaxDelay
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			lastErr = fmt.Errorf("%v (no time left to retry before deadline)", lastErr)
+			break
+		}
+
+		if err := d.sleep(ctx, wait); err != nil {
+			d.breaker.release()
+			return nil, err
+		}
+	}
+
+	d.breaker.failure()
+	return nil, fmt.Errorf("%w: %v", ErrOpenAIUnavailable, lastErr)
+}
+
+// attempt sends one copy of the request with a per-attempt timeout
+func (d *resilientDoer) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	timeout := d.config.AttemptTimeout
+	if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attemptReq.Body = body
+	} else if attempt > 0 {
+		cancel()
+		return nil, fmt.Errorf("request body cannot be replayed")
+	}
+
+	resp, err := d.client.Do(attemptReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Keep the attempt context alive until go-openai has read the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the exponential backoff for a retry with jitter in [delay/2, delay]
+func (d *resilientDoer) backoff(attempt int) time.Duration {
+	delay := d.config.BaseDelay << attempt
+	if delay <= 0 || delay > d.config.MaxDelay {
+		delay = d.config.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
+
+// isRetryable reports whether a response or transport error is worth retrying
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header (seconds or HTTP date), returning 0 if absent
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// readSnippet reads the start of an error body for diagnostics
+func readSnippet(body io.Reader) string {
+	snippet, _ := io.ReadAll(io.LimitReader(body, 512))
+	return string(snippet)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// circuitBreaker opens after a number of consecutive failed calls and lets a
+// single trial call through once the cooldown has elapsed
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	failures  int
+	openUntil time.Time
+	trial     bool // a half-open trial call is in flight
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, now func() time.Time) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: now}
+}
+
+// allow reports whether a call may proceed
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// success closes the circuit
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+}
+
+// failure records a failed call, opening the circuit once the threshold is reached
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// release ends a call that neither succeeded nor failed (e.g. cancelled by the caller)
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
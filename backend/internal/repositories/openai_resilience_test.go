@@ -0,0 +1,288 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/prompts"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// scriptedServer answers each request with the next scripted response, repeating the last one
+type scriptedServer struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses []scriptedResponse
+	requests  int
+	bodies    []string
+}
+
+type scriptedResponse struct {
+	status     int
+	retryAfter string
+	body       string
+}
+
+func newScriptedServer(t *testing.T, responses ...scriptedResponse) (*scriptedServer, *httptest.Server) {
+	s := &scriptedServer{t: t, responses: responses}
+	srv := httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *scriptedServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	i := s.requests
+	if i >= len(s.responses) {
+		i = len(s.responses) - 1
+	}
+	resp := s.responses[i]
+	s.requests++
+	s.bodies = append(s.bodies, string(body))
+	s.mu.Unlock()
+
+	if resp.retryAfter != "" {
+		w.Header().Set("Retry-After", resp.retryAfter)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+	io.WriteString(w, resp.body)
+}
+
+func (s *scriptedServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func chatResponse(t *testing.T, content string) string {
+	t.Helper()
+	body, err := json.Marshal(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+const apiErrorBody = `{"error":{"message":"upstream failure","type":"server_error"}}`
+
+// testResilienceConfig retries quickly; sleeps are recorded rather than slept
+func testResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		MaxRetries:       2,
+		BaseDelay:        100 * time.Millisecond,
+		MaxDelay:         5 * time.Second,
+		AttemptTimeout:   5 * time.Second,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+type testRepo struct {
+	repo   *openAIRepo
+	doer   *resilientDoer
+	sleeps []time.Duration
+	clock  time.Time
+}
+
+func newTestRepo(t *testing.T, baseURL string, config ResilienceConfig) *testRepo {
+	t.Helper()
+
+	registry, err := prompts.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &testRepo{clock: time.Date(2026, 2, 22, 10, 0, 0, 0, time.UTC)}
+	tr.doer = newResilientDoer(&http.Client{}, config)
+	tr.doer.sleep = func(ctx context.Context, d time.Duration) error {
+		tr.sleeps = append(tr.sleeps, d)
+		return ctx.Err()
+	}
+	tr.doer.breaker.now = func() time.Time { return tr.clock }
+
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = baseURL + "/v1"
+	clientConfig.HTTPClient = tr.doer
+	tr.repo = &openAIRepo{client: openai.NewClientWithConfig(clientConfig), prompts: registry}
+	return tr
+}
+
+func TestExtractRetriesServerErrorsThenSucceeds(t *testing.T) {
+	server, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusInternalServerError, body: apiErrorBody},
+		scriptedResponse{status: http.StatusBadGateway, body: apiErrorBody},
+		scriptedResponse{status: http.StatusOK, body: chatResponse(t, `[{"unit_price": 3.5, "quantity": 2, "unit": "kg", "description": "arroz"}]`)},
+	)
+	tr := newTestRepo(t, srv.URL, testResilienceConfig())
+
+	command, err := tr.repo.InterpretTranscription(context.Background(), "dos kilos de arroz a tres cincuenta", models.LanguageSpanish, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if command.Intent != models.VoiceIntentAdd || len(command.Expenses) != 1 || command.Expenses[0].Description != "arroz" {
+		t.Fatalf("unexpected command: %+v", command)
+	}
+	if server.count() != 3 {
+		t.Fatalf("expected 3 requests, got %d", server.count())
+	}
+	if len(tr.sleeps) != 2 {
+		t.Fatalf("expected 2 backoff sleeps, got %v", tr.sleeps)
+	}
+	for i, d := range tr.sleeps {
+		max := testResilienceConfig().BaseDelay << i
+		if d < max/2 || d > max {
+			t.Errorf("sleep %d = %v, want within [%v, %v]", i, d, max/2, max)
+		}
+	}
+}
+
+func TestRetryAfterHeaderIsHonored(t *testing.T) {
+	_, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusTooManyRequests, retryAfter: "2", body: apiErrorBody},
+		scriptedResponse{status: http.StatusOK, body: chatResponse(t, `[{"unit_price": 1, "quantity": 1, "unit": "u", "description": "pan"}]`)},
+	)
+	tr := newTestRepo(t, srv.URL, testResilienceConfig())
+
+	if _, err := tr.repo.InterpretTranscription(context.Background(), "pan a un sol", models.LanguageSpanish, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tr.sleeps) != 1 || tr.sleeps[0] != 2*time.Second {
+		t.Fatalf("expected a single 2s sleep, got %v", tr.sleeps)
+	}
+}
+
+func TestRetriesExhaustedReturnsUnavailable(t *testing.T) {
+	server, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusServiceUnavailable, body: apiErrorBody},
+	)
+	tr := newTestRepo(t, srv.URL, testResilienceConfig())
+
+	_, err := tr.repo.InterpretTranscription(context.Background(), "pan", models.LanguageSpanish, time.Now())
+	if !errors.Is(err, ErrOpenAIUnavailable) {
+		t.Fatalf("expected ErrOpenAIUnavailable, got %v", err)
+	}
+	if server.count() != 3 {
+		t.Fatalf("expected 1 attempt + 2 retries, got %d requests", server.count())
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	server, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusBadRequest, body: `{"error":{"message":"bad request","type":"invalid_request_error"}}`},
+	)
+	tr := newTestRepo(t, srv.URL, testResilienceConfig())
+
+	_, err := tr.repo.InterpretTranscription(context.Background(), "pan", models.LanguageSpanish, time.Now())
+	if err == nil || errors.Is(err, ErrOpenAIUnavailable) {
+		t.Fatalf("expected a plain API error, got %v", err)
+	}
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a 400 APIError, got %v", err)
+	}
+	if server.count() != 1 {
+		t.Fatalf("expected a single request, got %d", server.count())
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	server, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusInternalServerError, body: apiErrorBody},
+	)
+	config := testResilienceConfig()
+	config.MaxRetries = 0
+	tr := newTestRepo(t, srv.URL, config)
+	ctx := context.Background()
+
+	for i := 0; i < config.BreakerThreshold; i++ {
+		if _, err := tr.repo.InterpretTranscription(ctx, "pan", models.LanguageSpanish, time.Now()); !errors.Is(err, ErrOpenAIUnavailable) {
+			t.Fatalf("call %d: expected ErrOpenAIUnavailable, got %v", i, err)
+		}
+	}
+
+	_, err := tr.repo.InterpretTranscription(ctx, "pan", models.LanguageSpanish, time.Now())
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if server.count() != config.BreakerThreshold {
+		t.Fatalf("open circuit should not reach the server, got %d requests", server.count())
+	}
+
+	// After the cooldown a trial call goes through and closes the circuit on success
+	server.mu.Lock()
+	server.responses = []scriptedResponse{{status: http.StatusOK, body: chatResponse(t, `[{"unit_price": 1, "quantity": 1, "unit": "u", "description": "pan"}]`)}}
+	server.requests = 0
+	server.mu.Unlock()
+	tr.clock = tr.clock.Add(config.BreakerCooldown + time.Second)
+
+	if _, err := tr.repo.InterpretTranscription(ctx, "pan", models.LanguageSpanish, time.Now()); err != nil {
+		t.Fatalf("trial call: unexpected error %v", err)
+	}
+	if _, err := tr.repo.InterpretTranscription(ctx, "pan", models.LanguageSpanish, time.Now()); err != nil {
+		t.Fatalf("closed circuit: unexpected error %v", err)
+	}
+}
+
+func TestNoRetryPastRequestDeadline(t *testing.T) {
+	server, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusTooManyRequests, retryAfter: "30", body: apiErrorBody},
+	)
+	config := testResilienceConfig()
+	config.MaxDelay = time.Minute
+	tr := newTestRepo(t, srv.URL, config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := tr.repo.InterpretTranscription(ctx, "pan", models.LanguageSpanish, time.Now())
+	if !errors.Is(err, ErrOpenAIUnavailable) {
+		t.Fatalf("expected ErrOpenAIUnavailable, got %v", err)
+	}
+	if server.count() != 1 || len(tr.sleeps) != 0 {
+		t.Fatalf("expected no retry, got %d requests and sleeps %v", server.count(), tr.sleeps)
+	}
+}
+
+func TestTranscriptionRetryReplaysAudio(t *testing.T) {
+	server, srv := newScriptedServer(t,
+		scriptedResponse{status: http.StatusBadGateway, body: apiErrorBody},
+		scriptedResponse{status: http.StatusOK, body: `{"text": "un pan", "language": "spanish", "segments": [{"start": 0, "end": 2, "avg_logprob": -0.1}]}`},
+	)
+	tr := newTestRepo(t, srv.URL, testResilienceConfig())
+
+	audioPath := filepath.Join(t.TempDir(), "note.m4a")
+	if err := os.WriteFile(audioPath, []byte("fake-audio-bytes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	transcription, err := tr.repo.TranscribeAudio(context.Background(), audioPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transcription.Text != "un pan" || transcription.Language != models.LanguageSpanish {
+		t.Fatalf("unexpected transcription: %+v", transcription)
+	}
+	if server.count() != 2 {
+		t.Fatalf("expected 2 requests, got %d", server.count())
+	}
+	if server.bodies[0] != server.bodies[1] || len(server.bodies[1]) == 0 {
+		t.Fatal("retried request did not replay the same multipart body")
+	}
+}
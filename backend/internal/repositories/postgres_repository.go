@@ -3,17 +3,157 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 	"upload-lambda/internal/models"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // ExpenseRepository defines the interface for expense data operations
+//
+// Every change is recorded in the expense_events audit history, in the same transaction, with
+// the actor who made it (the expense's user for creations). Deleted expenses are kept with a
+// deleted_at time and are not found, listed or summarized until restored.
 type ExpenseRepository interface {
 	Create(ctx context.Context, expense *models.Expense) error
+	// Import creates imported expenses in one transaction, skipping those whose ExternalID the
+	// user already has, and returns the ones it created
+	Import(ctx context.Context, expenses []*models.Expense) ([]*models.Expense, error)
 	FindByID(ctx context.Context, id string) (*models.Expense, error)
+	// FindByIDWithDeleted is FindByID including deleted expenses
+	FindByIDWithDeleted(ctx context.Context, id string) (*models.Expense, error)
 	List(ctx context.Context, params models.ListExpensesParams) (*models.PaginatedExpenses, error)
+	// Export calls fn for every expense matching the list filters, in list order, reading them
+	// in batches through a server-side cursor. Pagination parameters are ignored.
+	Export(ctx context.Context, params models.ListExpensesParams, fn func(*models.Expense) error) error
+	UpdateStatus(ctx context.Context, id string, status string, actor string) error
+	// Update saves the unit price, quantity, unit and description of an expense;
+	// ErrExpenseClaimed if it is claimed in a submitted report
+	Update(ctx context.Context, expense *models.Expense, actor string) error
+	// FindLatest returns a user's most recently recorded expenses, latest first. Imported bank
+	// transactions were not recorded by the user and are left out.
+	FindLatest(ctx context.Context, userID string, limit int) ([]*models.Expense, error)
+	// FindRecent returns a user's expenses purchased within [from, to]
+	FindRecent(ctx context.Context, userID string, from time.Time, to time.Time) ([]*models.Expense, error)
+	// UpdatePossibleDuplicate flags an expense as a likely duplicate of another, or clears the flag if duplicateOf is nil
+	UpdatePossibleDuplicate(ctx context.Context, id string, duplicateOf *string, actor string) error
+	// Delete soft-deletes an expense, taking it out of its pending report and clearing the
+	// duplicate flags that point to it; ErrExpenseClaimed if it is claimed in a submitted report
+	Delete(ctx context.Context, id string, actor string) error
+	// Restore undeletes an expense, flagging again the duplicates its deletion unflagged, and
+	// returns it; ErrExpenseNotDeleted if it is not deleted
+	Restore(ctx context.Context, id string, actor string) (*models.Expense, error)
+	// History returns the audit history of an expense, deleted or not, oldest first
+	History(ctx context.Context, id string) ([]*models.ExpenseEvent, error)
+	// UpdateReimbursable flags an expense as reimbursable, pending its claim, or unflags it,
+	// taking it out of its report; ErrExpenseClaimed if it is claimed in a submitted report
+	UpdateReimbursable(ctx context.Context, id string, reimbursable bool, actor string) error
+	// AddTags tags an expense with normalized tags, creating those its user does not have yet,
+	// and returns the expense
+	AddTags(ctx context.Context, id string, tags []string, actor string) (*models.Expense, error)
+	// RemoveTag removes a tag from an expense and returns the expense
+	RemoveTag(ctx context.Context, id string, tag string, actor string) (*models.Expense, error)
+	// Summarize totals a user's expenses, grouped by params.GroupBy if set
+	Summarize(ctx context.Context, params models.SummaryParams) (*models.ExpenseSummary, error)
+}
+
+// Expense errors
+var (
+	// ErrExpenseNotFound is returned when no expense matches the given ID, or it is deleted
+	ErrExpenseNotFound = errors.New("expense not found")
+	// ErrExpenseNotDeleted is returned when restoring an expense that is not deleted
+	ErrExpenseNotDeleted = errors.New("expense is not deleted")
+	// ErrExpenseClaimed is returned when changing or deleting an expense claimed in a
+	// submitted report, which must stay as it was claimed
+	ErrExpenseClaimed = errors.New("expense was claimed in a submitted report")
+)
+
+// unclaimedCondition limits an update to expenses not claimed in a submitted report. The
+// updates using it run in change, with the row locked, so it is the only reason they miss.
+const unclaimedCondition = `(reimbursement_status IS NULL OR reimbursement_status = 'pending')`
+
+// expenseColumns lists the columns read by scanExpense, in order
+const expenseColumns = `id, user_id, unit_price, quantity, unit, description, purchased_at, recording_id, receipt_id, merchant_id,
+	(SELECT name FROM merchants WHERE merchants.id = expenses.merchant_id), source, external_id, prompt_version, confidence, status, possible_duplicate_of, created_at, deleted_at,
+	reimbursable, reimbursement_status, report_id,
+	ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = expenses.id ORDER BY t.name)`
+
+// searchConfig is the text search configuration of expenses.search_vector: Spanish stemming
+// of accent-folded words
+const searchConfig = "es_unaccent"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanExpense scans a row selected with expenseColumns, followed by any extra columns
+func scanExpense(row rowScanner, extra ...any) (*models.Expense, error) {
+	var expense models.Expense
+	var recordingID, receiptID, merchantID, merchant, externalID, promptVersion, possibleDuplicateOf, reimbursementStatus, reportID sql.NullString
+	var confidence sql.NullFloat64
+	var deletedAt sql.NullTime
+	var tags pq.StringArray
+	err := row.Scan(append([]any{
+		&expense.ID,
+		&expense.UserID,
+		&expense.UnitPrice,
+		&expense.Quantity,
+		&expense.Unit,
+		&expense.Description,
+		&expense.PurchasedAt,
+		&recordingID,
+		&receiptID,
+		&merchantID,
+		&merchant,
+		&expense.Source,
+		&externalID,
+		&promptVersion,
+		&confidence,
+		&expense.Status,
+		&possibleDuplicateOf,
+		&expense.CreatedAt,
+		&deletedAt,
+		&expense.Reimbursable,
+		&reimbursementStatus,
+		&reportID,
+		&tags,
+	}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if possibleDuplicateOf.Valid {
+		expense.PossibleDuplicateOf = &possibleDuplicateOf.String
+	}
+	if merchantID.Valid {
+		expense.MerchantID = &merchantID.String
+	}
+	if confidence.Valid {
+		expense.Confidence = &confidence.Float64
+	}
+	if reportID.Valid {
+		expense.ReportID = &reportID.String
+	}
+	// TIMESTAMPTZ values come back in the session timezone
+	expense.PurchasedAt = expense.PurchasedAt.UTC()
+	expense.CreatedAt = expense.CreatedAt.UTC()
+	if deletedAt.Valid {
+		deleted := deletedAt.Time.UTC()
+		expense.DeletedAt = &deleted
+	}
+	expense.Merchant = merchant.String
+	expense.ExternalID = externalID.String
+	expense.RecordingID = recordingID.String
+	expense.ReceiptID = receiptID.String
+	expense.PromptVersion = promptVersion.String
+	expense.ReimbursementStatus = reimbursementStatus.String
+	if len(tags) > 0 {
+		expense.Tags = []string(tags)
+	}
+	return &expense, nil
 }
 
 type postgresRepo struct {
@@ -27,6 +167,44 @@ func NewPostgresRepository(dbURL string) ExpenseRepository {
 	}
 }
 
+// insertExpense inserts an expense with the arguments of insertExpenseArgs
+const insertExpense = `
+	INSERT INTO expenses (id, user_id, unit_price, quantity, unit, description, purchased_at, recording_id, receipt_id, merchant_id, source, external_id, prompt_version, confidence, status, possible_duplicate_of, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+`
+
+// insertExpenseArgs returns the arguments of insertExpense, defaulting the user and source
+func insertExpenseArgs(expense *models.Expense) []any {
+	userID := expense.UserID
+	if userID == "" {
+		userID = models.DefaultUserID
+	}
+	source := expense.Source
+	if source == "" {
+		source = models.ExpenseSourceVoice
+	}
+
+	return []any{
+		expense.ID,
+		userID,
+		expense.UnitPrice,
+		expense.Quantity,
+		expense.Unit,
+		expense.Description,
+		expense.PurchasedAt,
+		sql.NullString{String: expense.RecordingID, Valid: expense.RecordingID != ""},
+		sql.NullString{String: expense.ReceiptID, Valid: expense.ReceiptID != ""},
+		expense.MerchantID,
+		source,
+		sql.NullString{String: expense.ExternalID, Valid: expense.ExternalID != ""},
+		sql.NullString{String: expense.PromptVersion, Valid: expense.PromptVersion != ""},
+		expense.Confidence,
+		expense.Status,
+		expense.PossibleDuplicateOf,
+		expense.CreatedAt,
+	}
+}
+
 func (r *postgresRepo) Create(ctx context.Context, expense *models.Expense) error {
 	db, err := sql.Open("postgres", r.dbURL)
 	if err != nil {
@@ -38,29 +216,103 @@ func (r *postgresRepo) Create(ctx context.Context, expense *models.Expense) erro
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, insertExpense, insertExpenseArgs(expense)...); err != nil {
+		return fmt.Errorf("failed to insert expense: %w", err)
+	}
+	if err := insertTags(ctx, tx, expense.ID, expense.Tags); err != nil {
+		return err
+	}
+	if err := recordCreation(ctx, tx, expense.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit expense: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresRepo) Import(ctx context.Context, expenses []*models.Expense) ([]*models.Expense, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := insertExpense + `ON CONFLICT (user_id, external_id) WHERE external_id IS NOT NULL DO NOTHING`
+
+	var created []*models.Expense
+	for _, expense := range expenses {
+		result, err := tx.ExecContext(ctx, query, insertExpenseArgs(expense)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert expense: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rows > 0 {
+			if err := recordCreation(ctx, tx, expense.ID); err != nil {
+				return nil, err
+			}
+			created = append(created, expense)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *postgresRepo) FindByID(ctx context.Context, id string) (*models.Expense, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
 	query := `
-		INSERT INTO expenses (id, unit_price, quantity, unit, description, purchased_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	_, err = db.ExecContext(ctx, query,
-		expense.ID,
-		expense.UnitPrice,
-		expense.Quantity,
-		expense.Unit,
-		expense.Description,
-		expense.PurchasedAt,
-		expense.CreatedAt,
-	)
+	expense, err := scanExpense(db.QueryRowContext(ctx, query, id))
 
+	if err == sql.ErrNoRows {
+		return nil, ErrExpenseNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to insert expense: %w", err)
+		return nil, fmt.Errorf("failed to query expense: %w", err)
 	}
 
-	return nil
+	return expense, nil
 }
 
-func (r *postgresRepo) FindByID(ctx context.Context, id string) (*models.Expense, error) {
+func (r *postgresRepo) FindByIDWithDeleted(ctx context.Context, id string) (*models.Expense, error) {
 	db, err := sql.Open("postgres", r.dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -72,30 +324,21 @@ func (r *postgresRepo) FindByID(ctx context.Context, id string) (*models.Expense
 	}
 
 	query := `
-		SELECT id, unit_price, quantity, unit, description, purchased_at, created_at
+		SELECT ` + expenseColumns + `
 		FROM expenses
 		WHERE id = $1
 	`
 
-	var expense models.Expense
-	err = db.QueryRowContext(ctx, query, id).Scan(
-		&expense.ID,
-		&expense.UnitPrice,
-		&expense.Quantity,
-		&expense.Unit,
-		&expense.Description,
-		&expense.PurchasedAt,
-		&expense.CreatedAt,
-	)
+	expense, err := scanExpense(db.QueryRowContext(ctx, query, id))
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("expense not found")
+		return nil, ErrExpenseNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query expense: %w", err)
 	}
 
-	return &expense, nil
+	return expense, nil
 }
 
 func (r *postgresRepo) List(ctx context.Context, params models.ListExpensesParams) (*models.PaginatedExpenses, error) {
@@ -116,33 +359,80 @@ func (r *postgresRepo) List(ctx context.Context, params models.ListExpensesParam
 	if params.PerPage < 1 || params.PerPage > 100 {
 		params.PerPage = 10
 	}
-	if params.OrderBy != "purchased_at" && params.OrderBy != "created_at" {
-		params.OrderBy = "created_at"
+	params.OrderBy, params.OrderDir = listOrder(params)
+	if params.Query != "" {
+		params.OrderBy, params.OrderDir = OrderByRank, "desc"
 	}
-	if params.OrderDir != "asc" && params.OrderDir != "desc" {
-		params.OrderDir = "desc"
+
+	where, args := listFilters(params)
+	result := &models.PaginatedExpenses{PerPage: params.PerPage}
+
+	// Counting scans every match, so it is only done when asked for
+	if params.IncludeTotal {
+		var total int
+		countQuery := `SELECT COUNT(*) FROM expenses ` + where
+		err = db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count expenses: %w", err)
+		}
+		totalPages := (total + params.PerPage - 1) / params.PerPage
+		result.Total = &total
+		result.TotalPages = &totalPages
 	}
 
-	// Count total records
-	var total int
-	countQuery := `SELECT COUNT(*) FROM expenses`
-	err = db.QueryRowContext(ctx, countQuery).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count expenses: %w", err)
+	// Search results are ranked and come with excerpts. PostgreSQL computes the excerpts
+	// after the LIMIT, only for the rows returned.
+	orderKey := params.OrderBy
+	searchColumns := ""
+	if params.Query != "" {
+		args = append(args, params.Query)
+		tsquery := fmt.Sprintf("websearch_to_tsquery('%s', $%d)", searchConfig, len(args))
+		orderKey = fmt.Sprintf("ts_rank(search_vector, %s)", tsquery)
+		searchColumns = fmt.Sprintf(`, %s,
+			ts_headline('%s', description, %s, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			COALESCE((SELECT ts_headline('%s', transcription, %s, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=15')
+				FROM recordings
+				WHERE recordings.id = expenses.recording_id AND to_tsvector('%s', transcription) @@ %s), '')`,
+			orderKey, searchConfig, tsquery, searchConfig, tsquery, searchConfig, tsquery)
 	}
 
-	// Calculate offset
-	offset := (params.Page - 1) * params.PerPage
+	// A cursor continues after the last expense seen (keyset pagination), so expenses created
+	// meanwhile neither shift nor repeat rows; without one, the page is an offset
+	var pagination string
+	if params.Cursor != "" {
+		position, err := DecodeListCursor(params.Cursor, params.OrderBy, params.OrderDir)
+		if err != nil {
+			return nil, err
+		}
+		comparison := "<"
+		if params.OrderDir == "asc" {
+			comparison = ">"
+		}
+		var value any = position.Value
+		if params.OrderBy == OrderByRank {
+			value = position.Rank
+		}
+		args = append(args, value, position.ID)
+		keyset := fmt.Sprintf("(%s, id) %s ($%d, $%d)", orderKey, comparison, len(args)-1, len(args))
+		where += " AND " + keyset
+		args = append(args, params.PerPage+1)
+		pagination = fmt.Sprintf("LIMIT $%d", len(args))
+	} else {
+		result.Page = params.Page
+		args = append(args, params.PerPage+1, (params.Page-1)*params.PerPage)
+		pagination = fmt.Sprintf("LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
 
-	// Build query with ORDER BY
+	// One extra row tells whether there is a next page
 	query := fmt.Sprintf(`
-		SELECT id, unit_price, quantity, unit, description, purchased_at, created_at
+		SELECT %s%s
 		FROM expenses
-		ORDER BY %s %s
-		LIMIT $1 OFFSET $2
-	`, params.OrderBy, params.OrderDir)
+		%s
+		ORDER BY %s %s, id %s
+		%s
+	`, expenseColumns, searchColumns, where, orderKey, params.OrderDir, params.OrderDir, pagination)
 
-	rows, err := db.QueryContext(ctx, query, params.PerPage, offset)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query expenses: %w", err)
 	}
@@ -150,34 +440,566 @@ func (r *postgresRepo) List(ctx context.Context, params models.ListExpensesParam
 
 	var expenses []*models.Expense
 	for rows.Next() {
-		var expense models.Expense
-		err := rows.Scan(
-			&expense.ID,
-			&expense.UnitPrice,
-			&expense.Quantity,
-			&expense.Unit,
-			&expense.Description,
-			&expense.PurchasedAt,
-			&expense.CreatedAt,
-		)
+		var match models.ExpenseMatch
+		var extra []any
+		if params.Query != "" {
+			extra = []any{&match.Rank, &match.Description, &match.Transcription}
+		}
+		expense, err := scanExpense(rows, extra...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan expense: %w", err)
 		}
-		expenses = append(expenses, &expense)
+		if params.Query != "" {
+			expense.Match = &match
+		}
+		expenses = append(expenses, expense)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating expenses: %w", err)
 	}
 
-	// Calculate total pages
-	totalPages := (total + params.PerPage - 1) / params.PerPage
+	if len(expenses) > params.PerPage {
+		expenses = expenses[:params.PerPage]
+		result.NextCursor = EncodeListCursor(expenses[len(expenses)-1], params.OrderBy, params.OrderDir)
+	}
+	result.Data = expenses
+	return result, nil
+}
+
+// exportBatchSize is how many rows each FETCH from the export cursor reads
+const exportBatchSize = 500
+
+func (r *postgresRepo) Export(ctx context.Context, params models.ListExpensesParams, fn func(*models.Expense) error) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Cursors live inside a transaction; a read-only one is enough
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	orderBy, orderDir := listOrder(params)
+	where, args := listFilters(params)
+	query := fmt.Sprintf(`
+		DECLARE expense_export NO SCROLL CURSOR FOR
+		SELECT %s
+		FROM expenses
+		%s
+		ORDER BY %s %s, id %s
+	`, expenseColumns, where, orderBy, orderDir, orderDir)
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to declare export cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf(`FETCH %d FROM expense_export`, exportBatchSize)
+	for {
+		batch, err := fetchExpenses(ctx, tx, fetch)
+		if err != nil {
+			return err
+		}
+		// fn may be slow (it writes to the client), so it runs with the batch already read
+		for _, expense := range batch {
+			if err := fn(expense); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			break
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to close export cursor: %w", err)
+	}
+
+	return nil
+}
+
+// fetchExpenses reads the expenses returned by one FETCH
+func fetchExpenses(ctx context.Context, tx *sql.Tx, fetch string) ([]*models.Expense, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var expenses []*models.Expense
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		expenses = append(expenses, expense)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expenses: %w", err)
+	}
+
+	return expenses, nil
+}
+
+func (r *postgresRepo) UpdateStatus(ctx context.Context, id string, status string, actor string) error {
+	_, err := r.change(ctx, id, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE expenses SET status = $1 WHERE id = $2`, status, id); err != nil {
+			return fmt.Errorf("failed to update expense status: %w", err)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *postgresRepo) Update(ctx context.Context, expense *models.Expense, actor string) error {
+	query := `
+		UPDATE expenses
+		SET unit_price = $1, quantity = $2, unit = $3, description = $4
+		WHERE id = $5 AND ` + unclaimedCondition + `
+	`
+
+	_, err := r.change(ctx, expense.ID, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, expense.UnitPrice, expense.Quantity, expense.Unit, expense.Description, expense.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update expense: %w", err)
+		}
+		return requireRow(result, ErrExpenseClaimed)
+	})
+	return err
+}
+
+func (r *postgresRepo) UpdateReimbursable(ctx context.Context, id string, reimbursable bool, actor string) error {
+	query := `
+		UPDATE expenses
+		SET reimbursable = $1, reimbursement_status = CASE WHEN $1 THEN $2 END, report_id = NULL
+		WHERE id = $3 AND ` + unclaimedCondition + `
+	`
+
+	_, err := r.change(ctx, id, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, reimbursable, models.ReimbursementPending, id)
+		if err != nil {
+			return fmt.Errorf("failed to update reimbursable: %w", err)
+		}
+		return requireRow(result, ErrExpenseClaimed)
+	})
+	return err
+}
+
+func (r *postgresRepo) FindLatest(ctx context.Context, userID string, limit int) ([]*models.Expense, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE user_id = $1 AND deleted_at IS NULL AND source <> 'import'
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var expenses []*models.Expense
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		expenses = append(expenses, expense)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expenses: %w", err)
+	}
+
+	return expenses, nil
+}
+
+func (r *postgresRepo) FindRecent(ctx context.Context, userID string, from time.Time, to time.Time) ([]*models.Expense, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE user_id = $1 AND purchased_at BETWEEN $2 AND $3 AND deleted_at IS NULL
+		ORDER BY purchased_at DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var expenses []*models.Expense
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		expenses = append(expenses, expense)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expenses: %w", err)
+	}
+
+	return expenses, nil
+}
+
+func (r *postgresRepo) UpdatePossibleDuplicate(ctx context.Context, id string, duplicateOf *string, actor string) error {
+	_, err := r.change(ctx, id, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+		return setPossibleDuplicate(ctx, tx, id, duplicateOf)
+	})
+	return err
+}
+
+// setPossibleDuplicate sets or clears the duplicate flag of an expense
+func setPossibleDuplicate(ctx context.Context, tx *sql.Tx, id string, duplicateOf *string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE expenses SET possible_duplicate_of = $1 WHERE id = $2`, duplicateOf, id); err != nil {
+		return fmt.Errorf("failed to update possible duplicate: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, id string, actor string) error {
+	_, err := r.change(ctx, id, models.ExpenseEventDelete, actor, func(tx *sql.Tx) error {
+		// A deleted expense leaves its pending report, whose status changes only reach the
+		// expenses it still has; restored, it is pending again without a report
+		result, err := tx.ExecContext(ctx, `UPDATE expenses SET deleted_at = NOW(), report_id = NULL WHERE id = $1 AND `+unclaimedCondition, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete expense: %w", err)
+		}
+		if err := requireRow(result, ErrExpenseClaimed); err != nil {
+			return err
+		}
+
+		// Like the ON DELETE SET NULL of a hard delete, expenses flagged as duplicates of the
+		// deleted one are no longer flagged
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM expenses WHERE possible_duplicate_of = $1 AND deleted_at IS NULL`, id)
+		if err != nil {
+			return fmt.Errorf("failed to query duplicates: %w", err)
+		}
+		var duplicates []string
+		for rows.Next() {
+			var duplicate string
+			if err := rows.Scan(&duplicate); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan duplicate: %w", err)
+			}
+			duplicates = append(duplicates, duplicate)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating duplicates: %w", err)
+		}
+
+		for _, duplicate := range duplicates {
+			_, err := changeInTx(ctx, tx, duplicate, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+				return setPossibleDuplicate(ctx, tx, duplicate, nil)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *postgresRepo) Restore(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	return r.change(ctx, id, models.ExpenseEventRestore, actor, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE expenses SET deleted_at = NULL WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to restore expense: %w", err)
+		}
+
+		// The duplicates unflagged by the last deletion are found in its audit events; while the
+		// expense was deleted, no other change could flag or unflag them against it
+		rows, err := tx.QueryContext(ctx, `
+			SELECT DISTINCT ev.expense_id
+			FROM expense_events ev
+			JOIN expenses e ON e.id = ev.expense_id AND e.deleted_at IS NULL AND e.possible_duplicate_of IS NULL
+			WHERE ev.action = 'update'
+				AND ev.before->>'possible_duplicate_of' = $1::text
+				AND ev.after->>'possible_duplicate_of' IS NULL
+				AND ev.id > (SELECT MAX(id) FROM expense_events WHERE expense_id = $1::uuid AND action = 'delete')
+		`, id)
+		if err != nil {
+			return fmt.Errorf("failed to query unflagged duplicates: %w", err)
+		}
+		var duplicates []string
+		for rows.Next() {
+			var duplicate string
+			if err := rows.Scan(&duplicate); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan duplicate: %w", err)
+			}
+			duplicates = append(duplicates, duplicate)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating duplicates: %w", err)
+		}
+
+		for _, duplicate := range duplicates {
+			_, err := changeInTx(ctx, tx, duplicate, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+				return setPossibleDuplicate(ctx, tx, duplicate, &id)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// summaryGrouping is the SQL of a summary grouping: its group key and label, the join
+// they need and the order of the groups. Overlapping groups count an expense in more than
+// one group, so their totals do not add up to the summary's.
+type summaryGrouping struct {
+	key, label, join, order string
+	overlapping             bool
+}
+
+// summaryGroupingFor returns the SQL of a supported group_by, bucketing days in timezone
+func summaryGroupingFor(groupBy string, timezone string) (summaryGrouping, bool) {
+	switch groupBy {
+	case models.SummaryGroupByMerchant:
+		return summaryGrouping{
+			key:   "COALESCE(e.merchant_id::text, '')",
+			label: "COALESCE(m.name, '')",
+			join:  "LEFT JOIN merchants m ON m.id = e.merchant_id",
+			order: "3 DESC, 2",
+		}, true
+	case models.SummaryGroupByTag:
+		return summaryGrouping{
+			key:         "COALESCE(t.name, '')",
+			label:       "COALESCE(t.name, '')",
+			join:        "LEFT JOIN expense_tags et ON et.expense_id = e.id LEFT JOIN tags t ON t.id = et.tag_id",
+			order:       "3 DESC, 2",
+			overlapping: true,
+		}, true
+	case models.SummaryGroupByDay:
+		// The timezone is a validated IANA name from the user's settings
+		day := fmt.Sprintf("to_char(e.purchased_at AT TIME ZONE %s, 'YYYY-MM-DD')", pq.QuoteLiteral(timezone))
+		return summaryGrouping{key: day, label: day, order: "1"}, true
+	}
+	// Without a grouping, everything falls in a single group
+	return summaryGrouping{key: "''", label: "''", order: "1"}, false
+}
+
+func (r *postgresRepo) Summarize(ctx context.Context, params models.SummaryParams) (*models.ExpenseSummary, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	timezone := params.Timezone
+	if timezone == "" {
+		timezone = models.DefaultTimezone
+	}
+	grouping, ok := summaryGroupingFor(params.GroupBy, timezone)
+
+	args := []any{params.UserID}
+	conditions := []string{
+		"e.user_id = $1",
+		"e.deleted_at IS NULL",
+		// An imported transaction matched to a recorded expense is the same purchase
+		`NOT EXISTS (
+			SELECT 1 FROM reconciliation_matches m
+			JOIN expenses recorded ON recorded.id = m.expense_id AND recorded.deleted_at IS NULL
+			WHERE m.transaction_id = e.id
+		)`,
+	}
+	if params.From != nil {
+		args = append(args, *params.From)
+		conditions = append(conditions, fmt.Sprintf("e.purchased_at >= $%d", len(args)))
+	}
+	if params.To != nil {
+		args = append(args, *params.To)
+		conditions = append(conditions, fmt.Sprintf("e.purchased_at <= $%d", len(args)))
+	}
+	if params.MerchantID != "" {
+		args = append(args, params.MerchantID)
+		conditions = append(conditions, fmt.Sprintf("e.merchant_id = $%d", len(args)))
+	}
+	if params.Query != "" {
+		args = append(args, params.Query)
+		conditions = append(conditions, fmt.Sprintf("e.search_vector @@ websearch_to_tsquery('%s', $%d)", searchConfig, len(args)))
+	}
+	if len(params.Units) > 0 {
+		args = append(args, pq.Array(params.Units))
+		conditions = append(conditions, fmt.Sprintf("e.unit = ANY($%d)", len(args)))
+	}
+	if len(params.Tags) > 0 {
+		var condition string
+		condition, args = tagCondition("e.id", params.TagFilter, args)
+		conditions = append(conditions, condition)
+	}
+
+	where := strings.Join(conditions, " AND ")
+	groups, err := summaryGroups(ctx, db, grouping, where, args)
+	if err != nil {
+		return nil, err
+	}
+	// The totals count an expense in several overlapping groups (e.g. tags) once
+	totals := groups
+	if grouping.overlapping {
+		plain, _ := summaryGroupingFor("", timezone)
+		if totals, err = summaryGroups(ctx, db, plain, where, args); err != nil {
+			return nil, err
+		}
+	}
+
+	summary := &models.ExpenseSummary{
+		GroupBy:  params.GroupBy,
+		Timezone: timezone,
+		From:     params.From,
+		To:       params.To,
+	}
+	for _, group := range totals {
+		if summary.Count == 0 || group.Min < summary.Min {
+			summary.Min = group.Min
+		}
+		if summary.Count == 0 || group.Max > summary.Max {
+			summary.Max = group.Max
+		}
+		summary.Total += group.Total
+		summary.Count += group.Count
+	}
+	if ok {
+		summary.Groups = groups
+	}
+
+	return summary, nil
+}
+
+// summaryGroups totals the expenses matching the summary conditions in each group of a grouping
+func summaryGroups(ctx context.Context, db *sql.DB, grouping summaryGrouping, where string, args []any) ([]models.SummaryGroup, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, %s, SUM(e.unit_price * e.quantity), COUNT(*), MIN(e.unit_price * e.quantity), MAX(e.unit_price * e.quantity)
+		FROM expenses e
+		%s
+		WHERE %s
+		GROUP BY 1, 2
+		ORDER BY %s
+	`, grouping.key, grouping.label, grouping.join, where, grouping.order)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.SummaryGroup
+	for rows.Next() {
+		var group models.SummaryGroup
+		if err := rows.Scan(&group.Key, &group.Label, &group.Total, &group.Count, &group.Min, &group.Max); err != nil {
+			return nil, fmt.Errorf("failed to scan summary group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating summary groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// listOrder returns the validated sort field and direction of a list (default: created_at desc)
+func listOrder(params models.ListExpensesParams) (string, string) {
+	orderBy, orderDir := params.OrderBy, params.OrderDir
+	if orderBy != "purchased_at" && orderBy != "created_at" {
+		orderBy = "created_at"
+	}
+	if orderDir != "asc" && orderDir != "desc" {
+		orderDir = "desc"
+	}
+	return orderBy, orderDir
+}
+
+// listFilters builds the WHERE clause and its arguments for the list filters
+func listFilters(params models.ListExpensesParams) (string, []any) {
+	userID := params.UserID
+	if userID == "" {
+		userID = models.DefaultUserID
+	}
+	conditions := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []any{userID}
+
+	if params.Status != "" {
+		args = append(args, params.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if params.PossibleDuplicate {
+		conditions = append(conditions, "possible_duplicate_of IS NOT NULL")
+	}
+
+	if params.MerchantID != "" {
+		args = append(args, params.MerchantID)
+		conditions = append(conditions, fmt.Sprintf("merchant_id = $%d", len(args)))
+	}
+
+	if params.ReimbursementStatus != "" {
+		args = append(args, params.ReimbursementStatus)
+		conditions = append(conditions, fmt.Sprintf("reimbursement_status = $%d", len(args)))
+	}
+
+	if len(params.Tags) > 0 {
+		var condition string
+		condition, args = tagCondition("expenses.id", params.TagFilter, args)
+		conditions = append(conditions, condition)
+	}
+
+	if params.Query != "" {
+		args = append(args, params.Query)
+		conditions = append(conditions, fmt.Sprintf("search_vector @@ websearch_to_tsquery('%s', $%d)", searchConfig, len(args)))
+	}
+
+	if params.From != nil {
+		args = append(args, *params.From)
+		conditions = append(conditions, fmt.Sprintf("purchased_at >= $%d", len(args)))
+	}
+
+	if params.To != nil {
+		args = append(args, *params.To)
+		conditions = append(conditions, fmt.Sprintf("purchased_at <= $%d", len(args)))
+	}
 
-	return &models.PaginatedExpenses{
-		Data:       expenses,
-		Page:       params.Page,
-		PerPage:    params.PerPage,
-		Total:      total,
-		TotalPages: totalPages,
-	}, nil
+	return "WHERE " + strings.Join(conditions, " AND "), args
 }
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"upload-lambda/internal/models"
+)
+
+// ReceiptRepository defines the interface for receipt data operations
+type ReceiptRepository interface {
+	Create(ctx context.Context, receipt *models.Receipt) error
+	// FindByID returns a receipt including its image
+	FindByID(ctx context.Context, id string) (*models.Receipt, error)
+}
+
+// ErrReceiptNotFound is returned when no receipt matches the given ID
+var ErrReceiptNotFound = errors.New("receipt not found")
+
+type postgresReceiptRepo struct {
+	dbURL string
+}
+
+// NewPostgresReceiptRepository creates a new PostgreSQL receipt repository
+func NewPostgresReceiptRepository(dbURL string) ReceiptRepository {
+	return &postgresReceiptRepo{
+		dbURL: dbURL,
+	}
+}
+
+func (r *postgresReceiptRepo) Create(ctx context.Context, receipt *models.Receipt) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		INSERT INTO receipts (id, user_id, content_type, image, language, ocr_text, ocr_engine, ocr_confidence, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err = db.ExecContext(ctx, query,
+		receipt.ID,
+		receipt.UserID,
+		receipt.ContentType,
+		receipt.Image,
+		receipt.Language,
+		receipt.OCRText,
+		receipt.OCREngine,
+		receipt.OCRConfidence,
+		receipt.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert receipt: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresReceiptRepo) FindByID(ctx context.Context, id string) (*models.Receipt, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, content_type, image, language, ocr_text, ocr_engine, ocr_confidence, created_at
+		FROM receipts
+		WHERE id = $1
+	`
+
+	var receipt models.Receipt
+	var confidence sql.NullFloat64
+	err = db.QueryRowContext(ctx, query, id).Scan(
+		&receipt.ID,
+		&receipt.UserID,
+		&receipt.ContentType,
+		&receipt.Image,
+		&receipt.Language,
+		&receipt.OCRText,
+		&receipt.OCREngine,
+		&confidence,
+		&receipt.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query receipt: %w", err)
+	}
+	if confidence.Valid {
+		receipt.OCRConfidence = &confidence.Float64
+	}
+
+	return &receipt, nil
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"upload-lambda/internal/models"
+)
+
+// ErrMatchNotFound is returned when a bank transaction has no reconciliation match
+var ErrMatchNotFound = errors.New("reconciliation match not found")
+
+// ReconciliationRepository defines the interface for reconciliation match data operations
+type ReconciliationRepository interface {
+	// SaveMatches stores matches of a user; an expense already matched keeps its match
+	SaveMatches(ctx context.Context, userID string, matches []models.ReconciliationMatch) error
+	// ListMatches returns all the matches of a user, leaving out those of deleted expenses
+	// until they are restored
+	ListMatches(ctx context.Context, userID string) ([]models.ReconciliationMatch, error)
+	// DeleteMatches deletes the matches of a bank transaction of a user and records them as
+	// rejected; returns ErrMatchNotFound if it has none
+	DeleteMatches(ctx context.Context, userID string, transactionID string) error
+	// ListRejections returns the matches a user deleted
+	ListRejections(ctx context.Context, userID string) ([]models.ReconciliationRejection, error)
+}
+
+type postgresReconciliationRepo struct {
+	dbURL string
+}
+
+// NewPostgresReconciliationRepository creates a new PostgreSQL reconciliation repository
+func NewPostgresReconciliationRepository(dbURL string) ReconciliationRepository {
+	return &postgresReconciliationRepo{
+		dbURL: dbURL,
+	}
+}
+
+func (r *postgresReconciliationRepo) SaveMatches(ctx context.Context, userID string, matches []models.ReconciliationMatch) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, match := range matches {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO reconciliation_matches (expense_id, transaction_id, user_id, matched_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (expense_id) DO NOTHING
+		`, match.ExpenseID, match.TransactionID, userID, match.MatchedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert reconciliation match: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reconciliation matches: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresReconciliationRepo) ListMatches(ctx context.Context, userID string) ([]models.ReconciliationMatch, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.expense_id, m.transaction_id, m.matched_at
+		FROM reconciliation_matches m
+		JOIN expenses recorded ON recorded.id = m.expense_id AND recorded.deleted_at IS NULL
+		JOIN expenses bank ON bank.id = m.transaction_id AND bank.deleted_at IS NULL
+		WHERE m.user_id = $1
+		ORDER BY m.matched_at, m.expense_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reconciliation matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []models.ReconciliationMatch
+	for rows.Next() {
+		var match models.ReconciliationMatch
+		if err := rows.Scan(&match.ExpenseID, &match.TransactionID, &match.MatchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reconciliation match: %w", err)
+		}
+		match.MatchedAt = match.MatchedAt.UTC()
+		matches = append(matches, match)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reconciliation matches: %w", err)
+	}
+
+	return matches, nil
+}
+
+func (r *postgresReconciliationRepo) DeleteMatches(ctx context.Context, userID string, transactionID string) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM reconciliation_matches
+			WHERE user_id = $1 AND transaction_id = $2
+			RETURNING expense_id, transaction_id, user_id
+		)
+		INSERT INTO reconciliation_rejections (expense_id, transaction_id, user_id)
+		SELECT expense_id, transaction_id, user_id FROM deleted
+		ON CONFLICT (expense_id, transaction_id) DO NOTHING
+	`, userID, transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reconciliation matches: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrMatchNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deleted reconciliation matches: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresReconciliationRepo) ListRejections(ctx context.Context, userID string) ([]models.ReconciliationRejection, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT expense_id, transaction_id, rejected_at
+		FROM reconciliation_rejections
+		WHERE user_id = $1
+		ORDER BY rejected_at, expense_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reconciliation rejections: %w", err)
+	}
+	defer rows.Close()
+
+	var rejections []models.ReconciliationRejection
+	for rows.Next() {
+		var rejection models.ReconciliationRejection
+		if err := rows.Scan(&rejection.ExpenseID, &rejection.TransactionID, &rejection.RejectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reconciliation rejection: %w", err)
+		}
+		rejection.RejectedAt = rejection.RejectedAt.UTC()
+		rejections = append(rejections, rejection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reconciliation rejections: %w", err)
+	}
+
+	return rejections, nil
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"upload-lambda/internal/models"
+)
+
+// RecordingRepository defines the interface for recording data operations
+type RecordingRepository interface {
+	Create(ctx context.Context, recording *models.Recording) error
+}
+
+type postgresRecordingRepo struct {
+	dbURL string
+}
+
+// NewPostgresRecordingRepository creates a new PostgreSQL recording repository
+func NewPostgresRecordingRepository(dbURL string) RecordingRepository {
+	return &postgresRecordingRepo{
+		dbURL: dbURL,
+	}
+}
+
+func (r *postgresRecordingRepo) Create(ctx context.Context, recording *models.Recording) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		INSERT INTO recordings (id, user_id, client_recording_id, language, transcription, transcription_confidence, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = db.ExecContext(ctx, query,
+		recording.ID,
+		recording.UserID,
+		sql.NullString{String: recording.ClientRecordingID, Valid: recording.ClientRecordingID != ""},
+		recording.Language,
+		recording.Transcription,
+		recording.TranscriptionConfidence,
+		recording.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert recording: %w", err)
+	}
+
+	return nil
+}
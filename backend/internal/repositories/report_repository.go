@@ -0,0 +1,380 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"upload-lambda/internal/models"
+)
+
+// ReportRepository defines the interface for expense report data operations. Changes to the
+// reports' expenses are recorded in their audit history like any other expense change.
+type ReportRepository interface {
+	// Create creates a report claiming the given expenses, which must be pending reimbursable
+	// expenses of the report's user that no other report claims
+	Create(ctx context.Context, report *models.ExpenseReport, expenseIDs []string, actor string) error
+	// FindByID returns a report with its expenses and the transcriptions of their recordings
+	FindByID(ctx context.Context, id string) (*models.ExpenseReport, error)
+	// List returns a user's reports, without their expenses, latest first
+	List(ctx context.Context, userID string) ([]*models.ExpenseReport, error)
+	// AddExpenses adds expenses to a report that was not submitted, with the conditions of Create
+	AddExpenses(ctx context.Context, id string, expenseIDs []string, actor string) error
+	// RemoveExpense takes an expense out of a report that was not submitted
+	RemoveExpense(ctx context.Context, id string, expenseID string, actor string) error
+	// UpdateStatus moves a report and its expenses on to submitted or reimbursed, setting
+	// the report's submission or reimbursement time
+	UpdateStatus(ctx context.Context, id string, status string, actor string) error
+}
+
+// Report errors
+var (
+	ErrReportNotFound = errors.New("report not found")
+	// ErrExpenseNotReportable is returned when adding an expense that is not a pending
+	// reimbursable expense of the report's user, or is claimed by another report
+	ErrExpenseNotReportable = errors.New("expense cannot be added to the report")
+	// ErrExpenseNotInReport is returned when removing an expense the report does not claim
+	ErrExpenseNotInReport = errors.New("expense is not in the report")
+	// ErrReportStatus is returned when a report's status does not allow the change: reports
+	// only change before they are submitted, are only reimbursed once submitted, and are
+	// not submitted empty
+	ErrReportStatus = errors.New("report status does not allow the change")
+)
+
+// reportColumns lists the columns read by scanReport, in order
+const reportColumns = `r.id, r.user_id, r.title, r.submitted_at, r.reimbursed_at, r.created_at,
+	COALESCE((SELECT SUM(e.unit_price * e.quantity) FROM expenses e WHERE e.report_id = r.id AND e.deleted_at IS NULL), 0),
+	(SELECT COUNT(*) FROM expenses e WHERE e.report_id = r.id AND e.deleted_at IS NULL)`
+
+// scanReport scans a row selected with reportColumns
+func scanReport(row rowScanner) (*models.ExpenseReport, error) {
+	var report models.ExpenseReport
+	var submittedAt, reimbursedAt sql.NullTime
+	if err := row.Scan(
+		&report.ID,
+		&report.UserID,
+		&report.Title,
+		&submittedAt,
+		&reimbursedAt,
+		&report.CreatedAt,
+		&report.Total,
+		&report.Count,
+	); err != nil {
+		return nil, err
+	}
+	report.CreatedAt = report.CreatedAt.UTC()
+	report.Status = models.ReimbursementPending
+	if submittedAt.Valid {
+		submitted := submittedAt.Time.UTC()
+		report.SubmittedAt = &submitted
+		report.Status = models.ReimbursementSubmitted
+	}
+	if reimbursedAt.Valid {
+		reimbursed := reimbursedAt.Time.UTC()
+		report.ReimbursedAt = &reimbursed
+		report.Status = models.ReimbursementReimbursed
+	}
+	return &report, nil
+}
+
+type postgresReportRepo struct {
+	dbURL string
+}
+
+// NewPostgresReportRepository creates a new PostgreSQL expense report repository
+func NewPostgresReportRepository(dbURL string) ReportRepository {
+	return &postgresReportRepo{
+		dbURL: dbURL,
+	}
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *postgresReportRepo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresReportRepo) Create(ctx context.Context, report *models.ExpenseReport, expenseIDs []string, actor string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO expense_reports (id, user_id, title, created_at)
+			VALUES ($1, $2, $3, $4)
+		`, report.ID, report.UserID, report.Title, report.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert report: %w", err)
+		}
+		return addReportExpenses(ctx, tx, report, expenseIDs, actor)
+	})
+}
+
+func (r *postgresReportRepo) FindByID(ctx context.Context, id string) (*models.ExpenseReport, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT ` + reportColumns + `
+		FROM expense_reports r
+		WHERE r.id = $1
+	`
+
+	report, err := scanReport(db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report: %w", err)
+	}
+
+	expensesQuery := `
+		SELECT ` + expenseColumns + `,
+			COALESCE((SELECT transcription FROM recordings WHERE recordings.id = expenses.recording_id), '')
+		FROM expenses
+		WHERE report_id = $1 AND deleted_at IS NULL
+		ORDER BY purchased_at, id
+	`
+
+	rows, err := db.QueryContext(ctx, expensesQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report expenses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transcription string
+		expense, err := scanExpense(rows, &transcription)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		expense.Transcription = transcription
+		report.Expenses = append(report.Expenses, expense)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating report expenses: %w", err)
+	}
+
+	return report, nil
+}
+
+func (r *postgresReportRepo) List(ctx context.Context, userID string) ([]*models.ExpenseReport, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT ` + reportColumns + `
+		FROM expense_reports r
+		WHERE r.user_id = $1
+		ORDER BY r.created_at DESC, r.id DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*models.ExpenseReport{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+func (r *postgresReportRepo) AddExpenses(ctx context.Context, id string, expenseIDs []string, actor string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		report, err := lockReport(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if report.SubmittedAt != nil {
+			return fmt.Errorf("%w: report already submitted", ErrReportStatus)
+		}
+		return addReportExpenses(ctx, tx, report, expenseIDs, actor)
+	})
+}
+
+func (r *postgresReportRepo) RemoveExpense(ctx context.Context, id string, expenseID string, actor string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		report, err := lockReport(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if report.SubmittedAt != nil {
+			return fmt.Errorf("%w: report already submitted", ErrReportStatus)
+		}
+
+		_, err = changeInTx(ctx, tx, expenseID, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+			result, err := tx.ExecContext(ctx, `UPDATE expenses SET report_id = NULL WHERE id = $1 AND report_id = $2`, expenseID, id)
+			if err != nil {
+				return fmt.Errorf("failed to remove expense from report: %w", err)
+			}
+			return requireRow(result, ErrExpenseNotInReport)
+		})
+		if errors.Is(err, ErrExpenseNotFound) {
+			return ErrExpenseNotInReport
+		}
+		return err
+	})
+}
+
+func (r *postgresReportRepo) UpdateStatus(ctx context.Context, id string, status string, actor string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		report, err := lockReport(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		var column string
+		switch {
+		case status == models.ReimbursementSubmitted && report.SubmittedAt == nil:
+			column = "submitted_at"
+		case status == models.ReimbursementReimbursed && report.SubmittedAt != nil && report.ReimbursedAt == nil:
+			column = "reimbursed_at"
+		default:
+			return fmt.Errorf("%w: report is %s", ErrReportStatus, report.Status)
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM expenses WHERE report_id = $1 AND deleted_at IS NULL ORDER BY purchased_at, id`, id)
+		if err != nil {
+			return fmt.Errorf("failed to query report expenses: %w", err)
+		}
+		var expenseIDs []string
+		for rows.Next() {
+			var expenseID string
+			if err := rows.Scan(&expenseID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan report expense: %w", err)
+			}
+			expenseIDs = append(expenseIDs, expenseID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating report expenses: %w", err)
+		}
+		if len(expenseIDs) == 0 {
+			return fmt.Errorf("%w: report has no expenses", ErrReportStatus)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE expense_reports SET `+column+` = NOW() WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to update report status: %w", err)
+		}
+
+		for _, expenseID := range expenseIDs {
+			_, err := changeInTx(ctx, tx, expenseID, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, `UPDATE expenses SET reimbursement_status = $1 WHERE id = $2`, status, expenseID); err != nil {
+					return fmt.Errorf("failed to update reimbursement status: %w", err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lockReport reads a report, locking its row until the transaction ends so its status and
+// expenses do not change meanwhile
+func lockReport(ctx context.Context, tx *sql.Tx, id string) (*models.ExpenseReport, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM expense_reports r
+		WHERE r.id = $1
+		FOR UPDATE OF r
+	`
+
+	report, err := scanReport(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock report: %w", err)
+	}
+	return report, nil
+}
+
+// addReportExpenses makes a report claim expenses, failing with ErrExpenseNotReportable on the
+// first one that cannot be claimed
+func addReportExpenses(ctx context.Context, tx *sql.Tx, report *models.ExpenseReport, expenseIDs []string, actor string) error {
+	query := `
+		UPDATE expenses
+		SET report_id = $1
+		WHERE id = $2 AND user_id = $3 AND reimbursement_status = $4 AND report_id IS NULL
+	`
+
+	for _, expenseID := range expenseIDs {
+		_, err := changeInTx(ctx, tx, expenseID, models.ExpenseEventUpdate, actor, func(tx *sql.Tx) error {
+			result, err := tx.ExecContext(ctx, query, report.ID, expenseID, report.UserID, models.ReimbursementPending)
+			if err != nil {
+				return fmt.Errorf("failed to add expense to report: %w", err)
+			}
+			return requireRow(result, ErrExpenseNotReportable)
+		})
+		if errors.Is(err, ErrExpenseNotFound) || errors.Is(err, ErrExpenseNotReportable) {
+			return fmt.Errorf("%w: %s", ErrExpenseNotReportable, expenseID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireRow returns notFound if a statement affected no rows
+func requireRow(result sql.Result, notFound error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+)
+
+// testDatabase migrates the database of TEST_DATABASE_URL from scratch and returns its URL,
+// skipping the test without one. The database must be disposable: its public schema is dropped.
+func testDatabase(t *testing.T) string {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`DROP SCHEMA public CASCADE; CREATE SCHEMA public`); err != nil {
+		t.Fatalf("failed to reset schema: %v", err)
+	}
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatal(err)
+	}
+	if err := goose.Up(db, "../../migrations"); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return dbURL
+}
+
+// recordExpense stores an expense read from a recording with the given transcription
+func recordExpense(t *testing.T, expenses ExpenseRepository, recordings RecordingRepository, userID, transcription, description string) {
+	t.Helper()
+	ctx := context.Background()
+	recording := &models.Recording{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Language:      models.LanguageSpanish,
+		Transcription: transcription,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := recordings.Create(ctx, recording); err != nil {
+		t.Fatal(err)
+	}
+	expense := &models.Expense{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		UnitPrice:   1,
+		Quantity:    1,
+		Unit:        models.DefaultUnit,
+		Description: description,
+		PurchasedAt: time.Now().UTC(),
+		RecordingID: recording.ID,
+		Status:      models.ExpenseStatusConfirmed,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := expenses.Create(ctx, expense); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPostgresSearch runs the full-text search of GET /expenses?q= against PostgreSQL, whose
+// stemming, accent folding and excerpts the in-memory repository only approximates
+func TestPostgresSearch(t *testing.T) {
+	dbURL := testDatabase(t)
+	expenses := NewPostgresRepository(dbURL)
+	recordings := NewPostgresRecordingRepository(dbURL)
+
+	recordExpense(t, expenses, recordings, "sam", "Compré pilas para el control", "pilas AA")
+	recordExpense(t, expenses, recordings, "sam", "Un café", "café")
+	recordExpense(t, expenses, recordings, "sam", "Linterna para el viaje, ya tenía las pilas", "linterna")
+	recordExpense(t, expenses, recordings, "ana", "Pilas de repuesto", "pilas")
+
+	search := func(userID, q string) *models.PaginatedExpenses {
+		t.Helper()
+		result, err := expenses.List(context.Background(), models.ListExpensesParams{UserID: userID, Query: q, IncludeTotal: true})
+		if err != nil {
+			t.Fatalf("search %q: %v", q, err)
+		}
+		return result
+	}
+
+	// Matches in the description rank above matches only in the transcription
+	batteries := search("sam", "pila")
+	if len(batteries.Data) != 2 || batteries.Data[0].Description != "pilas AA" || batteries.Data[1].Description != "linterna" {
+		t.Fatalf("results for pila = %+v", batteries.Data)
+	}
+	best, other := batteries.Data[0].Match, batteries.Data[1].Match
+	if best == nil || other == nil || best.Rank <= other.Rank {
+		t.Fatalf("matches = %+v, %+v", best, other)
+	}
+	if best.Description != "<mark>pilas</mark> AA" {
+		t.Errorf("description excerpt = %q", best.Description)
+	}
+	if !strings.Contains(other.Transcription, "las <mark>pilas</mark>") {
+		t.Errorf("transcription excerpt = %q", other.Transcription)
+	}
+
+	// Accents are ignored
+	if coffee := search("sam", "cafe"); len(coffee.Data) != 1 || coffee.Data[0].Description != "café" {
+		t.Errorf("results for cafe = %+v", coffee.Data)
+	}
+	if none := search("sam", "pilas arroz"); len(none.Data) != 0 || *none.Total != 0 {
+		t.Errorf("results for pilas arroz = %+v", none.Data)
+	}
+	if theirs := search("ana", "pila"); len(theirs.Data) != 1 || theirs.Data[0].Description != "pilas" {
+		t.Errorf("ana's results for pila = %+v, want only hers", theirs.Data)
+	}
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"upload-lambda/internal/models"
+)
+
+// SettingsRepository defines the interface for user settings data operations
+type SettingsRepository interface {
+	// Get returns the settings for a user, or nil if the user has none stored
+	Get(ctx context.Context, userID string) (*models.UserSettings, error)
+	Upsert(ctx context.Context, settings *models.UserSettings) error
+}
+
+type postgresSettingsRepo struct {
+	dbURL string
+}
+
+// NewPostgresSettingsRepository creates a new PostgreSQL user settings repository
+func NewPostgresSettingsRepository(dbURL string) SettingsRepository {
+	return &postgresSettingsRepo{
+		dbURL: dbURL,
+	}
+}
+
+func (r *postgresSettingsRepo) Get(ctx context.Context, userID string) (*models.UserSettings, error) {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		SELECT user_id, language, timezone, updated_at
+		FROM user_settings
+		WHERE user_id = $1
+	`
+
+	var settings models.UserSettings
+	err = db.QueryRowContext(ctx, query, userID).Scan(
+		&settings.UserID,
+		&settings.Language,
+		&settings.Timezone,
+		&settings.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (r *postgresSettingsRepo) Upsert(ctx context.Context, settings *models.UserSettings) error {
+	db, err := sql.Open("postgres", r.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_settings (user_id, language, timezone, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET language = EXCLUDED.language, timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err = db.ExecContext(ctx, query,
+		settings.UserID,
+		settings.Language,
+		settings.Timezone,
+		settings.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save user settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"upload-lambda/internal/audio"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Ask errors
+var (
+	// ErrInvalidQuestion is returned when a question is empty or too long
+	ErrInvalidQuestion = errors.New("invalid question")
+	// ErrUnanswerableQuestion is returned when a question translates into a query outside
+	// what can be answered (see validateAskQuery)
+	ErrUnanswerableQuestion = errors.New("question cannot be answered")
+)
+
+// Bounds of questions and of the queries they translate into
+const (
+	maxQuestionLength = 500
+	maxSearchLength   = 200
+	maxReplyLength    = 300
+)
+
+// Reply placeholders
+const (
+	replyAnswer = "{answer}"
+	replyGroup  = "{group}"
+)
+
+// defaultReplies are used when the model's reply has no {answer} placeholder
+var defaultReplies = map[string]string{
+	models.LanguageSpanish:    "Resultado: {answer}",
+	models.LanguageEnglish:    "Result: {answer}",
+	models.LanguagePortuguese: "Resultado: {answer}",
+}
+
+// AskService defines the interface for answering questions about expenses
+type AskService interface {
+	// Ask answers a question about the user's expenses, typed or recorded. The model only
+	// translates the question; the answer is computed from the stored expenses. Expenses are
+	// found by full-text search on the model's search words: there is no semantic search.
+	Ask(ctx context.Context, params models.AskParams) (*models.AskAnswer, error)
+}
+
+type askService struct {
+	openaiRepo      repositories.OpenAIRepository
+	expenseService  ExpenseService
+	settingsService SettingsService
+	merchantService MerchantService
+	audioProcessor  *audio.Processor
+}
+
+// NewAskService creates a new ask service
+func NewAskService(
+	openaiRepo repositories.OpenAIRepository,
+	expenseService ExpenseService,
+	settingsService SettingsService,
+	merchantService MerchantService,
+	audioProcessor *audio.Processor,
+) AskService {
+	return &askService{
+		openaiRepo:      openaiRepo,
+		expenseService:  expenseService,
+		settingsService: settingsService,
+		merchantService: merchantService,
+		audioProcessor:  audioProcessor,
+	}
+}
+
+func (s *askService) Ask(ctx context.Context, params models.AskParams) (*models.AskAnswer, error) {
+	settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	questionLanguage := settings.Language
+
+	// Step 1: Transcribe a recorded question
+	question := strings.TrimSpace(params.Question)
+	if question == "" && params.AudioPath != "" {
+		prepared, err := s.audioProcessor.Prepare(ctx, params.AudioPath)
+		if err != nil {
+			log.Printf("Audio rejected: %v", err)
+			return nil, err
+		}
+		defer prepared.Cleanup()
+
+		transcription, err := s.openaiRepo.TranscribeAudio(ctx, prepared.Path)
+		if err != nil {
+			log.Printf("Transcription error: %v", err)
+			return nil, err
+		}
+		question = strings.TrimSpace(transcription.Text)
+		if models.IsSupportedLanguage(transcription.Language) {
+			questionLanguage = transcription.Language
+		}
+	}
+	if question == "" {
+		return nil, fmt.Errorf("%w: empty question", ErrInvalidQuestion)
+	}
+	if utf8.RuneCountInString(question) > maxQuestionLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidQuestion, maxQuestionLength)
+	}
+	log.Printf("Question from user %s (%s): %s", params.UserID, questionLanguage, question)
+
+	// Step 2: Translate it into a query, telling the model the merchants it may name
+	merchants, err := s.merchantService.ListMerchants(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	askContext := models.AskContext{
+		Language: questionLanguage,
+		Now:      time.Now().In(settings.Location()),
+	}
+	for _, merchant := range merchants {
+		askContext.Merchants = append(askContext.Merchants, merchant.Name)
+	}
+	translation, err := s.openaiRepo.TranslateQuestion(ctx, question, askContext)
+	if err != nil {
+		log.Printf("Failed to translate question: %v", err)
+		return nil, err
+	}
+
+	// Step 3: Only whitelisted queries reach the repository
+	summaryParams, err := validateAskQuery(translation.Query, merchants)
+	if err != nil {
+		log.Printf("Rejected query %+v for user %s: %v", translation.Query, params.UserID, err)
+		return nil, err
+	}
+	summaryParams.UserID = params.UserID
+	summary, err := s.expenseService.Summarize(ctx, summaryParams)
+	if errors.Is(err, ErrInvalidDateRange) {
+		return nil, fmt.Errorf("%w: %v", ErrUnanswerableQuestion, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: Aggregate, and put the number into the reply
+	answer := &models.AskAnswer{
+		Question:      question,
+		Query:         translation.Query,
+		PromptVersion: translation.PromptVersion,
+	}
+	aggregation := translation.Query.Aggregation
+	overall := models.SummaryGroup{Total: summary.Total, Count: summary.Count, Min: summary.Min, Max: summary.Max}
+	answer.Answer, answer.Count = aggregate(aggregation, overall), summary.Count
+	if summaryParams.GroupBy != "" {
+		answer.Group = topGroup(aggregation, summary.Groups)
+		if answer.Group != nil {
+			answer.Answer, answer.Count = aggregate(aggregation, *answer.Group), answer.Group.Count
+		}
+	}
+	answer.Reply = formatReply(translation.Reply, questionLanguage, aggregation, answer)
+
+	log.Printf("Answered question of user %s: %s = %v over %d expense(s)", params.UserID, aggregation, answer.Answer, answer.Count)
+	return answer, nil
+}
+
+// validateAskQuery checks a translated query against what can be answered and turns it into
+// summary parameters, resolving the merchant among the user's
+func validateAskQuery(query models.AskQuery, merchants []*models.Merchant) (models.SummaryParams, error) {
+	var params models.SummaryParams
+
+	if !slices.Contains(models.AskAggregations, query.Aggregation) {
+		return params, fmt.Errorf("%w: unsupported aggregation %q", ErrUnanswerableQuestion, query.Aggregation)
+	}
+	if query.GroupBy != "" && !slices.Contains(models.SummaryGroupings, query.GroupBy) {
+		return params, fmt.Errorf("%w: unsupported group_by %q", ErrUnanswerableQuestion, query.GroupBy)
+	}
+	params.GroupBy = query.GroupBy
+
+	for _, day := range []string{query.From, query.To} {
+		if day == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			return params, fmt.Errorf("%w: invalid day %q", ErrUnanswerableQuestion, day)
+		}
+	}
+	if query.From != "" && query.To != "" && query.From > query.To {
+		return params, fmt.Errorf("%w: from %s is after to %s", ErrUnanswerableQuestion, query.From, query.To)
+	}
+	params.FromDay, params.ToDay = query.From, query.To
+
+	if utf8.RuneCountInString(query.Search) > maxSearchLength {
+		return params, fmt.Errorf("%w: search longer than %d characters", ErrUnanswerableQuestion, maxSearchLength)
+	}
+	params.Query = strings.TrimSpace(query.Search)
+
+	for _, unit := range query.Units {
+		if !isKnownUnitInAnyLanguage(unit) {
+			return params, fmt.Errorf("%w: unknown unit %q", ErrUnanswerableQuestion, unit)
+		}
+	}
+	params.Units = query.Units
+
+	if name := normalizeMerchantName(query.Merchant); name != "" {
+		for _, merchant := range merchants {
+			if merchant.NormalizedName == name || slices.Contains(merchant.Aliases, name) {
+				params.MerchantID = merchant.ID
+				break
+			}
+		}
+		if params.MerchantID == "" {
+			return params, fmt.Errorf("%w: unknown merchant %q", ErrUnanswerableQuestion, query.Merchant)
+		}
+	}
+	return params, nil
+}
+
+// isKnownUnitInAnyLanguage reports whether a unit belongs to any language's vocabulary, since
+// stored expenses keep the unit of the language they were recorded in
+func isKnownUnitInAnyLanguage(unit string) bool {
+	for language := range models.LanguageUnits {
+		if models.IsKnownUnit(language, unit) {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregate computes an aggregation over the totals of a group of expenses
+func aggregate(aggregation string, group models.SummaryGroup) float64 {
+	switch aggregation {
+	case models.AskAggregationCount:
+		return float64(group.Count)
+	case models.AskAggregationAverage:
+		if group.Count == 0 {
+			return 0
+		}
+		return math.Round(group.Total/float64(group.Count)*100) / 100
+	case models.AskAggregationMax:
+		return group.Max
+	case models.AskAggregationMin:
+		return group.Min
+	}
+	return math.Round(group.Total*100) / 100
+}
+
+// topGroup returns the group with the largest aggregated value, or the smallest for min
+func topGroup(aggregation string, groups []models.SummaryGroup) *models.SummaryGroup {
+	var top *models.SummaryGroup
+	for i := range groups {
+		value := aggregate(aggregation, groups[i])
+		if top == nil {
+			top = &groups[i]
+			continue
+		}
+		best := aggregate(aggregation, *top)
+		if aggregation == models.AskAggregationMin && value < best || aggregation != models.AskAggregationMin && value > best {
+			top = &groups[i]
+		}
+	}
+	return top
+}
+
+// formatReply fills the model's reply template with the answer, formatted for the language
+func formatReply(reply string, lang string, aggregation string, answer *models.AskAnswer) string {
+	reply = strings.TrimSpace(reply)
+	if !strings.Contains(reply, replyAnswer) || utf8.RuneCountInString(reply) > maxReplyLength {
+		reply = defaultReplies[lang]
+		if reply == "" {
+			reply = defaultReplies[models.DefaultLanguage]
+		}
+	}
+
+	printer := message.NewPrinter(language.Make(lang))
+	value := printer.Sprintf("%.2f", answer.Answer)
+	if aggregation == models.AskAggregationCount {
+		value = printer.Sprintf("%d", answer.Count)
+	}
+	group := ""
+	if answer.Group != nil {
+		group = answer.Group.Label
+	}
+	return strings.NewReplacer(replyAnswer, value, replyGroup, group).Replace(reply)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+// Duplicate detection thresholds
+const (
+	// DuplicateWindow is how far apart two purchases of the same thing may be recorded
+	DuplicateWindow = 24 * time.Hour
+	// duplicateSimilarityThreshold is the minimum description similarity (0-1) of a duplicate
+	duplicateSimilarityThreshold = 0.5
+	// duplicateAmountTolerance is the relative difference allowed between the totals of a duplicate
+	duplicateAmountTolerance = 0.01
+)
+
+// findDuplicate returns the candidate most likely to record the same purchase as expense:
+// same total (within duplicateAmountTolerance), purchased within DuplicateWindow and with a
+// similar description. Returns nil if no candidate qualifies.
+func findDuplicate(expense *models.Expense, candidates []*models.Expense) *models.Expense {
+	var best *models.Expense
+	var bestSimilarity float64
+	var bestDistance time.Duration
+
+	for _, candidate := range candidates {
+		if candidate.ID == expense.ID || candidate.RecordingID != "" && candidate.RecordingID == expense.RecordingID {
+			continue
+		}
+		// Bank transactions are linked to recorded expenses by reconciliation instead
+		if candidate.Source == models.ExpenseSourceImport {
+			continue
+		}
+		if !sameAmount(candidate.Total(), expense.Total()) {
+			continue
+		}
+		distance := candidate.PurchasedAt.Sub(expense.PurchasedAt)
+		if distance < 0 {
+			distance = -distance
+		}
+		if distance > DuplicateWindow {
+			continue
+		}
+		similarity := descriptionSimilarity(candidate.Description, expense.Description)
+		if similarity < duplicateSimilarityThreshold {
+			continue
+		}
+
+		if best == nil || similarity > bestSimilarity || similarity == bestSimilarity && distance < bestDistance {
+			best, bestSimilarity, bestDistance = candidate, similarity, distance
+		}
+	}
+
+	return best
+}
+
+// sameAmount reports whether two totals are equal within duplicateAmountTolerance (and a cent)
+func sameAmount(a float64, b float64) bool {
+	tolerance := math.Max(0.01, duplicateAmountTolerance*math.Max(math.Abs(a), math.Abs(b)))
+	return math.Abs(a-b) <= tolerance
+}
+
+// descriptionSimilarity is the Dice coefficient of the character trigrams of two normalized
+// descriptions, so "pan" and "panes" or "arroz" and "arroz blanco" still match
+func descriptionSimilarity(a string, b string) float64 {
+	ta, tb := trigrams(normalizeText(a)), trigrams(normalizeText(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ta)+len(tb))
+}
+
+// trigrams returns the set of character trigrams of each word, padded like pg_trgm
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
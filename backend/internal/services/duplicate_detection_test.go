@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"pan", "panes", true},
+		{"arroz", "Arroz blanco", true},
+		{"plátanos", "platanos", true},
+		{"leche", "pan", false},
+		{"pasaje de bus", "leche", false},
+	}
+	for _, tt := range tests {
+		similar := descriptionSimilarity(tt.a, tt.b) >= duplicateSimilarityThreshold
+		if similar != tt.want {
+			t.Errorf("descriptionSimilarity(%q, %q) = %.2f, similar = %t, want %t", tt.a, tt.b, descriptionSimilarity(tt.a, tt.b), similar, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	at := time.Date(2026, 2, 21, 12, 0, 0, 0, time.UTC)
+	expense := &models.Expense{ID: "new", RecordingID: "r2", UnitPrice: 1, Quantity: 3, Description: "pan", PurchasedAt: at}
+
+	candidates := []*models.Expense{
+		{ID: "other-amount", UnitPrice: 2, Quantity: 3, Description: "pan", PurchasedAt: at},
+		{ID: "too-old", UnitPrice: 1, Quantity: 3, Description: "pan", PurchasedAt: at.Add(-48 * time.Hour)},
+		{ID: "same-recording", RecordingID: "r2", UnitPrice: 1, Quantity: 3, Description: "pan", PurchasedAt: at},
+		{ID: "match", RecordingID: "r1", UnitPrice: 1.5, Quantity: 2, Description: "panes", PurchasedAt: at.Add(-3 * time.Hour)},
+	}
+
+	duplicate := findDuplicate(expense, candidates)
+	if duplicate == nil || duplicate.ID != "match" {
+		t.Fatalf("findDuplicate = %+v, want match", duplicate)
+	}
+}
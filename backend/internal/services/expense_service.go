@@ -2,8 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+	"upload-lambda/internal/audio"
 	"upload-lambda/internal/models"
 	"upload-lambda/internal/repositories"
 
@@ -12,68 +18,342 @@ import (
 
 // ExpenseService defines the interface for expense business logic
 type ExpenseService interface {
-	ProcessAudioExpense(ctx context.Context, audioPath string, purchasedAt time.Time) ([]*models.Expense, error)
+	// ProcessAudioExpense transcribes a recording and, depending on its intent, records the
+	// expenses it describes or corrects or deletes one of the user's recent expenses
+	ProcessAudioExpense(ctx context.Context, params models.ProcessAudioParams) (*models.VoiceCommandResult, error)
+	ProcessAudioBatch(ctx context.Context, batch []models.ProcessAudioParams) []BatchResult
+	ProcessReceiptExpense(ctx context.Context, params models.ProcessReceiptParams) ([]*models.Expense, error)
+	// ImportStatement creates expenses from the charges of a bank statement (CSV or OFX),
+	// skipping transactions imported before
+	ImportStatement(ctx context.Context, params models.ImportStatementParams) (*models.ImportResult, error)
+	// GetReceipt returns a receipt of the user including its image
+	GetReceipt(ctx context.Context, id string, userID string) (*models.Receipt, error)
 	ListExpenses(ctx context.Context, params models.ListExpensesParams) (*models.PaginatedExpenses, error)
+	// ExportExpenses calls fn for every expense matching the list filters, ignoring pagination,
+	// with times in the user's timezone. An error returned by fn stops the export.
+	ExportExpenses(ctx context.Context, params models.ListExpensesParams, fn func(*models.Expense) error) error
+	// ConfirmExpense marks an expense as confirmed. actor is the user making the change, recorded
+	// in the expense's history like in every method below that changes an expense.
+	ConfirmExpense(ctx context.Context, id string, actor string) (*models.Expense, error)
+	// UpdateExpense changes the unit price, quantity, unit or description of an expense
+	UpdateExpense(ctx context.Context, id string, update models.ExpenseUpdate, actor string) (*models.Expense, error)
+	// DeleteExpense soft-deletes an expense; it can be restored with RestoreExpense
+	DeleteExpense(ctx context.Context, id string, actor string) (*models.Expense, error)
+	// RestoreExpense undoes the deletion of an expense
+	RestoreExpense(ctx context.Context, id string, actor string) (*models.Expense, error)
+	// ExpenseHistory returns the audit history of an expense of the user, deleted or not, oldest first
+	ExpenseHistory(ctx context.Context, id string, userID string) ([]*models.ExpenseEvent, error)
+	// SetReimbursable flags an expense as reimbursable, pending its claim, or unflags it. Expenses
+	// of submitted reports cannot be changed.
+	SetReimbursable(ctx context.Context, id string, reimbursable bool, actor string) (*models.Expense, error)
+	// TagExpense adds tags to an expense. Tags are normalized: "#Trip Cusco" is "trip-cusco".
+	TagExpense(ctx context.Context, id string, tags []string, actor string) (*models.Expense, error)
+	// UntagExpense removes a tag from an expense
+	UntagExpense(ctx context.Context, id string, tag string, actor string) (*models.Expense, error)
+	// MergeDuplicate deletes an expense flagged as a duplicate and returns the expense it duplicates
+	MergeDuplicate(ctx context.Context, id string, actor string) (*models.Expense, error)
+	// DismissDuplicate clears the duplicate flag of an expense, keeping both expenses
+	DismissDuplicate(ctx context.Context, id string, actor string) (*models.Expense, error)
+	// Summarize totals a user's expenses, optionally grouped (see models.SummaryGroupByMerchant)
+	Summarize(ctx context.Context, params models.SummaryParams) (*models.ExpenseSummary, error)
+}
+
+// Listing and summary errors
+var (
+	// ErrUnsupportedGrouping is returned when summarizing by an unknown group_by
+	ErrUnsupportedGrouping = errors.New("unsupported group_by")
+	// ErrInvalidDateRange is returned when a day of a date range is not a valid YYYY-MM-DD date
+	ErrInvalidDateRange = errors.New("invalid date range")
+)
+
+// ErrNotFlaggedAsDuplicate is returned when merging or dismissing an expense that is not flagged as a duplicate
+var ErrNotFlaggedAsDuplicate = errors.New("expense is not flagged as a possible duplicate")
+
+// ReviewConfidenceThreshold is the confidence below which extracted expenses need review
+const ReviewConfidenceThreshold = 0.7
+
+// batchWorkers bounds how many recordings of a batch are processed at once,
+// keeping concurrent Whisper and GPT calls under the account's rate limits
+const batchWorkers = 3
+
+// BatchResult is the outcome of one recording of a batch, in the order it was submitted
+type BatchResult struct {
+	Result *models.VoiceCommandResult
+	Err    error
 }
 
 type expenseService struct {
-	openaiRepo  repositories.OpenAIRepository
-	expenseRepo repositories.ExpenseRepository
+	openaiRepo      repositories.OpenAIRepository
+	expenseRepo     repositories.ExpenseRepository
+	recordingRepo   repositories.RecordingRepository
+	receiptRepo     repositories.ReceiptRepository
+	ocrRepo         repositories.OCRRepository
+	settingsService SettingsService
+	merchantService MerchantService
+	audioProcessor  *audio.Processor
 }
 
 // NewExpenseService creates a new expense service
 func NewExpenseService(
 	openaiRepo repositories.OpenAIRepository,
 	expenseRepo repositories.ExpenseRepository,
+	recordingRepo repositories.RecordingRepository,
+	receiptRepo repositories.ReceiptRepository,
+	ocrRepo repositories.OCRRepository,
+	settingsService SettingsService,
+	merchantService MerchantService,
+	audioProcessor *audio.Processor,
 ) ExpenseService {
 	return &expenseService{
-		openaiRepo:  openaiRepo,
-		expenseRepo: expenseRepo,
+		openaiRepo:      openaiRepo,
+		expenseRepo:     expenseRepo,
+		recordingRepo:   recordingRepo,
+		receiptRepo:     receiptRepo,
+		ocrRepo:         ocrRepo,
+		settingsService: settingsService,
+		merchantService: merchantService,
+		audioProcessor:  audioProcessor,
 	}
 }
 
-func (s *expenseService) ProcessAudioExpense(ctx context.Context, audioPath string, purchasedAt time.Time) ([]*models.Expense, error) {
+func (s *expenseService) ProcessAudioExpense(ctx context.Context, params models.ProcessAudioParams) (*models.VoiceCommandResult, error) {
+	// Step 0: Validate (and transcode if needed) before spending a Whisper call
+	prepared, err := s.audioProcessor.Prepare(ctx, params.AudioPath)
+	if err != nil {
+		log.Printf("Audio rejected: %v", err)
+		return nil, err
+	}
+	defer prepared.Cleanup()
+	log.Printf("Audio accepted: format=%s, duration=%s, transcoded=%t", prepared.Format, prepared.Duration, prepared.Transcoded)
+
 	// Step 1: Transcribe audio
-	log.Printf("Transcribing audio: %s", audioPath)
-	transcription, err := s.openaiRepo.TranscribeAudio(ctx, audioPath)
+	log.Printf("Transcribing audio: %s", prepared.Path)
+	transcription, err := s.openaiRepo.TranscribeAudio(ctx, prepared.Path)
 	if err != nil {
 		log.Printf("Transcription error: %v", err)
 		return nil, err
 	}
-	log.Printf("Transcription: %s", transcription)
+	log.Printf("Transcription (%s): %s", transcription.Language, transcription.Text)
+
+	// Step 2: Resolve language (detected, then user default)
+	language, err := s.resolveLanguage(ctx, params.UserID, transcription.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	// The recording is only saved once its content was accepted, so rejected uploads leave nothing behind
+	recording := &models.Recording{
+		ID:                      uuid.New().String(),
+		UserID:                  params.UserID,
+		ClientRecordingID:       params.ClientRecordingID,
+		Language:                language,
+		Transcription:           transcription.Text,
+		TranscriptionConfidence: transcription.Confidence,
+		CreatedAt:               time.Now().UTC(),
+	}
 
-	// Step 2: Extract expense data (may be multiple expenses)
-	log.Printf("Extracting expense data from transcription")
-	expensesData, err := s.openaiRepo.ExtractExpenseData(ctx, transcription)
+	// Step 3: Interpret the transcription (may be multiple expenses, or a correction), with the
+	// recording time in the user's timezone so spoken dates like "ayer" can be resolved
+	location := params.Location
+	if location == nil {
+		settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+		if err != nil {
+			return nil, err
+		}
+		location = settings.Location()
+	}
+	reference := params.PurchasedAt.In(location)
+	log.Printf("Interpreting transcription (reference %s)", reference.Format(time.RFC3339))
+	command, err := s.openaiRepo.InterpretTranscription(ctx, transcription.Text, language, reference)
 	if err != nil {
 		log.Printf("Extraction error: %v", err)
 		return nil, err
 	}
-	log.Printf("Extracted %d expense(s)", len(expensesData))
+	log.Printf("Intent %s with %d expense(s)", command.Intent, len(command.Expenses))
+
+	result := &models.VoiceCommandResult{
+		Intent:        command.Intent,
+		Transcription: transcription.Text,
+		RecordingID:   recording.ID,
+		PromptVersion: command.PromptVersion,
+	}
+	switch command.Intent {
+	case models.VoiceIntentCorrect, models.VoiceIntentDelete:
+		result.Changes, err = s.applyVoiceCommand(ctx, params.UserID, command)
+		if err != nil {
+			log.Printf("Rejected %s command of recording %s: %v", command.Intent, recording.ID, err)
+			return nil, err
+		}
+		if err := s.saveRecording(ctx, recording); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case models.VoiceIntentQuery:
+		// Questions are answered by the ask service; the app sends the transcription there
+		if err := s.saveRecording(ctx, recording); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case models.VoiceIntentAdd:
+		// Recorded below
+	default:
+		return nil, fmt.Errorf("%w: unknown intent %q", ErrSuspiciousExtraction, command.Intent)
+	}
+	expensesData := command.Expenses
+
+	// Reject out-of-bounds model output instead of persisting it
+	if err := validateExtraction(expensesData, language); err != nil {
+		log.Printf("Rejected extraction for recording %s: %v", recording.ID, err)
+		return nil, err
+	}
+
+	// Spoken dates override the upload's purchased_at, per expense; unusable ones are left for review
+	purchaseDates, dateProblems := resolvePurchaseDates(expensesData, reference, params.PurchasedAt)
+	unresolvedDates := make([]bool, len(dateProblems))
+	for i, problem := range dateProblems {
+		if problem != nil {
+			log.Printf("Expense %d of recording %s keeps the upload time for review: %v", i+1, recording.ID, problem)
+			unresolvedDates[i] = true
+		}
+	}
+
+	if err := s.saveRecording(ctx, recording); err != nil {
+		return nil, err
+	}
+
+	// Step 4: Create and save each expense
+	result.Expenses, err = s.saveExpenses(ctx, expenseSource{
+		UserID:          params.UserID,
+		PurchasedAt:     params.PurchasedAt,
+		PurchaseDates:   purchaseDates,
+		UnresolvedDates: unresolvedDates,
+		RecordingID:     recording.ID,
+		Confidence:      transcription.Confidence,
+	}, expensesData)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveRecording stores an accepted recording with its transcription
+func (s *expenseService) saveRecording(ctx context.Context, recording *models.Recording) error {
+	log.Printf("Saving recording to database: %s (language=%s)", recording.ID, recording.Language)
+	if err := s.recordingRepo.Create(ctx, recording); err != nil {
+		log.Printf("Database error for recording %s: %v", recording.ID, err)
+		return err
+	}
+	return nil
+}
+
+// expenseSource is what extracted expenses were read from
+type expenseSource struct {
+	UserID      string
+	PurchasedAt time.Time
+	// PurchaseDates are the purchase times of each expense, if they differ from PurchasedAt
+	PurchaseDates []time.Time
+	// UnresolvedDates marks the expenses whose spoken date was unusable, which are reviewed
+	UnresolvedDates []bool
+	RecordingID     string
+	ReceiptID       string
+	// Confidence of the transcription or OCR, nil if unknown
+	Confidence *float64
+}
+
+// saveExpenses creates the expenses of validated extraction output, setting their review
+// status, linking them to merchants and flagging likely duplicates of the user's recent expenses
+func (s *expenseService) saveExpenses(ctx context.Context, source expenseSource, expensesData []models.ExpenseData) ([]*models.Expense, error) {
+	// Recent expenses of the user to check the new ones against; detection failures don't block the upload
+	purchaseDates := source.PurchaseDates
+	if purchaseDates == nil {
+		purchaseDates = make([]time.Time, len(expensesData))
+		for i := range purchaseDates {
+			purchaseDates[i] = source.PurchasedAt
+		}
+	}
+	earliest, latest := purchaseDates[0], purchaseDates[0]
+	for _, purchasedAt := range purchaseDates {
+		if purchasedAt.Before(earliest) {
+			earliest = purchasedAt
+		}
+		if purchasedAt.After(latest) {
+			latest = purchasedAt
+		}
+	}
+
+	candidates, err := s.expenseRepo.FindRecent(ctx, source.UserID, earliest.Add(-DuplicateWindow), latest.Add(DuplicateWindow))
+	if err != nil {
+		log.Printf("Skipping duplicate detection: %v", err)
+		candidates = nil
+	}
+
+	origin := models.ExpenseSourceVoice
+	if source.ReceiptID != "" {
+		origin = models.ExpenseSourceReceipt
+	}
+
+	// Expenses of one recording or receipt usually share a merchant
+	merchants := make(map[string]*models.Merchant)
 
-	// Step 3: Create and save each expense
 	var expenses []*models.Expense
 	for i, data := range expensesData {
 		// Default unit to "u" if not specified
 		unit := data.Unit
 		if unit == "" {
-			unit = "u"
+			unit = models.DefaultUnit
+		}
+
+		// Expenses of unknown confidence or purchase date are reviewed too
+		confidence := expenseConfidence(data.Confidence, source.Confidence)
+		status := models.ExpenseStatusConfirmed
+		if confidence == nil || *confidence < ReviewConfidenceThreshold || (i < len(source.UnresolvedDates) && source.UnresolvedDates[i]) {
+			status = models.ExpenseStatusNeedsReview
 		}
 
-		log.Printf("Processing expense %d/%d: unit_price=%.2f, quantity=%.2f, unit=%s, description=%s",
-			i+1, len(expensesData), data.UnitPrice, data.Quantity, unit, data.Description)
+		log.Printf("Processing expense %d/%d: unit_price=%.2f, quantity=%.2f, unit=%s, description=%s, merchant=%s, prompt=%s, confidence=%s",
+			i+1, len(expensesData), data.UnitPrice, data.Quantity, unit, data.Description, data.Merchant, data.PromptVersion, formatConfidence(confidence))
 
 		expense := &models.Expense{
-			ID:          uuid.New().String(),
-			UnitPrice:   data.UnitPrice,
-			Quantity:    data.Quantity,
-			Unit:        unit,
-			Description: data.Description,
-			PurchasedAt: purchasedAt,
-			CreatedAt:   time.Now().UTC(),
+			ID:            uuid.New().String(),
+			UserID:        source.UserID,
+			UnitPrice:     data.UnitPrice,
+			Quantity:      data.Quantity,
+			Unit:          unit,
+			Description:   strings.TrimSpace(data.Description),
+			PurchasedAt:   purchaseDates[i],
+			RecordingID:   source.RecordingID,
+			ReceiptID:     source.ReceiptID,
+			Source:        origin,
+			PromptVersion: data.PromptVersion,
+			Confidence:    confidence,
+			Status:        status,
+			Tags:          normalizeTags(data.Tags),
+			CreatedAt:     time.Now().UTC(),
+		}
+
+		if name := strings.TrimSpace(data.Merchant); name != "" {
+			merchant, cached := merchants[name]
+			if !cached {
+				// Like duplicate detection, a failed lookup leaves the expense unlinked instead of failing the upload
+				merchant, err = s.merchantService.Resolve(ctx, source.UserID, name)
+				if err != nil {
+					log.Printf("Skipping merchant %q: %v", name, err)
+					merchant = nil
+				}
+				merchants[name] = merchant
+			}
+			if merchant != nil {
+				expense.MerchantID = &merchant.ID
+				expense.Merchant = merchant.Name
+			}
+		}
+
+		if duplicate := findDuplicate(expense, candidates); duplicate != nil {
+			log.Printf("Expense %s looks like a duplicate of %s (%s)", expense.ID, duplicate.ID, duplicate.Description)
+			expense.PossibleDuplicateOf = &duplicate.ID
 		}
 
-		// Step 4: Save to database
+		// Step 5: Save to database
 		log.Printf("Saving expense to database: %s", expense.ID)
 		err = s.expenseRepo.Create(ctx, expense)
 		if err != nil {
@@ -89,9 +369,84 @@ func (s *expenseService) ProcessAudioExpense(ctx context.Context, audioPath stri
 	return expenses, nil
 }
 
+// ProcessAudioBatch processes several recordings concurrently with at most batchWorkers
+// in flight. A failing recording does not affect the others; each gets its own result.
+func (s *expenseService) ProcessAudioBatch(ctx context.Context, batch []models.ProcessAudioParams) []BatchResult {
+	log.Printf("Processing batch of %d recording(s) with %d worker(s)", len(batch), batchWorkers)
+
+	results := make([]BatchResult, len(batch))
+	sem := make(chan struct{}, batchWorkers)
+	var wg sync.WaitGroup
+	for i, params := range batch {
+		wg.Add(1)
+		go func(i int, params models.ProcessAudioParams) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := s.ProcessAudioExpense(ctx, params)
+			if err != nil {
+				log.Printf("Batch recording %d/%d (client id %q) failed: %v", i+1, len(batch), params.ClientRecordingID, err)
+			}
+			results[i] = BatchResult{Result: result, Err: err}
+		}(i, params)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// expenseConfidence combines the model's confidence with the transcription confidence,
+// keeping the lower of the two. It is nil (unknown) when neither is known.
+func expenseConfidence(extraction *float64, transcription *float64) *float64 {
+	lowest := extraction
+	if lowest == nil || (transcription != nil && *transcription < *lowest) {
+		lowest = transcription
+	}
+	if lowest == nil {
+		return nil
+	}
+	confidence := *lowest
+	return &confidence
+}
+
+// formatConfidence formats a confidence for the logs
+func formatConfidence(confidence *float64) string {
+	if confidence == nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.2f", *confidence)
+}
+
+// resolveLanguage returns the detected language if supported, otherwise the user's default language
+func (s *expenseService) resolveLanguage(ctx context.Context, userID string, detected string) (string, error) {
+	if models.IsSupportedLanguage(detected) {
+		return detected, nil
+	}
+
+	settings, err := s.settingsService.GetSettings(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Detected language %q not supported, using default %q for user %s", detected, settings.Language, userID)
+	return settings.Language, nil
+}
+
 func (s *expenseService) ListExpenses(ctx context.Context, params models.ListExpensesParams) (*models.PaginatedExpenses, error) {
-	log.Printf("Listing expenses: page=%d, per_page=%d, order_by=%s, order_dir=%s",
-		params.Page, params.PerPage, params.OrderBy, params.OrderDir)
+	log.Printf("Listing expenses: page=%d, cursor=%t, per_page=%d, order_by=%s, order_dir=%s",
+		params.Page, params.Cursor != "", params.PerPage, params.OrderBy, params.OrderDir)
+	params.Tags = normalizeTags(params.Tags)
+
+	// Only whole-day filters need the user's timezone
+	if params.FromDay != "" || params.ToDay != "" {
+		settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveDays(&params.DateRange, settings.Location()); err != nil {
+			return nil, err
+		}
+	}
 
 	result, err := s.expenseRepo.List(ctx, params)
 	if err != nil {
@@ -99,6 +454,247 @@ func (s *expenseService) ListExpenses(ctx context.Context, params models.ListExp
 		return nil, err
 	}
 
-	log.Printf("Retrieved %d expenses (page %d of %d)", len(result.Data), result.Page, result.TotalPages)
+	log.Printf("Retrieved %d expenses (page %d, more: %t)", len(result.Data), result.Page, result.NextCursor != "")
 	return result, nil
 }
+
+func (s *expenseService) ExportExpenses(ctx context.Context, params models.ListExpensesParams, fn func(*models.Expense) error) error {
+	settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+	if err != nil {
+		return err
+	}
+	location := settings.Location()
+	if err := resolveDays(&params.DateRange, location); err != nil {
+		return err
+	}
+	params.Tags = normalizeTags(params.Tags)
+
+	log.Printf("Exporting expenses for user %s: order_by=%s, order_dir=%s, timezone=%s",
+		params.UserID, params.OrderBy, params.OrderDir, location)
+
+	exported := 0
+	err = s.expenseRepo.Export(ctx, params, func(expense *models.Expense) error {
+		expense.PurchasedAt = expense.PurchasedAt.In(location)
+		expense.CreatedAt = expense.CreatedAt.In(location)
+		exported++
+		return fn(expense)
+	})
+	if err != nil {
+		log.Printf("Failed to export expenses after %d rows: %v", exported, err)
+		return err
+	}
+
+	log.Printf("Exported %d expenses", exported)
+	return nil
+}
+
+func (s *expenseService) ConfirmExpense(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	log.Printf("Confirming expense: %s", id)
+
+	if _, err := s.findOwnExpense(ctx, id, actor); err != nil {
+		return nil, err
+	}
+	if err := s.expenseRepo.UpdateStatus(ctx, id, models.ExpenseStatusConfirmed, actor); err != nil {
+		log.Printf("Failed to confirm expense %s: %v", id, err)
+		return nil, err
+	}
+
+	return s.expenseRepo.FindByID(ctx, id)
+}
+
+func (s *expenseService) UpdateExpense(ctx context.Context, id string, update models.ExpenseUpdate, actor string) (*models.Expense, error) {
+	log.Printf("Updating expense: %s", id)
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyExpenseUpdate(expense, update); err != nil {
+		return nil, err
+	}
+
+	if err := s.expenseRepo.Update(ctx, expense, actor); err != nil {
+		log.Printf("Failed to update expense %s: %v", id, err)
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *expenseService) SetReimbursable(ctx context.Context, id string, reimbursable bool, actor string) (*models.Expense, error) {
+	log.Printf("Setting reimbursable=%t on expense: %s", reimbursable, id)
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if expense.Reimbursable == reimbursable {
+		return expense, nil
+	}
+
+	if err := s.expenseRepo.UpdateReimbursable(ctx, id, reimbursable, actor); err != nil {
+		log.Printf("Failed to set reimbursable on expense %s: %v", id, err)
+		return nil, err
+	}
+
+	return s.expenseRepo.FindByID(ctx, id)
+}
+
+func (s *expenseService) DeleteExpense(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	log.Printf("Deleting expense: %s", id)
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.expenseRepo.Delete(ctx, id, actor); err != nil {
+		log.Printf("Failed to delete expense %s: %v", id, err)
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *expenseService) RestoreExpense(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	log.Printf("Restoring expense: %s", id)
+
+	if _, err := s.findOwnExpenseWithDeleted(ctx, id, actor); err != nil {
+		return nil, err
+	}
+	expense, err := s.expenseRepo.Restore(ctx, id, actor)
+	if err != nil {
+		log.Printf("Failed to restore expense %s: %v", id, err)
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *expenseService) ExpenseHistory(ctx context.Context, id string, userID string) ([]*models.ExpenseEvent, error) {
+	if _, err := s.findOwnExpenseWithDeleted(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.expenseRepo.History(ctx, id)
+}
+
+func (s *expenseService) MergeDuplicate(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	log.Printf("Merging duplicate expense: %s", id)
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if expense.PossibleDuplicateOf == nil {
+		return nil, ErrNotFlaggedAsDuplicate
+	}
+
+	original, err := s.findOwnExpense(ctx, *expense.PossibleDuplicateOf, actor)
+	if err != nil {
+		log.Printf("Failed to find original %s of duplicate expense %s: %v", *expense.PossibleDuplicateOf, id, err)
+		return nil, err
+	}
+
+	if err := s.expenseRepo.Delete(ctx, id, actor); err != nil {
+		log.Printf("Failed to delete duplicate expense %s: %v", id, err)
+		return nil, err
+	}
+
+	log.Printf("Merged duplicate expense %s into %s", id, original.ID)
+	return original, nil
+}
+
+func (s *expenseService) DismissDuplicate(ctx context.Context, id string, actor string) (*models.Expense, error) {
+	log.Printf("Dismissing duplicate flag of expense: %s", id)
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if expense.PossibleDuplicateOf == nil {
+		return nil, ErrNotFlaggedAsDuplicate
+	}
+	if _, err := s.findOwnExpense(ctx, *expense.PossibleDuplicateOf, actor); err != nil {
+		return nil, err
+	}
+
+	if err := s.expenseRepo.UpdatePossibleDuplicate(ctx, id, nil, actor); err != nil {
+		log.Printf("Failed to dismiss duplicate flag of expense %s: %v", id, err)
+		return nil, err
+	}
+
+	expense.PossibleDuplicateOf = nil
+	return expense, nil
+}
+
+// findOwnExpense finds an expense of the given user; other users' expenses are not visible
+func (s *expenseService) findOwnExpense(ctx context.Context, id string, userID string) (*models.Expense, error) {
+	expense, err := s.expenseRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if expense.UserID != userID {
+		return nil, repositories.ErrExpenseNotFound
+	}
+	return expense, nil
+}
+
+// findOwnExpenseWithDeleted is findOwnExpense including deleted expenses
+func (s *expenseService) findOwnExpenseWithDeleted(ctx context.Context, id string, userID string) (*models.Expense, error) {
+	expense, err := s.expenseRepo.FindByIDWithDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if expense.UserID != userID {
+		return nil, repositories.ErrExpenseNotFound
+	}
+	return expense, nil
+}
+
+func (s *expenseService) Summarize(ctx context.Context, params models.SummaryParams) (*models.ExpenseSummary, error) {
+	if params.GroupBy != "" && !slices.Contains(models.SummaryGroupings, params.GroupBy) {
+		return nil, fmt.Errorf("%w: %q (supported: %s)", ErrUnsupportedGrouping, params.GroupBy, strings.Join(models.SummaryGroupings, ", "))
+	}
+
+	// Days are bucketed and bounded in the user's timezone
+	settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	location := settings.Location()
+	params.Timezone = location.String()
+	if err := resolveDays(&params.DateRange, location); err != nil {
+		return nil, err
+	}
+	params.Tags = normalizeTags(params.Tags)
+
+	log.Printf("Summarizing expenses for user %s: group_by=%s, timezone=%s", params.UserID, params.GroupBy, params.Timezone)
+	summary, err := s.expenseRepo.Summarize(ctx, params)
+	if err != nil {
+		log.Printf("Failed to summarize expenses for user %s: %v", params.UserID, err)
+		return nil, err
+	}
+	return summary, nil
+}
+
+// resolveDays sets the bounds of a range from its whole days in location: FromDay starts at
+// its local midnight and ToDay ends at the last instant before the next local midnight
+func resolveDays(dateRange *models.DateRange, location *time.Location) error {
+	if dateRange.FromDay != "" {
+		from, err := time.ParseInLocation(time.DateOnly, dateRange.FromDay, location)
+		if err != nil {
+			return fmt.Errorf("%w: from %q", ErrInvalidDateRange, dateRange.FromDay)
+		}
+		dateRange.From = &from
+	}
+	if dateRange.ToDay != "" {
+		day, err := time.ParseInLocation(time.DateOnly, dateRange.ToDay, location)
+		if err != nil {
+			return fmt.Errorf("%w: to %q", ErrInvalidDateRange, dateRange.ToDay)
+		}
+		// PostgreSQL timestamps have microsecond resolution
+		to := day.AddDate(0, 0, 1).Add(-time.Microsecond)
+		dateRange.To = &to
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"unicode/utf8"
+	"upload-lambda/internal/models"
+)
+
+// Tag errors
+var (
+	// ErrInvalidTags is returned when tags are empty, too long or too many for an expense
+	ErrInvalidTags = errors.New("invalid tags")
+	// ErrTagNotFound is returned when removing a tag the expense does not have
+	ErrTagNotFound = errors.New("expense does not have the tag")
+)
+
+// normalizeTag turns a typed or spoken tag into its stored form, so "#Trip Cusco" and
+// "trip-cusco" are the same tag: lowercase, accents folded, words joined by hyphens
+func normalizeTag(tag string) string {
+	return strings.ReplaceAll(normalizeText(tag), " ", "-")
+}
+
+// normalizeTags normalizes tags, dropping empty and repeated ones, and sorts them like the
+// repository returns them
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized
+}
+
+// tagProblems describes the normalized tags of an expense that are out of bounds
+func tagProblems(tags []string) []string {
+	var problems []string
+	if len(tags) > maxTagsPerExpense {
+		problems = append(problems, fmt.Sprintf("%d tags (max %d)", len(tags), maxTagsPerExpense))
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			problems = append(problems, fmt.Sprintf("tag %q longer than %d characters", tag, maxTagLength))
+		}
+	}
+	return problems
+}
+
+func (s *expenseService) TagExpense(ctx context.Context, id string, tags []string, actor string) (*models.Expense, error) {
+	log.Printf("Tagging expense %s: %v", id, tags)
+
+	tags = normalizeTags(tags)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%w: no tags given", ErrInvalidTags)
+	}
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	added := slices.DeleteFunc(tags, func(tag string) bool { return slices.Contains(expense.Tags, tag) })
+	if problems := tagProblems(append(slices.Clone(expense.Tags), added...)); len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTags, strings.Join(problems, "; "))
+	}
+	// Tagging with tags the expense already has changes nothing, and records nothing
+	if len(added) == 0 {
+		return expense, nil
+	}
+
+	expense, err = s.expenseRepo.AddTags(ctx, id, added, actor)
+	if err != nil {
+		log.Printf("Failed to tag expense %s: %v", id, err)
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *expenseService) UntagExpense(ctx context.Context, id string, tag string, actor string) (*models.Expense, error) {
+	log.Printf("Removing tag %q from expense %s", tag, id)
+
+	expense, err := s.findOwnExpense(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	tag = normalizeTag(tag)
+	if !slices.Contains(expense.Tags, tag) {
+		return nil, fmt.Errorf("%w: %q", ErrTagNotFound, tag)
+	}
+
+	expense, err = s.expenseRepo.RemoveTag(ctx, id, tag, actor)
+	if err != nil {
+		log.Printf("Failed to remove tag %q from expense %s: %v", tag, id, err)
+		return nil, err
+	}
+
+	return expense, nil
+}
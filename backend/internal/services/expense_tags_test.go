@@ -0,0 +1,32 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"viaje", "viaje"},
+		{"#Trip Cusco", "trip-cusco"},
+		{"trip-cusco", "trip-cusco"},
+		{"  Cumpleaños de Ana ", "cumpleanos-de-ana"},
+		{"trabajo_2026", "trabajo-2026"},
+		{"#", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeTag(tt.tag); got != tt.want {
+			t.Errorf("normalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTagsDropsEmptyAndRepeatedTags(t *testing.T) {
+	got := normalizeTags([]string{"Viaje", "#", "trabajo", "viaje", " "})
+	if want := []string{"trabajo", "viaje"}; !slices.Equal(got, want) {
+		t.Errorf("normalizeTags = %v, want %v", got, want)
+	}
+}
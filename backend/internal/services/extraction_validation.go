@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+	"upload-lambda/internal/models"
+)
+
+// ErrSuspiciousExtraction is returned when the model output falls outside the accepted bounds
+var ErrSuspiciousExtraction = errors.New("suspicious extraction")
+
+// Bounds for extracted expense data
+const (
+	maxExpensesPerRecording = 50
+	maxUnitPrice            = 100000.0
+	maxQuantity             = 10000.0
+	maxDescriptionLength    = 200
+	maxMerchantLength       = 100
+	maxTagsPerExpense       = 20
+	maxTagLength            = 50
+)
+
+// validateExtraction checks the model output against strict bounds and returns
+// ErrSuspiciousExtraction describing every problem found. Empty units are
+// accepted and default to models.DefaultUnit.
+func validateExtraction(expensesData []models.ExpenseData, language string) error {
+	if len(expensesData) > maxExpensesPerRecording {
+		return fmt.Errorf("%w: %d expenses in one recording (max %d)", ErrSuspiciousExtraction, len(expensesData), maxExpensesPerRecording)
+	}
+
+	var problems []string
+	for i, data := range expensesData {
+		if math.IsNaN(data.UnitPrice) || data.UnitPrice < 0 || data.UnitPrice > maxUnitPrice {
+			problems = append(problems, fmt.Sprintf("expense %d: unit_price %v out of range [0, %v]", i+1, data.UnitPrice, maxUnitPrice))
+		}
+		if math.IsNaN(data.Quantity) || data.Quantity <= 0 || data.Quantity > maxQuantity {
+			problems = append(problems, fmt.Sprintf("expense %d: quantity %v out of range (0, %v]", i+1, data.Quantity, maxQuantity))
+		}
+		if data.Unit != "" && !models.IsKnownUnit(language, data.Unit) {
+			problems = append(problems, fmt.Sprintf("expense %d: unknown unit %q for language %s", i+1, data.Unit, language))
+		}
+		if data.Confidence != nil && (math.IsNaN(*data.Confidence) || *data.Confidence < 0 || *data.Confidence > 1) {
+			problems = append(problems, fmt.Sprintf("expense %d: confidence %v out of range [0, 1]", i+1, *data.Confidence))
+		}
+		description := strings.TrimSpace(data.Description)
+		if description == "" {
+			problems = append(problems, fmt.Sprintf("expense %d: empty description", i+1))
+		} else if utf8.RuneCountInString(description) > maxDescriptionLength {
+			problems = append(problems, fmt.Sprintf("expense %d: description longer than %d characters", i+1, maxDescriptionLength))
+		}
+		if utf8.RuneCountInString(strings.TrimSpace(data.Merchant)) > maxMerchantLength {
+			problems = append(problems, fmt.Sprintf("expense %d: merchant longer than %d characters", i+1, maxMerchantLength))
+		}
+		for _, problem := range tagProblems(normalizeTags(data.Tags)) {
+			problems = append(problems, fmt.Sprintf("expense %d: %s", i+1, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSuspiciousExtraction, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ErrInvalidExpenseUpdate is returned when an expense update is empty or out of bounds
+var ErrInvalidExpenseUpdate = errors.New("invalid expense update")
+
+// applyExpenseUpdate checks an update against the extraction bounds and applies it to
+// expense. Units may be those of any language, since expenses keep the unit of the language
+// they were recorded in.
+func applyExpenseUpdate(expense *models.Expense, update models.ExpenseUpdate) error {
+	if update.UnitPrice == nil && update.Quantity == nil && update.Unit == nil && update.Description == nil {
+		return fmt.Errorf("%w: nothing to update", ErrInvalidExpenseUpdate)
+	}
+
+	var problems []string
+	if update.UnitPrice != nil && (math.IsNaN(*update.UnitPrice) || *update.UnitPrice < 0 || *update.UnitPrice > maxUnitPrice) {
+		problems = append(problems, fmt.Sprintf("unit_price %v out of range [0, %v]", *update.UnitPrice, maxUnitPrice))
+	}
+	if update.Quantity != nil && (math.IsNaN(*update.Quantity) || *update.Quantity <= 0 || *update.Quantity > maxQuantity) {
+		problems = append(problems, fmt.Sprintf("quantity %v out of range (0, %v]", *update.Quantity, maxQuantity))
+	}
+	if update.Unit != nil && !isKnownUnitInAnyLanguage(*update.Unit) {
+		problems = append(problems, fmt.Sprintf("unknown unit %q", *update.Unit))
+	}
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		if description == "" {
+			problems = append(problems, "empty description")
+		} else if utf8.RuneCountInString(description) > maxDescriptionLength {
+			problems = append(problems, fmt.Sprintf("description longer than %d characters", maxDescriptionLength))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidExpenseUpdate, strings.Join(problems, "; "))
+	}
+
+	if update.UnitPrice != nil {
+		expense.UnitPrice = *update.UnitPrice
+	}
+	if update.Quantity != nil {
+		expense.Quantity = *update.Quantity
+	}
+	if update.Unit != nil {
+		expense.Unit = *update.Unit
+	}
+	if update.Description != nil {
+		expense.Description = strings.TrimSpace(*update.Description)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+func TestValidateExtraction(t *testing.T) {
+	valid := func() models.ExpenseData {
+		return models.ExpenseData{UnitPrice: 4.5, Quantity: 2, Unit: "kg", Description: "arroz"}
+	}
+	confidence := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		change func(*models.ExpenseData)
+		// problem is part of the error, empty if the expense is valid
+		problem string
+	}{
+		{"valid", func(*models.ExpenseData) {}, ""},
+		{"free item", func(d *models.ExpenseData) { d.UnitPrice = 0 }, ""},
+		{"highest unit price", func(d *models.ExpenseData) { d.UnitPrice = maxUnitPrice }, ""},
+		{"negative unit price", func(d *models.ExpenseData) { d.UnitPrice = -1 }, "unit_price"},
+		{"unit price too high", func(d *models.ExpenseData) { d.UnitPrice = maxUnitPrice + 0.01 }, "unit_price"},
+		{"NaN unit price", func(d *models.ExpenseData) { d.UnitPrice = math.NaN() }, "unit_price"},
+		{"highest quantity", func(d *models.ExpenseData) { d.Quantity = maxQuantity }, ""},
+		{"zero quantity", func(d *models.ExpenseData) { d.Quantity = 0 }, "quantity"},
+		{"negative quantity", func(d *models.ExpenseData) { d.Quantity = -1 }, "quantity"},
+		{"quantity too high", func(d *models.ExpenseData) { d.Quantity = maxQuantity + 1 }, "quantity"},
+		{"NaN quantity", func(d *models.ExpenseData) { d.Quantity = math.NaN() }, "quantity"},
+		{"empty unit", func(d *models.ExpenseData) { d.Unit = "" }, ""},
+		{"unknown unit", func(d *models.ExpenseData) { d.Unit = "bitcoin" }, "unknown unit"},
+		{"unit of another language", func(d *models.ExpenseData) { d.Unit = "lb" }, "unknown unit"},
+		{"confidence bounds", func(d *models.ExpenseData) { d.Confidence = confidence(1) }, ""},
+		{"negative confidence", func(d *models.ExpenseData) { d.Confidence = confidence(-0.1) }, "confidence"},
+		{"confidence above 1", func(d *models.ExpenseData) { d.Confidence = confidence(1.1) }, "confidence"},
+		{"NaN confidence", func(d *models.ExpenseData) { d.Confidence = confidence(math.NaN()) }, "confidence"},
+		{"blank description", func(d *models.ExpenseData) { d.Description = "  " }, "empty description"},
+		{"longest description", func(d *models.ExpenseData) { d.Description = strings.Repeat("ñ", maxDescriptionLength) }, ""},
+		{"description too long", func(d *models.ExpenseData) { d.Description = strings.Repeat("ñ", maxDescriptionLength+1) }, "description longer"},
+		{"longest merchant", func(d *models.ExpenseData) { d.Merchant = strings.Repeat("a", maxMerchantLength) }, ""},
+		{"merchant too long", func(d *models.ExpenseData) { d.Merchant = strings.Repeat("a", maxMerchantLength+1) }, "merchant longer"},
+		{"most tags", func(d *models.ExpenseData) { d.Tags = numberedTags(maxTagsPerExpense) }, ""},
+		{"too many tags", func(d *models.ExpenseData) { d.Tags = numberedTags(maxTagsPerExpense + 1) }, "tags"},
+		{"tag too long", func(d *models.ExpenseData) { d.Tags = []string{strings.Repeat("a", maxTagLength+1)} }, "tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := valid()
+			tt.change(&data)
+			err := validateExtraction([]models.ExpenseData{valid(), data}, models.LanguageSpanish)
+			if tt.problem == "" {
+				if err != nil {
+					t.Errorf("rejected: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSuspiciousExtraction) {
+				t.Fatalf("err = %v, want ErrSuspiciousExtraction", err)
+			}
+			if !strings.Contains(err.Error(), "expense 2: ") || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("err = %v, want a %q problem of expense 2", err, tt.problem)
+			}
+		})
+	}
+}
+
+func TestValidateExtractionLimitsExpensesPerRecording(t *testing.T) {
+	data := make([]models.ExpenseData, maxExpensesPerRecording+1)
+	for i := range data {
+		data[i] = models.ExpenseData{UnitPrice: 1, Quantity: 1, Description: "pan"}
+	}
+	if err := validateExtraction(data[:maxExpensesPerRecording], models.LanguageSpanish); err != nil {
+		t.Errorf("%d expenses rejected: %v", maxExpensesPerRecording, err)
+	}
+	if err := validateExtraction(data, models.LanguageSpanish); !errors.Is(err, ErrSuspiciousExtraction) {
+		t.Errorf("%d expenses: err = %v, want ErrSuspiciousExtraction", len(data), err)
+	}
+}
+
+func TestValidateExtractionReportsEveryProblem(t *testing.T) {
+	err := validateExtraction([]models.ExpenseData{{UnitPrice: -1, Quantity: 0, Description: ""}}, models.LanguageSpanish)
+	for _, problem := range []string{"unit_price", "quantity", "empty description"} {
+		if err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("err = %v, want it to report %s", err, problem)
+		}
+	}
+}
+
+// numberedTags returns n distinct tags
+func numberedTags(n int) []string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = "tag-" + strings.Repeat("x", i)
+	}
+	return tags
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+)
+
+// Idempotency errors
+var (
+	// ErrIdempotencyKeyReused is returned when a key is reused with a different payload
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different payload")
+	// ErrIdempotencyInProgress is returned when the original request is still being processed
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+const (
+	// IdempotencyKeyTTL is how long a completed response is replayed
+	IdempotencyKeyTTL = 24 * time.Hour
+	// idempotencyAbandonAfter is how long a pending key blocks retries; past the Lambda timeout
+	// the original request can no longer complete
+	idempotencyAbandonAfter = 2 * time.Minute
+)
+
+// IdempotencyService defines the interface for idempotent request handling
+type IdempotencyService interface {
+	// Begin claims a key for a request. It returns nil if the request should be processed,
+	// or the stored record if the same request already completed.
+	Begin(ctx context.Context, userID string, key string, fingerprint string) (*models.IdempotencyRecord, error)
+	// Complete stores the response to replay for the key
+	Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, body []byte) error
+	// Abandon releases the key after a failure worth retrying
+	Abandon(ctx context.Context, userID string, key string) error
+}
+
+type idempotencyService struct {
+	idempotencyRepo repositories.IdempotencyRepository
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(idempotencyRepo repositories.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{
+		idempotencyRepo: idempotencyRepo,
+	}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, userID string, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	now := time.Now().UTC()
+	existing, claimed, err := s.idempotencyRepo.Claim(ctx, &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+	}, now.Add(-IdempotencyKeyTTL), now.Add(-idempotencyAbandonAfter))
+	if err != nil {
+		log.Printf("Failed to claim idempotency key %q for user %s: %v", key, userID, err)
+		return nil, err
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		log.Printf("Idempotency key %q for user %s reused with a different payload", key, userID)
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return nil, ErrIdempotencyInProgress
+	}
+
+	log.Printf("Replaying stored response for idempotency key %q (user %s, status %d)", key, userID, existing.StatusCode)
+	return existing, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, body []byte) error {
+	if err := s.idempotencyRepo.Complete(ctx, userID, key, statusCode, contentType, body); err != nil {
+		log.Printf("Failed to store response for idempotency key %q (user %s): %v", key, userID, err)
+		return err
+	}
+	return nil
+}
+
+func (s *idempotencyService) Abandon(ctx context.Context, userID string, key string) error {
+	if err := s.idempotencyRepo.Delete(ctx, userID, key); err != nil {
+		log.Printf("Failed to release idempotency key %q (user %s): %v", key, userID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidMerchantAlias is returned when an alias has no letters or digits
+var ErrInvalidMerchantAlias = errors.New("alias must contain letters or digits")
+
+// MerchantService defines the interface for merchant business logic
+type MerchantService interface {
+	// Resolve returns the user's merchant for a mentioned name, creating it on first mention.
+	// Returns nil if the name is empty once normalized.
+	Resolve(ctx context.Context, userID string, name string) (*models.Merchant, error)
+	ListMerchants(ctx context.Context, userID string) ([]*models.Merchant, error)
+	// AddAlias makes another spelling resolve to one of the user's merchants
+	AddAlias(ctx context.Context, userID string, merchantID string, alias string) (*models.Merchant, error)
+}
+
+type merchantService struct {
+	merchantRepo repositories.MerchantRepository
+}
+
+// NewMerchantService creates a new merchant service
+func NewMerchantService(merchantRepo repositories.MerchantRepository) MerchantService {
+	return &merchantService{
+		merchantRepo: merchantRepo,
+	}
+}
+
+// merchantNoiseWords are the articles and prepositions dropped from the start of spoken
+// merchant names ("en el Tottus", "at the market", "no mercado")
+var merchantNoiseWords = map[string]bool{
+	"el": true, "la": true, "los": true, "las": true, "del": true, "de": true, "en": true, "al": true,
+	"the": true, "at": true,
+	"o": true, "a": true, "os": true, "as": true, "no": true, "na": true, "do": true, "da": true,
+}
+
+// normalizeMerchantName normalizes a merchant name for matching, dropping leading
+// articles and prepositions as long as a word remains
+func normalizeMerchantName(name string) string {
+	words := strings.Fields(normalizeText(name))
+	for len(words) > 1 && merchantNoiseWords[words[0]] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func (s *merchantService) Resolve(ctx context.Context, userID string, name string) (*models.Merchant, error) {
+	normalized := normalizeMerchantName(name)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	merchant, err := s.merchantRepo.FindOrCreate(ctx, &models.Merchant{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		Name:           strings.TrimSpace(name),
+		NormalizedName: normalized,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to resolve merchant %q for user %s: %v", name, userID, err)
+		return nil, err
+	}
+	return merchant, nil
+}
+
+func (s *merchantService) ListMerchants(ctx context.Context, userID string) ([]*models.Merchant, error) {
+	merchants, err := s.merchantRepo.List(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to list merchants for user %s: %v", userID, err)
+		return nil, err
+	}
+	return merchants, nil
+}
+
+func (s *merchantService) AddAlias(ctx context.Context, userID string, merchantID string, alias string) (*models.Merchant, error) {
+	normalized := normalizeMerchantName(alias)
+	if normalized == "" {
+		return nil, ErrInvalidMerchantAlias
+	}
+
+	merchant, err := s.merchantRepo.FindByID(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	// Other users' merchants are not visible
+	if merchant.UserID != userID {
+		return nil, repositories.ErrMerchantNotFound
+	}
+
+	log.Printf("Adding alias %q to merchant %s (%s)", normalized, merchant.ID, merchant.Name)
+	if err := s.merchantRepo.AddAlias(ctx, merchant.ID, userID, normalized); err != nil {
+		log.Printf("Failed to add alias %q to merchant %s: %v", normalized, merchant.ID, err)
+		return nil, err
+	}
+
+	return s.merchantRepo.FindByID(ctx, merchant.ID)
+}
@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestNormalizeMerchantName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Tottus", "tottus"},
+		{"en el Tottus", "tottus"},
+		{"Panadería Doña Julia", "panaderia dona julia"},
+		{"at the Corner Shop", "corner shop"},
+		{"no Pão de Açúcar", "pao de acucar"},
+		{"La", "la"},
+		{"  ¡! ", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeMerchantName(tt.name); got != tt.want {
+			t.Errorf("normalizeMerchantName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+// maxDaysAgo bounds how far back a spoken purchase date may be
+const maxDaysAgo = 366
+
+// spokenDateLayouts are the layouts the extraction prompt asks for in purchased_at
+var spokenDateLayouts = []string{"2006-01-02T15:04", "2006-01-02"}
+
+// resolvePurchaseDates returns when each extracted expense was purchased. Spoken dates are
+// resolved against reference, the recording time in the user's timezone; days_ago is counted
+// here rather than by the model so the calendar arithmetic is exact. Expenses without a spoken
+// date keep fallback, and so do those whose spoken date is unparseable or out of range, for
+// which problems holds why; a misheard date must not cost the rest of the recording.
+func resolvePurchaseDates(expensesData []models.ExpenseData, reference time.Time, fallback time.Time) (dates []time.Time, problems []error) {
+	dates = make([]time.Time, len(expensesData))
+	problems = make([]error, len(expensesData))
+	for i, data := range expensesData {
+		purchasedAt, err := resolvePurchaseDate(data, reference)
+		switch {
+		case err != nil:
+			problems[i] = err
+			dates[i] = fallback
+		case purchasedAt.IsZero():
+			dates[i] = fallback
+		default:
+			dates[i] = purchasedAt.UTC()
+		}
+	}
+	return dates, problems
+}
+
+// resolvePurchaseDate resolves the spoken date of one expense, or returns the zero time if none
+// was spoken. Days without a time of day keep the reference's clock time.
+func resolvePurchaseDate(data models.ExpenseData, reference time.Time) (time.Time, error) {
+	spoken := strings.TrimSpace(data.PurchasedAt)
+	if spoken != "" {
+		for _, layout := range spokenDateLayouts {
+			purchasedAt, err := time.ParseInLocation(layout, spoken, reference.Location())
+			if err != nil {
+				continue
+			}
+			if len(spoken) == len("2006-01-02") {
+				purchasedAt = time.Date(purchasedAt.Year(), purchasedAt.Month(), purchasedAt.Day(),
+					reference.Hour(), reference.Minute(), reference.Second(), 0, reference.Location())
+			}
+			// A time later today is a misheard or planned purchase, not a past one
+			if purchasedAt.After(reference) {
+				return time.Time{}, fmt.Errorf("purchased_at %s is after the recording", spoken)
+			}
+			if purchasedAt.Before(reference.AddDate(0, 0, -maxDaysAgo)) {
+				return time.Time{}, fmt.Errorf("purchased_at %s is more than %d days before the recording", spoken, maxDaysAgo)
+			}
+			return purchasedAt, nil
+		}
+		return time.Time{}, fmt.Errorf("invalid purchased_at %q", spoken)
+	}
+
+	if data.DaysAgo != nil {
+		if *data.DaysAgo < 0 || *data.DaysAgo > maxDaysAgo {
+			return time.Time{}, fmt.Errorf("days_ago %d out of range [0, %d]", *data.DaysAgo, maxDaysAgo)
+		}
+		// AddDate keeps the wall clock across DST changes
+		return reference.AddDate(0, 0, -*data.DaysAgo), nil
+	}
+
+	return time.Time{}, nil
+}
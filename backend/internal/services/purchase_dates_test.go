@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+func TestResolvePurchaseDates(t *testing.T) {
+	lima, err := time.LoadLocation("America/Lima")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback := time.Date(2026, 2, 21, 13, 15, 0, 0, time.UTC)
+	reference := fallback.In(lima)
+
+	days := func(n int) *int { return &n }
+	data := []models.ExpenseData{
+		{Description: "unspoken"},
+		{Description: "ayer", DaysAgo: days(1)},
+		{Description: "el lunes", DaysAgo: days(5)},
+		{Description: "3 de febrero", PurchasedAt: "2026-02-03"},
+		{Description: "hoy a las 7", PurchasedAt: "2026-02-21T07:00"},
+	}
+	want := []time.Time{
+		fallback,
+		time.Date(2026, 2, 20, 13, 15, 0, 0, time.UTC),
+		time.Date(2026, 2, 16, 13, 15, 0, 0, time.UTC),
+		time.Date(2026, 2, 3, 13, 15, 0, 0, time.UTC),
+		time.Date(2026, 2, 21, 12, 0, 0, 0, time.UTC),
+	}
+
+	dates, problems := resolvePurchaseDates(data, reference, fallback)
+	for i := range want {
+		if problems[i] != nil {
+			t.Errorf("%s: %v", data[i].Description, problems[i])
+		}
+		if !dates[i].Equal(want[i]) {
+			t.Errorf("%s = %s, want %s", data[i].Description, dates[i], want[i])
+		}
+	}
+}
+
+func TestResolvePurchaseDatesFallsBackOnUnusableDates(t *testing.T) {
+	reference := time.Date(2026, 2, 21, 8, 0, 0, 0, time.UTC)
+	fallback := reference.Add(-time.Minute)
+	days := func(n int) *int { return &n }
+
+	data := []models.ExpenseData{
+		{Description: "future", PurchasedAt: "2026-02-21T20:00"},
+		{Description: "tomorrow", PurchasedAt: "2026-02-22"},
+		{Description: "too old", PurchasedAt: "2024-01-01"},
+		{Description: "garbled", PurchasedAt: "last tuesday"},
+		{Description: "negative", DaysAgo: days(-1)},
+		{Description: "too many days", DaysAgo: days(maxDaysAgo + 1)},
+		{Description: "fine", DaysAgo: days(1)},
+	}
+	dates, problems := resolvePurchaseDates(data, reference, fallback)
+	for i, d := range data[:len(data)-1] {
+		if problems[i] == nil || !dates[i].Equal(fallback) {
+			t.Errorf("%s: date %s, problem %v, want the fallback and a problem", d.Description, dates[i], problems[i])
+		}
+	}
+	// An unusable date does not affect the other expenses
+	if last := len(data) - 1; problems[last] != nil || !dates[last].Equal(reference.AddDate(0, 0, -1)) {
+		t.Errorf("fine: date %s, problem %v", dates[last], problems[last])
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Receipt image errors
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image too large")
+	ErrNoReceiptText    = errors.New("no text found in receipt image")
+)
+
+// MaxReceiptImageBytes bounds receipt photos to what a request to the Lambda deployment can
+// carry (OpenAI vision itself accepts up to 20 MB)
+const MaxReceiptImageBytes = 4 << 20
+
+// receiptImageTypes are the image formats accepted by both OCR engines
+var receiptImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// detectReceiptImage checks the size of an image and detects its format from the content
+func detectReceiptImage(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat image: %w", err)
+	}
+	if info.Size() > MaxReceiptImageBytes {
+		return "", fmt.Errorf("%w: %d bytes (max %d)", ErrImageTooLarge, info.Size(), MaxReceiptImageBytes)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !receiptImageTypes[contentType] {
+		return "", fmt.Errorf("%w: %s (expected JPEG, PNG or WebP)", ErrUnsupportedImage, contentType)
+	}
+	return contentType, nil
+}
+
+func (s *expenseService) ProcessReceiptExpense(ctx context.Context, params models.ProcessReceiptParams) ([]*models.Expense, error) {
+	// Step 0: Validate the image before spending an OCR call
+	contentType, err := detectReceiptImage(params.ImagePath)
+	if err != nil {
+		log.Printf("Receipt image rejected: %v", err)
+		return nil, err
+	}
+
+	// Step 1: Read the text of the receipt
+	ocr, err := s.ocrRepo.RecognizeText(ctx, params.ImagePath, contentType)
+	if err != nil {
+		log.Printf("OCR error: %v", err)
+		return nil, err
+	}
+	if ocr.Text == "" {
+		return nil, ErrNoReceiptText
+	}
+	log.Printf("OCR (%s): %d characters", ocr.Engine, len(ocr.Text))
+
+	// Step 2: Resolve language (requested, then user default)
+	language, err := s.resolveLanguage(ctx, params.UserID, params.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Extract expense data with the receipt prompt
+	expensesData, err := s.openaiRepo.ExtractReceiptData(ctx, ocr.Text, language)
+	if err != nil {
+		log.Printf("Extraction error: %v", err)
+		return nil, err
+	}
+	log.Printf("Extracted %d expense(s) from receipt", len(expensesData))
+
+	if err := validateExtraction(expensesData, language); err != nil {
+		log.Printf("Rejected extraction of receipt: %v", err)
+		return nil, err
+	}
+
+	// Step 4: Save the receipt, only once its extraction is accepted, and each expense
+	image, err := os.ReadFile(params.ImagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	receipt := &models.Receipt{
+		ID:            uuid.New().String(),
+		UserID:        params.UserID,
+		ContentType:   contentType,
+		Image:         image,
+		Language:      language,
+		OCRText:       ocr.Text,
+		OCREngine:     ocr.Engine,
+		OCRConfidence: ocr.Confidence,
+		CreatedAt:     time.Now().UTC(),
+	}
+	log.Printf("Saving receipt to database: %s (language=%s, %d bytes)", receipt.ID, receipt.Language, len(image))
+	if err := s.receiptRepo.Create(ctx, receipt); err != nil {
+		log.Printf("Database error for receipt %s: %v", receipt.ID, err)
+		return nil, err
+	}
+
+	return s.saveExpenses(ctx, expenseSource{
+		UserID:      params.UserID,
+		PurchasedAt: params.PurchasedAt,
+		ReceiptID:   receipt.ID,
+		Confidence:  ocr.Confidence,
+	}, expensesData)
+}
+
+func (s *expenseService) GetReceipt(ctx context.Context, id string, userID string) (*models.Receipt, error) {
+	receipt, err := s.receiptRepo.FindByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to get receipt %s: %v", id, err)
+		return nil, err
+	}
+	// Other users' receipts are not visible
+	if receipt.UserID != userID {
+		return nil, repositories.ErrReceiptNotFound
+	}
+	return receipt, nil
+}
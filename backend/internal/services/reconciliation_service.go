@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+)
+
+// ReconciliationWindow is how far a bank transaction may be posted from the recorded purchase
+const ReconciliationWindow = 3 * 24 * time.Hour
+
+// DefaultReconciliationPeriod is how far before to a reconciliation looks when from is not given
+const DefaultReconciliationPeriod = 90 * 24 * time.Hour
+
+// ReconciliationService defines the interface for reconciling recorded expenses against bank transactions
+type ReconciliationService interface {
+	// Reconcile matches the user's unmatched recorded expenses to unmatched imported
+	// transactions, stores the new matches and reports matched and unmatched expenses
+	Reconcile(ctx context.Context, params models.ReconciliationParams) (*models.Reconciliation, error)
+	// Report reports matched and unmatched expenses with the stored matches, without matching
+	Report(ctx context.Context, params models.ReconciliationParams) (*models.Reconciliation, error)
+	// Unmatch deletes the matches of a bank transaction of the user; reconciliation does not
+	// match the same expenses to it again
+	Unmatch(ctx context.Context, userID string, transactionID string) error
+}
+
+type reconciliationService struct {
+	expenseRepo        repositories.ExpenseRepository
+	reconciliationRepo repositories.ReconciliationRepository
+	settingsService    SettingsService
+}
+
+// NewReconciliationService creates a new reconciliation service
+func NewReconciliationService(
+	expenseRepo repositories.ExpenseRepository,
+	reconciliationRepo repositories.ReconciliationRepository,
+	settingsService SettingsService,
+) ReconciliationService {
+	return &reconciliationService{
+		expenseRepo:        expenseRepo,
+		reconciliationRepo: reconciliationRepo,
+		settingsService:    settingsService,
+	}
+}
+
+func (s *reconciliationService) Reconcile(ctx context.Context, params models.ReconciliationParams) (*models.Reconciliation, error) {
+	return s.reconcile(ctx, params, true)
+}
+
+func (s *reconciliationService) Report(ctx context.Context, params models.ReconciliationParams) (*models.Reconciliation, error) {
+	return s.reconcile(ctx, params, false)
+}
+
+func (s *reconciliationService) Unmatch(ctx context.Context, userID string, transactionID string) error {
+	log.Printf("Deleting reconciliation matches of transaction %s for user %s", transactionID, userID)
+	if err := s.reconciliationRepo.DeleteMatches(ctx, userID, transactionID); err != nil {
+		log.Printf("Failed to delete reconciliation matches of transaction %s: %v", transactionID, err)
+		return err
+	}
+	return nil
+}
+
+// reconcile reports the user's expenses within the range, first matching the unmatched ones if match is set
+func (s *reconciliationService) reconcile(ctx context.Context, params models.ReconciliationParams, match bool) (*models.Reconciliation, error) {
+	settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveDays(&params.DateRange, settings.Location()); err != nil {
+		return nil, err
+	}
+
+	from, to := reconciliationBounds(params.DateRange, time.Now().UTC())
+	if params.From != nil || params.To != nil {
+		params.From, params.To = &from, &to
+	}
+
+	// Purchases near the ends of the range may be posted outside it, and the other way round
+	expenses, err := s.expenseRepo.FindRecent(ctx, params.UserID, from.Add(-ReconciliationWindow), to.Add(ReconciliationWindow))
+	if err != nil {
+		log.Printf("Failed to load expenses to reconcile for user %s: %v", params.UserID, err)
+		return nil, err
+	}
+	matches, err := s.reconciliationRepo.ListMatches(ctx, params.UserID)
+	if err != nil {
+		log.Printf("Failed to load reconciliation matches for user %s: %v", params.UserID, err)
+		return nil, err
+	}
+
+	matchedTo := make(map[string]string, len(matches))
+	for _, match := range matches {
+		matchedTo[match.ExpenseID] = match.TransactionID
+	}
+	if !match {
+		return buildReconciliation(params.DateRange, expenses, matchedTo), nil
+	}
+
+	rejections, err := s.reconciliationRepo.ListRejections(ctx, params.UserID)
+	if err != nil {
+		log.Printf("Failed to load rejected reconciliation matches for user %s: %v", params.UserID, err)
+		return nil, err
+	}
+	rejected := make(map[reconciliationPair]bool, len(rejections))
+	for _, rejection := range rejections {
+		rejected[reconciliationPair{rejection.ExpenseID, rejection.TransactionID}] = true
+	}
+
+	paid := make(map[string]bool, len(matches))
+	for _, transactionID := range matchedTo {
+		paid[transactionID] = true
+	}
+	var recorded, transactions []*models.Expense
+	for _, expense := range expenses {
+		switch {
+		case expense.Source == models.ExpenseSourceImport && !paid[expense.ID]:
+			transactions = append(transactions, expense)
+		case expense.Source != models.ExpenseSourceImport && matchedTo[expense.ID] == "":
+			recorded = append(recorded, expense)
+		}
+	}
+
+	newMatches := matchTransactions(recorded, transactions, rejected, time.Now().UTC())
+	if len(newMatches) > 0 {
+		if err := s.reconciliationRepo.SaveMatches(ctx, params.UserID, newMatches); err != nil {
+			log.Printf("Failed to save reconciliation matches for user %s: %v", params.UserID, err)
+			return nil, err
+		}
+		for _, match := range newMatches {
+			matchedTo[match.ExpenseID] = match.TransactionID
+		}
+	}
+	log.Printf("Reconciled expenses of user %s: %d new match(es), %d in total", params.UserID, len(newMatches), len(matchedTo))
+
+	return buildReconciliation(params.DateRange, expenses, matchedTo), nil
+}
+
+// reconciliationBounds returns the period a reconciliation covers: up to to (default: now),
+// from from (default: DefaultReconciliationPeriod before to)
+func reconciliationBounds(dateRange models.DateRange, now time.Time) (time.Time, time.Time) {
+	to := now
+	if dateRange.To != nil {
+		to = *dateRange.To
+	}
+	from := to.Add(-DefaultReconciliationPeriod)
+	if dateRange.From != nil {
+		from = *dateRange.From
+	}
+	return from, to
+}
+
+// reconciliationPair is a recorded expense and a bank transaction
+type reconciliationPair struct {
+	expenseID     string
+	transactionID string
+}
+
+// reconciliationUnit is what one bank transaction can pay for: a single expense, or all
+// the expenses of a recording or receipt
+type reconciliationUnit struct {
+	expenses    []*models.Expense
+	total       float64
+	purchasedAt time.Time
+}
+
+// reconciliationUnits groups recorded expenses into units. Expenses of a recording or receipt
+// form a unit together and each on its own, since the purchase may have been paid in parts.
+func reconciliationUnits(recorded []*models.Expense) []reconciliationUnit {
+	var units []reconciliationUnit
+	groups := make(map[string][]*models.Expense)
+	var keys []string
+	for _, expense := range recorded {
+		units = append(units, reconciliationUnit{
+			expenses:    []*models.Expense{expense},
+			total:       expense.Total(),
+			purchasedAt: expense.PurchasedAt,
+		})
+
+		key := ""
+		if expense.RecordingID != "" {
+			key = "recording:" + expense.RecordingID
+		} else if expense.ReceiptID != "" {
+			key = "receipt:" + expense.ReceiptID
+		}
+		if key == "" {
+			continue
+		}
+		if _, seen := groups[key]; !seen {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], expense)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		unit := reconciliationUnit{expenses: group, purchasedAt: group[0].PurchasedAt}
+		for _, expense := range group {
+			unit.total += expense.Total()
+			if expense.PurchasedAt.Before(unit.purchasedAt) {
+				unit.purchasedAt = expense.PurchasedAt
+			}
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+// matchTransactions pairs recorded expenses with bank transactions of the same amount
+// (within the duplicate tolerance) posted within ReconciliationWindow, closest amounts and
+// dates first, leaving out rejected pairs. Each transaction and each expense is matched at
+// most once.
+func matchTransactions(recorded []*models.Expense, transactions []*models.Expense, rejected map[reconciliationPair]bool, now time.Time) []models.ReconciliationMatch {
+	type candidate struct {
+		unit        reconciliationUnit
+		transaction *models.Expense
+		difference  float64
+		distance    time.Duration
+	}
+
+	var candidates []candidate
+	for _, unit := range reconciliationUnits(recorded) {
+		for _, transaction := range transactions {
+			if !sameAmount(unit.total, transaction.Total()) || anyRejected(unit.expenses, transaction, rejected) {
+				continue
+			}
+			distance := transaction.PurchasedAt.Sub(unit.purchasedAt)
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance > ReconciliationWindow {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				unit:        unit,
+				transaction: transaction,
+				difference:  math.Round(math.Abs(transaction.Total()-unit.total)*100) / 100,
+				distance:    distance,
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.difference != b.difference {
+			return a.difference < b.difference
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		// Paying a whole recording explains more than paying one of its expenses
+		if len(a.unit.expenses) != len(b.unit.expenses) {
+			return len(a.unit.expenses) > len(b.unit.expenses)
+		}
+		if a.transaction.ID != b.transaction.ID {
+			return a.transaction.ID < b.transaction.ID
+		}
+		return a.unit.expenses[0].ID < b.unit.expenses[0].ID
+	})
+
+	usedExpenses := make(map[string]bool)
+	usedTransactions := make(map[string]bool)
+	var matches []models.ReconciliationMatch
+	for _, c := range candidates {
+		if usedTransactions[c.transaction.ID] || anyUsed(c.unit.expenses, usedExpenses) {
+			continue
+		}
+		usedTransactions[c.transaction.ID] = true
+		for _, expense := range c.unit.expenses {
+			usedExpenses[expense.ID] = true
+			matches = append(matches, models.ReconciliationMatch{
+				ExpenseID:     expense.ID,
+				TransactionID: c.transaction.ID,
+				MatchedAt:     now,
+			})
+		}
+	}
+	return matches
+}
+
+func anyUsed(expenses []*models.Expense, used map[string]bool) bool {
+	for _, expense := range expenses {
+		if used[expense.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+func anyRejected(expenses []*models.Expense, transaction *models.Expense, rejected map[reconciliationPair]bool) bool {
+	for _, expense := range expenses {
+		if rejected[reconciliationPair{expense.ID, transaction.ID}] {
+			return true
+		}
+	}
+	return false
+}
+
+// buildReconciliation reports the expenses within dateRange. Without a range, the report
+// covers the period of the imported transactions among expenses.
+func buildReconciliation(dateRange models.DateRange, expenses []*models.Expense, matchedTo map[string]string) *models.Reconciliation {
+	from, to := dateRange.From, dateRange.To
+	if from == nil && to == nil {
+		for _, expense := range expenses {
+			if expense.Source != models.ExpenseSourceImport {
+				continue
+			}
+			purchasedAt := expense.PurchasedAt
+			if from == nil || purchasedAt.Before(*from) {
+				from = &purchasedAt
+			}
+			if to == nil || purchasedAt.After(*to) {
+				to = &purchasedAt
+			}
+		}
+	}
+
+	report := &models.Reconciliation{
+		From:              from,
+		To:                to,
+		Matched:           []models.ReconciledTransaction{},
+		UnmatchedRecorded: []*models.Expense{},
+		UnmatchedBank:     []*models.Expense{},
+	}
+	// No imported transactions: nothing to reconcile
+	if from == nil && to == nil {
+		return report
+	}
+	inRange := func(expense *models.Expense) bool {
+		return (from == nil || !expense.PurchasedAt.Before(*from)) && (to == nil || !expense.PurchasedAt.After(*to))
+	}
+
+	sorted := make([]*models.Expense, len(expenses))
+	copy(sorted, expenses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PurchasedAt.Before(sorted[j].PurchasedAt)
+	})
+
+	paidBy := make(map[string][]*models.Expense)
+	for _, expense := range sorted {
+		if transactionID := matchedTo[expense.ID]; transactionID != "" {
+			paidBy[transactionID] = append(paidBy[transactionID], expense)
+		}
+	}
+
+	for _, expense := range sorted {
+		if !inRange(expense) {
+			continue
+		}
+		switch {
+		case expense.Source == models.ExpenseSourceImport && len(paidBy[expense.ID]) > 0:
+			pair := models.ReconciledTransaction{Transaction: expense, Expenses: paidBy[expense.ID]}
+			recordedTotal := 0.0
+			for _, recorded := range pair.Expenses {
+				recordedTotal += recorded.Total()
+			}
+			pair.Difference = math.Round((expense.Total()-recordedTotal)*100) / 100
+			report.Matched = append(report.Matched, pair)
+		case expense.Source == models.ExpenseSourceImport:
+			report.UnmatchedBank = append(report.UnmatchedBank, expense)
+		case matchedTo[expense.ID] == "":
+			report.UnmatchedRecorded = append(report.UnmatchedRecorded, expense)
+		}
+	}
+	return report
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+func TestMatchTransactions(t *testing.T) {
+	day := time.Date(2026, 2, 20, 15, 0, 0, 0, time.UTC)
+	recorded := []*models.Expense{
+		// One recording: rice and oil paid together
+		{ID: "rice", RecordingID: "r1", UnitPrice: 3.5, Quantity: 2, PurchasedAt: day},
+		{ID: "oil", RecordingID: "r1", UnitPrice: 4.2, Quantity: 1, PurchasedAt: day},
+		{ID: "taxi", UnitPrice: 15, Quantity: 1, PurchasedAt: day},
+		{ID: "late", UnitPrice: 40, Quantity: 1, PurchasedAt: day},
+	}
+	transactions := []*models.Expense{
+		{ID: "bank-groceries", Source: models.ExpenseSourceImport, UnitPrice: 11.2, Quantity: 1, PurchasedAt: day.Add(24 * time.Hour)},
+		{ID: "bank-taxi-far", Source: models.ExpenseSourceImport, UnitPrice: 15, Quantity: 1, PurchasedAt: day.Add(60 * time.Hour)},
+		{ID: "bank-taxi", Source: models.ExpenseSourceImport, UnitPrice: 15, Quantity: 1, PurchasedAt: day.Add(12 * time.Hour)},
+		{ID: "bank-late", Source: models.ExpenseSourceImport, UnitPrice: 40, Quantity: 1, PurchasedAt: day.Add(ReconciliationWindow + time.Hour)},
+	}
+
+	matches := matchTransactions(recorded, transactions, nil, day)
+
+	got := make(map[string]string)
+	for _, match := range matches {
+		got[match.ExpenseID] = match.TransactionID
+	}
+	want := map[string]string{"rice": "bank-groceries", "oil": "bank-groceries", "taxi": "bank-taxi"}
+	if len(got) != len(want) {
+		t.Fatalf("matches = %v, want %v", got, want)
+	}
+	for expenseID, transactionID := range want {
+		if got[expenseID] != transactionID {
+			t.Errorf("%s matched to %q, want %q", expenseID, got[expenseID], transactionID)
+		}
+	}
+}
+
+func TestMatchTransactionsSkipsRejectedPairs(t *testing.T) {
+	day := time.Date(2026, 2, 20, 15, 0, 0, 0, time.UTC)
+	recorded := []*models.Expense{
+		{ID: "rice", RecordingID: "r1", UnitPrice: 3.5, Quantity: 2, PurchasedAt: day},
+		{ID: "oil", RecordingID: "r1", UnitPrice: 4.2, Quantity: 1, PurchasedAt: day},
+		{ID: "taxi", UnitPrice: 15, Quantity: 1, PurchasedAt: day},
+	}
+	transactions := []*models.Expense{
+		{ID: "bank-groceries", Source: models.ExpenseSourceImport, UnitPrice: 11.2, Quantity: 1, PurchasedAt: day},
+		{ID: "bank-taxi", Source: models.ExpenseSourceImport, UnitPrice: 15, Quantity: 1, PurchasedAt: day},
+		{ID: "bank-taxi-later", Source: models.ExpenseSourceImport, UnitPrice: 15, Quantity: 1, PurchasedAt: day.Add(24 * time.Hour)},
+	}
+	rejected := map[reconciliationPair]bool{
+		{"oil", "bank-groceries"}: true,
+		{"taxi", "bank-taxi"}:     true,
+	}
+
+	matches := matchTransactions(recorded, transactions, rejected, day)
+
+	// The groceries cannot be paid together any more, and the taxi goes to the next closest charge
+	if len(matches) != 1 || matches[0].ExpenseID != "taxi" || matches[0].TransactionID != "bank-taxi-later" {
+		t.Errorf("matches = %+v, want only the taxi with the later charge", matches)
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// maxReportTitleLength bounds the title of an expense report
+const maxReportTitleLength = 200
+
+// ErrInvalidReport is returned when a report request has an empty or too long title, or invalid expense IDs
+var ErrInvalidReport = errors.New("invalid report")
+
+// ReportService defines the interface for claiming reimbursable expenses in expense reports
+type ReportService interface {
+	// CreateReport creates a report of the user claiming pending reimbursable expenses
+	CreateReport(ctx context.Context, userID string, request models.CreateReportRequest) (*models.ExpenseReport, error)
+	// GetReport returns a report of the user with its expenses, with times in the user's
+	// timezone; other users' reports are not found
+	GetReport(ctx context.Context, id string, userID string) (*models.ExpenseReport, error)
+	// ReportReceipts returns the receipts, with their images, that the expenses of a report
+	// were read from, by ID
+	ReportReceipts(ctx context.Context, report *models.ExpenseReport) (map[string]*models.Receipt, error)
+	// ListReports returns a user's reports, latest first
+	ListReports(ctx context.Context, userID string) ([]*models.ExpenseReport, error)
+	// AddExpenses adds pending reimbursable expenses to a report of the actor that was not submitted
+	AddExpenses(ctx context.Context, id string, expenseIDs []string, actor string) (*models.ExpenseReport, error)
+	// RemoveExpense takes an expense out of a report of the actor that was not submitted
+	RemoveExpense(ctx context.Context, id string, expenseID string, actor string) (*models.ExpenseReport, error)
+	// SubmitReport submits a report of the actor, moving its expenses to submitted
+	SubmitReport(ctx context.Context, id string, actor string) (*models.ExpenseReport, error)
+	// MarkReimbursed records that a submitted report of the actor was paid, moving its expenses
+	// to reimbursed
+	MarkReimbursed(ctx context.Context, id string, actor string) (*models.ExpenseReport, error)
+}
+
+type reportService struct {
+	reportRepo      repositories.ReportRepository
+	receiptRepo     repositories.ReceiptRepository
+	settingsService SettingsService
+}
+
+// NewReportService creates a new expense report service
+func NewReportService(reportRepo repositories.ReportRepository, receiptRepo repositories.ReceiptRepository, settingsService SettingsService) ReportService {
+	return &reportService{
+		reportRepo:      reportRepo,
+		receiptRepo:     receiptRepo,
+		settingsService: settingsService,
+	}
+}
+
+func (s *reportService) CreateReport(ctx context.Context, userID string, request models.CreateReportRequest) (*models.ExpenseReport, error) {
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: empty title", ErrInvalidReport)
+	}
+	if utf8.RuneCountInString(title) > maxReportTitleLength {
+		return nil, fmt.Errorf("%w: title longer than %d characters", ErrInvalidReport, maxReportTitleLength)
+	}
+	if err := validateExpenseIDs(request.ExpenseIDs); err != nil {
+		return nil, err
+	}
+
+	report := &models.ExpenseReport{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Title:     title,
+		CreatedAt: time.Now().UTC(),
+	}
+	log.Printf("Creating report %s for user %s with %d expense(s)", report.ID, userID, len(request.ExpenseIDs))
+	if err := s.reportRepo.Create(ctx, report, request.ExpenseIDs, userID); err != nil {
+		log.Printf("Failed to create report for user %s: %v", userID, err)
+		return nil, err
+	}
+
+	return s.GetReport(ctx, report.ID, userID)
+}
+
+func (s *reportService) GetReport(ctx context.Context, id string, userID string) (*models.ExpenseReport, error) {
+	report, err := s.findOwnReport(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.settingsService.GetSettings(ctx, report.UserID)
+	if err != nil {
+		return nil, err
+	}
+	location := settings.Location()
+	for _, expense := range report.Expenses {
+		expense.PurchasedAt = expense.PurchasedAt.In(location)
+		expense.CreatedAt = expense.CreatedAt.In(location)
+	}
+	return report, nil
+}
+
+func (s *reportService) ReportReceipts(ctx context.Context, report *models.ExpenseReport) (map[string]*models.Receipt, error) {
+	receipts := make(map[string]*models.Receipt)
+	for _, expense := range report.Expenses {
+		if expense.ReceiptID == "" || receipts[expense.ReceiptID] != nil {
+			continue
+		}
+		receipt, err := s.receiptRepo.FindByID(ctx, expense.ReceiptID)
+		if err != nil {
+			log.Printf("Failed to get receipt %s of report %s: %v", expense.ReceiptID, report.ID, err)
+			return nil, err
+		}
+		// The report's expenses are its user's, and so are their receipts
+		if receipt.UserID != report.UserID {
+			return nil, repositories.ErrReceiptNotFound
+		}
+		receipts[receipt.ID] = receipt
+	}
+	return receipts, nil
+}
+
+func (s *reportService) ListReports(ctx context.Context, userID string) ([]*models.ExpenseReport, error) {
+	return s.reportRepo.List(ctx, userID)
+}
+
+func (s *reportService) AddExpenses(ctx context.Context, id string, expenseIDs []string, actor string) (*models.ExpenseReport, error) {
+	if len(expenseIDs) == 0 {
+		return nil, fmt.Errorf("%w: no expense IDs", ErrInvalidReport)
+	}
+	if err := validateExpenseIDs(expenseIDs); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Adding %d expense(s) to report %s", len(expenseIDs), id)
+	if _, err := s.findOwnReport(ctx, id, actor); err != nil {
+		return nil, err
+	}
+	if err := s.reportRepo.AddExpenses(ctx, id, expenseIDs, actor); err != nil {
+		log.Printf("Failed to add expenses to report %s: %v", id, err)
+		return nil, err
+	}
+	return s.GetReport(ctx, id, actor)
+}
+
+func (s *reportService) RemoveExpense(ctx context.Context, id string, expenseID string, actor string) (*models.ExpenseReport, error) {
+	log.Printf("Removing expense %s from report %s", expenseID, id)
+	if _, err := s.findOwnReport(ctx, id, actor); err != nil {
+		return nil, err
+	}
+	if err := s.reportRepo.RemoveExpense(ctx, id, expenseID, actor); err != nil {
+		log.Printf("Failed to remove expense %s from report %s: %v", expenseID, id, err)
+		return nil, err
+	}
+	return s.GetReport(ctx, id, actor)
+}
+
+func (s *reportService) SubmitReport(ctx context.Context, id string, actor string) (*models.ExpenseReport, error) {
+	return s.updateStatus(ctx, id, models.ReimbursementSubmitted, actor)
+}
+
+func (s *reportService) MarkReimbursed(ctx context.Context, id string, actor string) (*models.ExpenseReport, error) {
+	return s.updateStatus(ctx, id, models.ReimbursementReimbursed, actor)
+}
+
+// updateStatus moves a report and its expenses on to a reimbursement status
+func (s *reportService) updateStatus(ctx context.Context, id string, status string, actor string) (*models.ExpenseReport, error) {
+	log.Printf("Moving report %s to %s", id, status)
+	if _, err := s.findOwnReport(ctx, id, actor); err != nil {
+		return nil, err
+	}
+	if err := s.reportRepo.UpdateStatus(ctx, id, status, actor); err != nil {
+		log.Printf("Failed to move report %s to %s: %v", id, status, err)
+		return nil, err
+	}
+	return s.GetReport(ctx, id, actor)
+}
+
+// findOwnReport finds a report of the given user; other users' reports are not visible
+func (s *reportService) findOwnReport(ctx context.Context, id string, userID string) (*models.ExpenseReport, error) {
+	report, err := s.reportRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.UserID != userID {
+		return nil, repositories.ErrReportNotFound
+	}
+	return report, nil
+}
+
+// validateExpenseIDs checks that expense IDs are distinct UUIDs
+func validateExpenseIDs(expenseIDs []string) error {
+	seen := make(map[string]bool)
+	for _, id := range expenseIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: invalid expense ID %q", ErrInvalidReport, id)
+		}
+		if seen[id] {
+			return fmt.Errorf("%w: repeated expense ID %s", ErrInvalidReport, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/repositories"
+)
+
+// Settings validation errors
+var (
+	// ErrUnsupportedLanguage is returned when a language has no extraction prompt
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	// ErrInvalidTimezone is returned when a timezone is not a known IANA name
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+// SettingsService defines the interface for user settings business logic
+type SettingsService interface {
+	GetSettings(ctx context.Context, userID string) (*models.UserSettings, error)
+	UpdateSettings(ctx context.Context, userID string, req models.UpdateSettingsRequest) (*models.UserSettings, error)
+}
+
+type settingsService struct {
+	settingsRepo repositories.SettingsRepository
+}
+
+// NewSettingsService creates a new user settings service
+func NewSettingsService(settingsRepo repositories.SettingsRepository) SettingsService {
+	return &settingsService{
+		settingsRepo: settingsRepo,
+	}
+}
+
+// GetSettings returns the stored settings for a user, or the defaults if none are stored
+func (s *settingsService) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	settings, err := s.settingsRepo.Get(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get settings for user %s: %v", userID, err)
+		return nil, err
+	}
+	if settings == nil {
+		settings = &models.UserSettings{
+			UserID:   userID,
+			Language: models.DefaultLanguage,
+			Timezone: models.DefaultTimezone,
+		}
+	}
+	return settings, nil
+}
+
+func (s *settingsService) UpdateSettings(ctx context.Context, userID string, req models.UpdateSettingsRequest) (*models.UserSettings, error) {
+	current, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &models.UserSettings{
+		UserID:    userID,
+		Language:  current.Language,
+		Timezone:  current.Timezone,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if req.Language != "" {
+		settings.Language = req.Language
+	}
+	if req.Timezone != "" {
+		settings.Timezone = req.Timezone
+	}
+
+	if !models.IsSupportedLanguage(settings.Language) {
+		return nil, fmt.Errorf("%w: %q (supported: %v)", ErrUnsupportedLanguage, settings.Language, models.SupportedLanguages)
+	}
+	// time.LoadLocation also accepts "" and "Local", which are not IANA names
+	if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "Local" {
+		return nil, fmt.Errorf("%w: %q (expected an IANA name like America/Lima)", ErrInvalidTimezone, settings.Timezone)
+	}
+
+	log.Printf("Updating settings for user %s: language=%s, timezone=%s", userID, settings.Language, settings.Timezone)
+	if err := s.settingsRepo.Upsert(ctx, settings); err != nil {
+		log.Printf("Failed to update settings for user %s: %v", userID, err)
+		return nil, err
+	}
+
+	return settings, nil
+}
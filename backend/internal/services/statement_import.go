@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"upload-lambda/internal/models"
+	"upload-lambda/internal/statements"
+
+	"github.com/google/uuid"
+)
+
+// ErrStatementTooLarge is returned when a bank statement exceeds MaxStatementBytes
+var ErrStatementTooLarge = errors.New("statement too large")
+
+// MaxStatementBytes bounds imported bank statements to what a request to the Lambda
+// deployment can carry
+const MaxStatementBytes = 4 << 20
+
+func (s *expenseService) ImportStatement(ctx context.Context, params models.ImportStatementParams) (*models.ImportResult, error) {
+	info, err := os.Stat(params.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat statement: %w", err)
+	}
+	if info.Size() > MaxStatementBytes {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrStatementTooLarge, info.Size(), MaxStatementBytes)
+	}
+	data, err := os.ReadFile(params.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement: %w", err)
+	}
+
+	// Dates without a time are days in the user's timezone
+	settings, err := s.settingsService.GetSettings(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	location := settings.Location()
+
+	// Step 1: Parse the transactions
+	var transactions []statements.Transaction
+	var rowErrors []statements.RowError
+	switch params.Format {
+	case models.StatementFormatCSV:
+		if params.Mapping == nil {
+			return nil, fmt.Errorf("%w: CSV statements need a column mapping", statements.ErrInvalidStatement)
+		}
+		transactions, rowErrors, err = statements.ParseCSV(data, *params.Mapping, location)
+	case models.StatementFormatOFX:
+		transactions, rowErrors, err = statements.ParseOFX(data, location)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", statements.ErrInvalidStatement, params.Format)
+	}
+	if err != nil {
+		log.Printf("Failed to parse %s statement for user %s: %v", params.Format, params.UserID, err)
+		return nil, err
+	}
+	log.Printf("Parsed %s statement for user %s: %d transaction(s), %d unreadable row(s)",
+		params.Format, params.UserID, len(transactions), len(rowErrors))
+
+	result := &models.ImportResult{
+		Format:   params.Format,
+		Expenses: []*models.Expense{},
+		Errors:   []models.ImportRowError{},
+	}
+	for _, rowErr := range rowErrors {
+		result.Errors = append(result.Errors, models.ImportRowError{Row: rowErr.Row, ExternalID: rowErr.ExternalID, Error: rowErr.Err.Error()})
+	}
+
+	// Step 2: Turn the charges into expenses; credits are not expenses
+	merchants := make(map[string]*models.Merchant)
+	var expenses []*models.Expense
+	for _, transaction := range transactions {
+		if transaction.Amount >= 0 {
+			result.Skipped++
+			continue
+		}
+
+		expense := &models.Expense{
+			ID:          uuid.New().String(),
+			UserID:      params.UserID,
+			UnitPrice:   -transaction.Amount,
+			Quantity:    1,
+			Unit:        models.DefaultUnit,
+			Description: truncateRunes(transaction.Description, maxDescriptionLength),
+			PurchasedAt: transaction.PostedAt.UTC(),
+			Source:      models.ExpenseSourceImport,
+			ExternalID:  transaction.ExternalID,
+			// Bank data needs no review
+			Status:    models.ExpenseStatusConfirmed,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		if name := truncateRunes(transaction.Merchant, maxMerchantLength); name != "" {
+			merchant, cached := merchants[name]
+			if !cached {
+				// As with extracted expenses, a failed lookup leaves the expense unlinked
+				merchant, err = s.merchantService.Resolve(ctx, params.UserID, name)
+				if err != nil {
+					log.Printf("Skipping merchant %q: %v", name, err)
+					merchant = nil
+				}
+				merchants[name] = merchant
+			}
+			if merchant != nil {
+				expense.MerchantID = &merchant.ID
+				expense.Merchant = merchant.Name
+			}
+		}
+
+		expenses = append(expenses, expense)
+	}
+
+	// Step 3: Save them, skipping the transactions imported before
+	if len(expenses) > 0 {
+		created, err := s.expenseRepo.Import(ctx, expenses)
+		if err != nil {
+			log.Printf("Failed to import %d expense(s) for user %s: %v", len(expenses), params.UserID, err)
+			return nil, err
+		}
+		if created != nil {
+			result.Expenses = created
+		}
+	}
+	result.Imported = len(result.Expenses)
+	result.Duplicates = len(expenses) - result.Imported
+
+	log.Printf("Imported %d expense(s) for user %s: %d duplicate(s), %d credit(s) skipped, %d error(s)",
+		result.Imported, params.UserID, result.Duplicates, result.Skipped, len(result.Errors))
+	return result, nil
+}
+
+// truncateRunes trims a string and cuts it to at most max characters
+func truncateRunes(value string, max int) string {
+	value = strings.TrimSpace(value)
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	return strings.TrimSpace(string([]rune(value)[:max]))
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accentFolding maps the accented letters of Spanish and Portuguese to their base letter
+var accentFolding = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// normalizeText lowercases text, folds accents and replaces punctuation with spaces,
+// so spoken and typed variants of the same words compare equal
+func normalizeText(text string) string {
+	folded := accentFolding.Replace(strings.ToLower(text))
+	return strings.Join(strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"upload-lambda/internal/models"
+)
+
+// ErrNoMatchingExpense is returned when a spoken correction or deletion does not match any of
+// the user's recent expenses
+var ErrNoMatchingExpense = errors.New("no recent expense matches the command")
+
+// voiceCommandWindow is how many of the user's latest expenses a spoken correction or
+// deletion can refer to
+const voiceCommandWindow = 20
+
+// applyVoiceCommand corrects or deletes the recent expense a command refers to and returns
+// the change, with the expense as it was so the app can undo it
+func (s *expenseService) applyVoiceCommand(ctx context.Context, userID string, command *models.VoiceCommand) ([]models.ExpenseChange, error) {
+	latest, err := s.expenseRepo.FindLatest(ctx, userID, voiceCommandWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	target := ""
+	if command.Target != nil {
+		target = command.Target.Description
+	}
+	expense := findVoiceTarget(latest, target)
+	if expense == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNoMatchingExpense, target)
+	}
+	before := *expense
+
+	if command.Intent == models.VoiceIntentDelete {
+		log.Printf("Deleting expense %s (%s) by voice", expense.ID, expense.Description)
+		if err := s.expenseRepo.Delete(ctx, expense.ID, userID); err != nil {
+			log.Printf("Failed to delete expense %s: %v", expense.ID, err)
+			return nil, err
+		}
+		return []models.ExpenseChange{{Action: models.ExpenseChangeDeleted, Before: &before}}, nil
+	}
+
+	if command.Changes == nil {
+		return nil, fmt.Errorf("%w: correction without changes", ErrSuspiciousExtraction)
+	}
+	if err := applyVoiceCorrection(expense, *command.Changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSuspiciousExtraction, err)
+	}
+	log.Printf("Correcting expense %s (%s) by voice", expense.ID, before.Description)
+	if err := s.expenseRepo.Update(ctx, expense, userID); err != nil {
+		log.Printf("Failed to update expense %s: %v", expense.ID, err)
+		return nil, err
+	}
+	return []models.ExpenseChange{{Action: models.ExpenseChangeUpdated, Before: &before, After: expense}}, nil
+}
+
+// applyVoiceCorrection applies a spoken correction to an expense. A corrected total is spread
+// over the quantity, after correcting it too if the speaker did.
+func applyVoiceCorrection(expense *models.Expense, correction models.VoiceCorrection) error {
+	update := correction.ExpenseUpdate
+	if correction.Total != nil && update.UnitPrice == nil {
+		quantity := expense.Quantity
+		if update.Quantity != nil {
+			quantity = *update.Quantity
+		}
+		if quantity > 0 {
+			unitPrice := *correction.Total / quantity
+			update.UnitPrice = &unitPrice
+		}
+	}
+	return applyExpenseUpdate(expense, update)
+}
+
+// findVoiceTarget returns the latest expense whose description contains every word of target,
+// or the latest expense if target is empty
+func findVoiceTarget(latest []*models.Expense, target string) *models.Expense {
+	words := strings.Fields(normalizeText(target))
+	for _, expense := range latest {
+		description := strings.Fields(normalizeText(expense.Description))
+		matches := true
+		for _, word := range words {
+			matches = matches && slices.ContainsFunc(description, func(other string) bool {
+				return sameWord(word, other)
+			})
+		}
+		if matches {
+			return expense
+		}
+	}
+	return nil
+}
+
+// sameWord reports whether two normalized words are equal up to a plural ending, so
+// "platanos" matches "platano" and "panes" matches "pan"
+func sameWord(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return b == a || b == a+"s" || b == a+"es"
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"upload-lambda/internal/models"
+)
+
+func TestFindVoiceTarget(t *testing.T) {
+	latest := []*models.Expense{
+		{ID: "milk", Description: "leche"},
+		{ID: "bananas", Description: "Plátanos de seda"},
+		{ID: "bread", Description: "pan integral"},
+	}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"", "milk"},
+		{"platano", "bananas"},
+		{"panes", "bread"},
+		{"pan integral", "bread"},
+		{"arroz", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if expense := findVoiceTarget(latest, tt.target); expense != nil {
+			got = expense.ID
+		}
+		if got != tt.want {
+			t.Errorf("findVoiceTarget(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestApplyVoiceCorrectionSpreadsTotal(t *testing.T) {
+	expense := &models.Expense{UnitPrice: 2, Quantity: 3, Unit: "kg", Description: "arroz"}
+	total, quantity := 10.0, 4.0
+	correction := models.VoiceCorrection{ExpenseUpdate: models.ExpenseUpdate{Quantity: &quantity}, Total: &total}
+
+	if err := applyVoiceCorrection(expense, correction); err != nil {
+		t.Fatal(err)
+	}
+	if expense.Quantity != 4 || expense.UnitPrice != 2.5 {
+		t.Errorf("expense = %+v, want 4 at 2.5", expense)
+	}
+}
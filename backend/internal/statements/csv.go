@@ -0,0 +1,248 @@
+package statements
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"upload-lambda/internal/models"
+)
+
+// dateFormatTokens translates mapping date formats into Go layouts
+var dateFormatTokens = strings.NewReplacer(
+	"YYYY", "2006", "YY", "06", "MM", "01", "DD", "02", "HH", "15", "mm", "04", "ss", "05",
+)
+
+// csvColumns are the header positions of the mapped columns, -1 if not mapped
+type csvColumns struct {
+	date, description, amount, debit, externalID, merchant int
+}
+
+// csvLayout is a validated column mapping
+type csvLayout struct {
+	mapping          models.CSVColumnMapping
+	dateFormat       string
+	dateLayout       string
+	hasTime          bool
+	decimalSeparator rune
+	delimiter        rune
+}
+
+// parseMapping validates a column mapping
+func parseMapping(mapping models.CSVColumnMapping) (*csvLayout, error) {
+	if mapping.Date == "" || mapping.Description == "" {
+		return nil, fmt.Errorf("%w: the mapping needs the date and description columns", ErrInvalidStatement)
+	}
+	if (mapping.Amount == "") == (mapping.Debit == "") {
+		return nil, fmt.Errorf("%w: the mapping needs either an amount or a debit column", ErrInvalidStatement)
+	}
+
+	layout := &csvLayout{mapping: mapping, decimalSeparator: '.', delimiter: ','}
+
+	format := mapping.DateFormat
+	if format == "" {
+		format = "YYYY-MM-DD"
+	}
+	layout.dateFormat = format
+	layout.dateLayout = dateFormatTokens.Replace(format)
+	if !strings.Contains(layout.dateLayout, "06") || !strings.Contains(layout.dateLayout, "01") || !strings.Contains(layout.dateLayout, "02") {
+		return nil, fmt.Errorf("%w: date_format %q needs the year (YYYY), month (MM) and day (DD)", ErrInvalidStatement, format)
+	}
+	layout.hasTime = strings.Contains(layout.dateLayout, "15")
+
+	switch mapping.DecimalSeparator {
+	case "", ".":
+	case ",":
+		layout.decimalSeparator = ','
+	default:
+		return nil, fmt.Errorf("%w: decimal_separator must be \".\" or \",\"", ErrInvalidStatement)
+	}
+
+	switch mapping.Delimiter {
+	case "", ",":
+	case ";", "\t", "|":
+		layout.delimiter, _ = utf8.DecodeRuneInString(mapping.Delimiter)
+	default:
+		return nil, fmt.Errorf("%w: delimiter must be \",\", \";\", \"|\" or a tab", ErrInvalidStatement)
+	}
+	if layout.delimiter == layout.decimalSeparator {
+		return nil, fmt.Errorf("%w: delimiter and decimal_separator must differ", ErrInvalidStatement)
+	}
+
+	return layout, nil
+}
+
+// ParseCSV reads the transactions of a bank CSV with a header row. Dates without a time
+// are placed in location. Rows that cannot be read are returned as RowErrors.
+func ParseCSV(data []byte, mapping models.CSVColumnMapping, location *time.Location) ([]Transaction, []RowError, error) {
+	layout, err := parseMapping(mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	text, err := toUTF8(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = layout.delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: the file is empty", ErrInvalidStatement)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	columns, err := findColumns(header, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var transactions []Transaction
+	var rowErrors []RowError
+	// Rows without an ID column are identified by their content and occurrence,
+	// so that the same two coffees on one day stay two transactions
+	occurrences := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+			}
+			rowErrors = append(rowErrors, RowError{Row: parseErr.Line, Err: parseErr.Err})
+			continue
+		}
+		row, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+		if len(transactions)+len(rowErrors) >= MaxTransactions {
+			return nil, nil, fmt.Errorf("%w: more than %d transactions", ErrInvalidStatement, MaxTransactions)
+		}
+
+		transaction, err := layout.transaction(record, columns, location)
+		transaction.Row = row
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, ExternalID: transaction.ExternalID, Err: err})
+			continue
+		}
+		if transaction.ExternalID == "" {
+			key := field(record, columns.date) + "|" + field(record, columns.amount) + field(record, columns.debit) + "|" + field(record, columns.description)
+			occurrences[key]++
+			sum := sha256.Sum256([]byte(key + "|" + strconv.Itoa(occurrences[key])))
+			transaction.ExternalID = "csv-" + hex.EncodeToString(sum[:10])
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// findColumns locates the mapped columns in the header row
+func findColumns(header []string, mapping models.CSVColumnMapping) (csvColumns, error) {
+	positions := make(map[string]int)
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var missing []string
+	find := func(name string) int {
+		if name == "" {
+			return -1
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			missing = append(missing, strconv.Quote(name))
+			return -1
+		}
+		return i
+	}
+
+	columns := csvColumns{
+		date:        find(mapping.Date),
+		description: find(mapping.Description),
+		amount:      find(mapping.Amount),
+		debit:       find(mapping.Debit),
+		externalID:  find(mapping.ExternalID),
+		merchant:    find(mapping.Merchant),
+	}
+	if len(missing) > 0 {
+		return csvColumns{}, fmt.Errorf("%w: columns %s not found in the header", ErrInvalidStatement, strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// transaction reads the mapped fields of a row
+func (l *csvLayout) transaction(record []string, columns csvColumns, location *time.Location) (Transaction, error) {
+	transaction := Transaction{
+		ExternalID:  field(record, columns.externalID),
+		Description: field(record, columns.description),
+		Merchant:    field(record, columns.merchant),
+	}
+
+	date := field(record, columns.date)
+	postedAt, err := time.ParseInLocation(l.dateLayout, date, location)
+	if err != nil {
+		return transaction, fmt.Errorf("invalid date %q (expected %s)", date, l.dateFormat)
+	}
+	if !l.hasTime {
+		postedAt = dateOnly(postedAt.Year(), postedAt.Month(), postedAt.Day(), location)
+	}
+	transaction.PostedAt = postedAt
+
+	if columns.debit >= 0 {
+		// Credit rows leave the debit column empty
+		debit := field(record, columns.debit)
+		if debit != "" {
+			amount, err := parseAmount(debit, l.decimalSeparator)
+			if err != nil {
+				return transaction, err
+			}
+			transaction.Amount = -amount
+		}
+	} else {
+		amount, err := parseAmount(field(record, columns.amount), l.decimalSeparator)
+		if err != nil {
+			return transaction, err
+		}
+		if l.mapping.ChargesPositive {
+			amount = -amount
+		}
+		transaction.Amount = amount
+	}
+
+	if transaction.Description == "" {
+		return transaction, errors.New("empty description")
+	}
+	return transaction, nil
+}
+
+// field returns a trimmed field of a row, empty if the column is not mapped or missing
+func field(record []string, column int) string {
+	if column < 0 || column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package statements
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ofxTransaction holds the fields of a <STMTTRN> aggregate
+type ofxTransaction map[string]string
+
+// ParseOFX reads the transactions of an OFX statement, either OFX 1.x (SGML, where leaf
+// elements have no closing tags) or OFX 2.x (XML). Transaction IDs (FITID) are prefixed
+// with the account ID so that IDs of different accounts do not collide.
+func ParseOFX(data []byte, location *time.Location) ([]Transaction, []RowError, error) {
+	text, err := toUTF8(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.Contains(strings.ToUpper(text), "<OFX>") {
+		return nil, nil, fmt.Errorf("%w: no <OFX> element found", ErrInvalidStatement)
+	}
+
+	var transactions []Transaction
+	var rowErrors []RowError
+	var account string
+	var current ofxTransaction
+	position := 0
+
+	for rest := text; ; {
+		start := strings.IndexByte(rest, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(rest[start+1 : start+end]))
+		rest = rest[start+end+1:]
+
+		// The value of a leaf element runs until the next tag
+		value := rest
+		if next := strings.IndexByte(rest, '<'); next >= 0 {
+			value = rest[:next]
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+			// XML declaration, OFX processing instruction or comment
+		case tag == "STMTTRN":
+			current = ofxTransaction{}
+		case tag == "/STMTTRN":
+			if current == nil {
+				continue
+			}
+			position++
+			if position > MaxTransactions {
+				return nil, nil, fmt.Errorf("%w: more than %d transactions", ErrInvalidStatement, MaxTransactions)
+			}
+			transaction, err := current.transaction(location)
+			transaction.Row = position
+			if account != "" && transaction.ExternalID != "" {
+				transaction.ExternalID = account + ":" + transaction.ExternalID
+			}
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: position, ExternalID: transaction.ExternalID, Err: err})
+			} else {
+				transactions = append(transactions, transaction)
+			}
+			current = nil
+		case tag == "ACCTID" && current == nil:
+			account = value
+		case current != nil && !strings.HasPrefix(tag, "/"):
+			current[tag] = unescapeOFX(value)
+		}
+	}
+
+	if position == 0 && !strings.Contains(strings.ToUpper(text), "<BANKTRANLIST>") {
+		return nil, nil, fmt.Errorf("%w: no transaction list found", ErrInvalidStatement)
+	}
+	return transactions, rowErrors, nil
+}
+
+// transaction reads the fields of a <STMTTRN>
+func (t ofxTransaction) transaction(location *time.Location) (Transaction, error) {
+	transaction := Transaction{
+		ExternalID:  t["FITID"],
+		Description: t["NAME"],
+	}
+	if memo := t["MEMO"]; transaction.Description == "" {
+		transaction.Description = memo
+	} else if memo != "" && memo != transaction.Description {
+		transaction.Description += " - " + memo
+	}
+
+	if transaction.ExternalID == "" {
+		return transaction, errors.New("missing FITID")
+	}
+	postedAt, err := parseOFXDate(t["DTPOSTED"], location)
+	if err != nil {
+		return transaction, err
+	}
+	transaction.PostedAt = postedAt
+
+	// OFX amounts use a period, but some banks write a comma
+	amount, err := parseAmount(strings.ReplaceAll(t["TRNAMT"], ",", "."), '.')
+	if err != nil {
+		return transaction, err
+	}
+	transaction.Amount = amount
+
+	if transaction.Description == "" {
+		return transaction, errors.New("missing NAME and MEMO")
+	}
+	return transaction, nil
+}
+
+// parseOFXDate parses an OFX date, YYYYMMDD optionally followed by HHMMSS, milliseconds and
+// a timezone offset in hours ("20260220143000.000[-5:EST]"). Times without an offset are UTC,
+// as the OFX specification says; dates without a time are days in location.
+func parseOFXDate(value string, location *time.Location) (time.Time, error) {
+	invalid := fmt.Errorf("invalid DTPOSTED %q", value)
+
+	offset := time.UTC
+	if open := strings.IndexByte(value, '['); open >= 0 {
+		zone := strings.TrimSuffix(value[open+1:], "]")
+		value = value[:open]
+		hours, _, _ := strings.Cut(zone, ":")
+		parsed, err := strconv.ParseFloat(hours, 64)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		offset = time.FixedZone(zone, int(parsed*3600))
+	}
+	value, _, _ = strings.Cut(value, ".")
+
+	switch len(value) {
+	case 8:
+		day, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		return dateOnly(day.Year(), day.Month(), day.Day(), location), nil
+	case 12, 14:
+		layout := "200601021504"
+		if len(value) == 14 {
+			layout += "05"
+		}
+		postedAt, err := time.ParseInLocation(layout, value, offset)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		return postedAt, nil
+	default:
+		return time.Time{}, invalid
+	}
+}
+
+// unescapeOFX replaces the SGML entities OFX allows in values
+func unescapeOFX(value string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(value)
+}
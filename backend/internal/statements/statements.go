@@ -0,0 +1,95 @@
+// Package statements parses bank statements (CSV and OFX) into transactions.
+package statements
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrInvalidStatement is returned when a statement cannot be read at all, as opposed to
+// a transaction of it (see RowError)
+var ErrInvalidStatement = errors.New("invalid statement")
+
+// MaxTransactions bounds the transactions of one statement
+const MaxTransactions = 5000
+
+// Transaction is a transaction read from a statement
+type Transaction struct {
+	// Row is the line of a CSV row or the position of an OFX transaction (1-based)
+	Row        int
+	ExternalID string
+	PostedAt   time.Time
+	// Amount is negative for money going out of the account
+	Amount      float64
+	Description string
+	// Merchant is only set when the statement has a merchant column
+	Merchant string
+}
+
+// RowError is a transaction that could not be read
+type RowError struct {
+	Row        int
+	ExternalID string
+	Err        error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// toUTF8 decodes statements that are not valid UTF-8 as Windows-1252, the usual
+// encoding of bank exports that are not UTF-8
+func toUTF8(data []byte) (string, error) {
+	data = []byte(strings.TrimPrefix(string(data), "\ufeff"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("%w: unknown text encoding", ErrInvalidStatement)
+	}
+	return string(decoded), nil
+}
+
+// dateOnly places a date without a time at noon in location, so the
+// transaction stays on that day in nearby timezones
+func dateOnly(year int, month time.Month, day int, location *time.Location) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, location)
+}
+
+// parseAmount parses an amount with the given decimal separator, ignoring currency symbols,
+// spaces and thousands separators. Parentheses mean a negative amount: "(12.50)".
+func parseAmount(value string, decimalSeparator rune) (float64, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")")
+
+	var digits strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == decimalSeparator:
+			digits.WriteRune('.')
+		case r == '-' && digits.Len() == 0:
+			negative = !negative
+		}
+	}
+	if digits.Len() == 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	amount, err := strconv.ParseFloat(digits.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
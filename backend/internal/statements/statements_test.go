@@ -0,0 +1,156 @@
+package statements
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"upload-lambda/internal/models"
+)
+
+func TestParseCSVWithDebitColumnAndDecimalComma(t *testing.T) {
+	lima, err := time.LoadLocation("America/Lima")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "Panadería" in Windows-1252
+	data := []byte("Fecha;Descripci\xf3n;Cargo;Abono\n" +
+		"20/02/2026;Panader\xeda;12,50;\n" +
+		"20/02/2026;Sueldo;;3.500,00\n" +
+		"21/02/2026;Tottus;1.234,90;\n" +
+		"31/02/2026;Fecha mala;1,00;\n")
+	mapping := models.CSVColumnMapping{
+		Date: "fecha", Description: "Descripción", Debit: "Cargo",
+		DateFormat: "DD/MM/YYYY", DecimalSeparator: ",", Delimiter: ";",
+	}
+
+	transactions, rowErrors, err := ParseCSV(data, mapping, lima)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("got %d transactions, want 3", len(transactions))
+	}
+
+	bakery := transactions[0]
+	if bakery.Description != "Panadería" || bakery.Amount != -12.5 || bakery.Row != 2 {
+		t.Errorf("bakery = %+v", bakery)
+	}
+	if want := time.Date(2026, 2, 20, 12, 0, 0, 0, lima); !bakery.PostedAt.Equal(want) {
+		t.Errorf("posted_at = %s, want %s", bakery.PostedAt, want)
+	}
+	if salary := transactions[1]; salary.Amount != 0 {
+		t.Errorf("credit row amount = %v, want 0", salary.Amount)
+	}
+	if tottus := transactions[2]; tottus.Amount != -1234.9 {
+		t.Errorf("tottus amount = %v", tottus.Amount)
+	}
+
+	if len(rowErrors) != 1 || rowErrors[0].Row != 5 {
+		t.Errorf("row errors = %v, want one on line 5", rowErrors)
+	}
+}
+
+func TestParseCSVDerivesStableExternalIDs(t *testing.T) {
+	data := []byte("date,description,amount\n" +
+		"2026-02-20,Cafe,-3.50\n" +
+		"2026-02-20,Cafe,-3.50\n")
+	mapping := models.CSVColumnMapping{Date: "date", Description: "description", Amount: "amount"}
+
+	first, _, err := ParseCSV(data, mapping, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, _ := ParseCSV(data, mapping, time.UTC)
+
+	if first[0].ExternalID == first[1].ExternalID {
+		t.Errorf("identical rows share the external ID %q", first[0].ExternalID)
+	}
+	if first[0].ExternalID != again[0].ExternalID || first[1].ExternalID != again[1].ExternalID {
+		t.Errorf("external IDs change between parses: %q/%q vs %q/%q",
+			first[0].ExternalID, first[1].ExternalID, again[0].ExternalID, again[1].ExternalID)
+	}
+}
+
+func TestParseCSVRejectsInvalidMappings(t *testing.T) {
+	data := []byte("date,description,amount\n2026-02-20,Cafe,-3.50\n")
+	for name, mapping := range map[string]models.CSVColumnMapping{
+		"no amount":       {Date: "date", Description: "description"},
+		"unknown column":  {Date: "date", Description: "memo", Amount: "amount"},
+		"bad date format": {Date: "date", Description: "description", Amount: "amount", DateFormat: "MM/DD"},
+		"same separators": {Date: "date", Description: "description", Amount: "amount", DecimalSeparator: ","},
+	} {
+		if _, _, err := ParseCSV(data, mapping, time.UTC); !errors.Is(err, ErrInvalidStatement) {
+			t.Errorf("%s: err = %v, want ErrInvalidStatement", name, err)
+		}
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	data := []byte(`OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>PEN
+<BANKACCTFROM><BANKID>002<ACCTID>1234567<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20260201<DTEND>20260228
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260220143000.000[-5:PET]<TRNAMT>-45.90<FITID>A1<NAME>TOTTUS MIRAFLORES<MEMO>Compra con tarjeta</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260221<TRNAMT>3500.00<FITID>A2<NAME>SUELDO</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260222<TRNAMT>-10.00<NAME>SIN ID</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`)
+
+	transactions, rowErrors, err := ParseOFX(data, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(transactions))
+	}
+
+	purchase := transactions[0]
+	if purchase.ExternalID != "1234567:A1" || purchase.Amount != -45.9 || purchase.Description != "TOTTUS MIRAFLORES - Compra con tarjeta" {
+		t.Errorf("purchase = %+v", purchase)
+	}
+	if want := time.Date(2026, 2, 20, 19, 30, 0, 0, time.UTC); !purchase.PostedAt.Equal(want) {
+		t.Errorf("posted_at = %s, want %s", purchase.PostedAt, want)
+	}
+	if salary := transactions[1]; salary.Amount != 3500 {
+		t.Errorf("salary = %+v", salary)
+	}
+
+	if len(rowErrors) != 1 || rowErrors[0].Row != 3 {
+		t.Errorf("row errors = %v, want the transaction without FITID", rowErrors)
+	}
+
+	if _, _, err := ParseOFX([]byte("date,amount\n"), time.UTC); !errors.Is(err, ErrInvalidStatement) {
+		t.Errorf("CSV parsed as OFX: err = %v", err)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	for _, tc := range []struct {
+		value     string
+		separator rune
+		want      float64
+	}{
+		{"-12.50", '.', -12.5},
+		{"S/ 1,234.90", '.', 1234.9},
+		{"1.234,90", ',', 1234.9},
+		{"(7.25)", '.', -7.25},
+		{"$ -3", '.', -3},
+	} {
+		got, err := parseAmount(tc.value, tc.separator)
+		if err != nil || got != tc.want {
+			t.Errorf("parseAmount(%q) = %v, %v; want %v", tc.value, got, err, tc.want)
+		}
+	}
+	if _, err := parseAmount("1.2.3", '.'); err == nil {
+		t.Error("parseAmount(1.2.3) succeeded")
+	}
+}
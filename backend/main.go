@@ -4,7 +4,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	_ "time/tzdata" // IANA timezones for spoken dates; the Lambda runtime has no zoneinfo
+	"upload-lambda/internal/audio"
 	"upload-lambda/internal/handlers"
+	"upload-lambda/internal/prompts"
 	"upload-lambda/internal/repositories"
 	"upload-lambda/internal/services"
 
@@ -51,21 +54,70 @@ func main() {
 		port = "8080"
 	}
 
+	// Load prompt templates (embedded unless PROMPTS_DIR is set)
+	promptRegistry, err := prompts.Load(os.Getenv("PROMPTS_DIR"))
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	if spec := os.Getenv("EXTRACT_PROMPT_WEIGHTS"); spec != "" {
+		weights, err := prompts.ParseWeights(spec)
+		if err != nil {
+			log.Fatalf("Invalid EXTRACT_PROMPT_WEIGHTS: %v", err)
+		}
+		if err := promptRegistry.SetWeights(prompts.ExtractExpenses, weights); err != nil {
+			log.Fatalf("Invalid EXTRACT_PROMPT_WEIGHTS: %v", err)
+		}
+	}
+
 	// Initialize repositories
-	openaiRepo := repositories.NewOpenAIRepository(openaiAPIKey)
+	openaiConfig := repositories.OpenAIConfig{
+		APIKey:     openaiAPIKey,
+		BaseURL:    os.Getenv("OPENAI_BASE_URL"),
+		Resilience: repositories.DefaultResilienceConfig(),
+	}
+	openaiRepo := repositories.NewOpenAIRepository(openaiConfig, promptRegistry)
 	expenseRepo := repositories.NewPostgresRepository(dbURL)
+	recordingRepo := repositories.NewPostgresRecordingRepository(dbURL)
+	settingsRepo := repositories.NewPostgresSettingsRepository(dbURL)
+	idempotencyRepo := repositories.NewPostgresIdempotencyRepository(dbURL)
+	receiptRepo := repositories.NewPostgresReceiptRepository(dbURL)
+	merchantRepo := repositories.NewPostgresMerchantRepository(dbURL)
+	reconciliationRepo := repositories.NewPostgresReconciliationRepository(dbURL)
+	reportRepo := repositories.NewPostgresReportRepository(dbURL)
+
+	// Receipt OCR: OpenAI vision by default, or a local tesseract binary
+	var ocrRepo repositories.OCRRepository
+	switch engine := os.Getenv("OCR_ENGINE"); engine {
+	case "", "openai":
+		ocrRepo = repositories.NewOpenAIVisionOCR(openaiConfig)
+	case "tesseract":
+		ocrRepo = repositories.NewTesseractOCR(os.Getenv("TESSERACT_PATH"), os.Getenv("TESSERACT_LANGUAGES"))
+	default:
+		log.Fatalf("Invalid OCR_ENGINE %q (expected openai or tesseract)", engine)
+	}
 
-	// Create service with dependency injection
-	expenseService := services.NewExpenseService(openaiRepo, expenseRepo)
+	// Create services with dependency injection
+	settingsService := services.NewSettingsService(settingsRepo)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo)
+	merchantService := services.NewMerchantService(merchantRepo)
+	ffmpegPath := os.Getenv("FFMPEG_PATH")
+	if ffmpegPath == "" {
+		log.Printf("FFMPEG_PATH not set: only WAV and m4a recordings are checked for duration, and only WAV for silence")
+	}
+	audioProcessor := audio.NewProcessor(audio.DefaultLimits(), ffmpegPath)
+	expenseService := services.NewExpenseService(openaiRepo, expenseRepo, recordingRepo, receiptRepo, ocrRepo, settingsService, merchantService, audioProcessor)
+	reconciliationService := services.NewReconciliationService(expenseRepo, reconciliationRepo, settingsService)
+	askService := services.NewAskService(openaiRepo, expenseService, settingsService, merchantService, audioProcessor)
+	reportService := services.NewReportService(reportRepo, receiptRepo, settingsService)
 
 	// Route based on environment
 	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
 		// Lambda mode
-		lambdaHandler := handlers.NewLambdaHandler(expenseService)
+		lambdaHandler := handlers.NewLambdaHandler(expenseService, settingsService, idempotencyService, merchantService, reconciliationService, askService, reportService)
 		lambda.Start(lambdaHandler.Handle)
 	} else {
 		// HTTP server mode (local development)
-		router := handlers.NewRouter(expenseService)
+		router := handlers.NewRouter(expenseService, settingsService, idempotencyService, merchantService, reconciliationService, askService, reportService)
 
 		log.Printf("🚀 Server starting on port %s", port)
 		log.Printf("📝 Test with: curl -X POST http://localhost:%s/upload -F \"audio=@your-file.m4a\"", port)
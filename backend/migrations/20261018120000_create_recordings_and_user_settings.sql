@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recordings (
+    id UUID PRIMARY KEY,
+    user_id TEXT NOT NULL DEFAULT 'default',
+    language VARCHAR(10) NOT NULL DEFAULT 'es',
+    transcription TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recordings_user_id ON recordings(user_id);
+
+ALTER TABLE expenses ADD COLUMN recording_id UUID REFERENCES recordings(id);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id TEXT PRIMARY KEY,
+    language VARCHAR(10) NOT NULL DEFAULT 'es',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_settings;
+ALTER TABLE expenses DROP COLUMN recording_id;
+DROP INDEX IF EXISTS idx_recordings_user_id;
+DROP TABLE IF EXISTS recordings;
+-- +goose StatementEnd
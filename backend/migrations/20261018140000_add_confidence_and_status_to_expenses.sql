@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expenses ADD COLUMN confidence DOUBLE PRECISION;
+ALTER TABLE expenses ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'confirmed';
+ALTER TABLE recordings ADD COLUMN transcription_confidence DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_status ON expenses(status) WHERE status = 'needs_review';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_status;
+ALTER TABLE recordings DROP COLUMN transcription_confidence;
+ALTER TABLE expenses DROP COLUMN status;
+ALTER TABLE expenses DROP COLUMN confidence;
+-- +goose StatementEnd
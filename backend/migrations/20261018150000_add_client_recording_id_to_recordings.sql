@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recordings ADD COLUMN client_recording_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_recordings_client_recording_id ON recordings(user_id, client_recording_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_recordings_client_recording_id;
+ALTER TABLE recordings DROP COLUMN client_recording_id;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expenses ADD COLUMN user_id TEXT NOT NULL DEFAULT 'default';
+
+UPDATE expenses e
+SET user_id = r.user_id
+FROM recordings r
+WHERE r.id = e.recording_id;
+
+ALTER TABLE expenses ADD COLUMN possible_duplicate_of UUID REFERENCES expenses(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_user_id_purchased_at ON expenses(user_id, purchased_at);
+CREATE INDEX IF NOT EXISTS idx_expenses_possible_duplicate_of ON expenses(possible_duplicate_of) WHERE possible_duplicate_of IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_possible_duplicate_of;
+DROP INDEX IF EXISTS idx_expenses_user_id_purchased_at;
+ALTER TABLE expenses DROP COLUMN possible_duplicate_of;
+ALTER TABLE expenses DROP COLUMN user_id;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS receipts (
+    id UUID PRIMARY KEY,
+    user_id TEXT NOT NULL DEFAULT 'default',
+    content_type VARCHAR(50) NOT NULL,
+    image BYTEA NOT NULL,
+    language VARCHAR(10) NOT NULL DEFAULT 'es',
+    ocr_text TEXT NOT NULL,
+    ocr_engine VARCHAR(20) NOT NULL,
+    ocr_confidence DOUBLE PRECISION,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipts_user_id ON receipts(user_id);
+
+ALTER TABLE expenses ADD COLUMN receipt_id UUID REFERENCES receipts(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE expenses DROP COLUMN receipt_id;
+DROP INDEX IF EXISTS idx_receipts_user_id;
+DROP TABLE IF EXISTS receipts;
+-- +goose StatementEnd
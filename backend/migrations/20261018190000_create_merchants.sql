@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS merchants (
+    id UUID PRIMARY KEY,
+    user_id TEXT NOT NULL DEFAULT 'default',
+    name TEXT NOT NULL,
+    normalized_name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, normalized_name)
+);
+
+CREATE TABLE IF NOT EXISTS merchant_aliases (
+    user_id TEXT NOT NULL DEFAULT 'default',
+    alias TEXT NOT NULL,
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, alias)
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_aliases_merchant_id ON merchant_aliases(merchant_id);
+
+ALTER TABLE expenses ADD COLUMN merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_merchant_id ON expenses(merchant_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_merchant_id;
+ALTER TABLE expenses DROP COLUMN merchant_id;
+DROP INDEX IF EXISTS idx_merchant_aliases_merchant_id;
+DROP TABLE IF EXISTS merchant_aliases;
+DROP TABLE IF EXISTS merchants;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expenses ADD COLUMN source TEXT NOT NULL DEFAULT 'voice';
+ALTER TABLE expenses ADD COLUMN external_id TEXT;
+
+UPDATE expenses SET source = 'receipt' WHERE receipt_id IS NOT NULL;
+
+-- Imported bank transactions are deduplicated by their ID
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expenses_user_id_external_id ON expenses(user_id, external_id) WHERE external_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_user_id_external_id;
+ALTER TABLE expenses DROP COLUMN external_id;
+ALTER TABLE expenses DROP COLUMN source;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reconciliation_matches (
+    expense_id UUID PRIMARY KEY REFERENCES expenses(id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL DEFAULT 'default',
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_matches_user_id ON reconciliation_matches(user_id);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_matches_transaction_id ON reconciliation_matches(transaction_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reconciliation_matches_transaction_id;
+DROP INDEX IF EXISTS idx_reconciliation_matches_user_id;
+DROP TABLE IF EXISTS reconciliation_matches;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keyset pagination orders by (created_at, id) or (purchased_at, id) in either direction
+DROP INDEX IF EXISTS idx_expenses_created_at;
+CREATE INDEX IF NOT EXISTS idx_expenses_created_at_id ON expenses(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_purchased_at_id ON expenses(purchased_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_purchased_at_id;
+DROP INDEX IF EXISTS idx_expenses_created_at_id;
+CREATE INDEX IF NOT EXISTS idx_expenses_created_at ON expenses(created_at DESC);
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- Spanish stemming of accent-folded words, so "cafe" finds "café" and "pila" finds "pilas"
+CREATE TEXT SEARCH CONFIGURATION es_unaccent (COPY = spanish);
+ALTER TEXT SEARCH CONFIGURATION es_unaccent
+    ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+
+ALTER TABLE expenses ADD COLUMN search_vector TSVECTOR;
+
+-- The description weighs more than the transcription of the recording the expense was read from
+CREATE OR REPLACE FUNCTION expenses_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('es_unaccent', COALESCE(NEW.description, '')), 'A') ||
+        setweight(to_tsvector('es_unaccent', COALESCE(
+            (SELECT transcription FROM recordings WHERE recordings.id = NEW.recording_id), '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER expenses_search_vector_update
+    BEFORE INSERT OR UPDATE OF description, recording_id ON expenses
+    FOR EACH ROW EXECUTE FUNCTION expenses_search_vector();
+
+-- Fires the trigger for the existing expenses
+UPDATE expenses SET description = description;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_search_vector ON expenses USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_search_vector;
+DROP TRIGGER IF EXISTS expenses_search_vector_update ON expenses;
+DROP FUNCTION IF EXISTS expenses_search_vector();
+ALTER TABLE expenses DROP COLUMN search_vector;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS es_unaccent;
+-- +goose StatementEnd
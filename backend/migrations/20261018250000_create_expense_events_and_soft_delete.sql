@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE expenses ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS expense_events (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    actor TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_events_expense_id ON expense_events(expense_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS expense_events;
+ALTER TABLE expenses DROP COLUMN deleted_at;
+-- +goose StatementEnd
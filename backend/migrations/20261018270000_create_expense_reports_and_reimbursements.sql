@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS expense_reports (
+    id UUID PRIMARY KEY,
+    user_id TEXT NOT NULL DEFAULT 'default',
+    title TEXT NOT NULL,
+    submitted_at TIMESTAMPTZ,
+    reimbursed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_reports_user_id ON expense_reports(user_id, created_at);
+
+ALTER TABLE expenses ADD COLUMN reimbursable BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE expenses ADD COLUMN reimbursement_status TEXT CHECK (reimbursement_status IN ('pending', 'submitted', 'reimbursed'));
+ALTER TABLE expenses ADD COLUMN report_id UUID REFERENCES expense_reports(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD CONSTRAINT expenses_reimbursable_status_check
+    CHECK (reimbursable = (reimbursement_status IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS idx_expenses_report_id ON expenses(report_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expenses_report_id;
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_reimbursable_status_check;
+ALTER TABLE expenses DROP COLUMN report_id;
+ALTER TABLE expenses DROP COLUMN reimbursement_status;
+ALTER TABLE expenses DROP COLUMN reimbursable;
+DROP INDEX IF EXISTS idx_expense_reports_user_id;
+DROP TABLE IF EXISTS expense_reports;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reconciliation_rejections (
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL DEFAULT 'default',
+    rejected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (expense_id, transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_rejections_user_id ON reconciliation_rejections(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reconciliation_rejections_user_id;
+DROP TABLE IF EXISTS reconciliation_rejections;
+-- +goose StatementEnd
@@ -40,6 +40,37 @@ resource "null_resource" "build_lambda" {
   }
 }
 
+# ffmpeg layer: a static ffmpeg build at /opt/bin/ffmpeg, used to measure the duration
+# and loudness of compressed recordings and to transcode the formats Whisper rejects
+resource "null_resource" "build_ffmpeg_layer" {
+  triggers = {
+    ffmpeg_url    = var.ffmpeg_url
+    ffmpeg_sha256 = var.ffmpeg_sha256
+  }
+
+  # The archive is checked against ffmpeg_sha256 before anything is extracted from it
+  provisioner "local-exec" {
+    command     = "rm -rf ../build/ffmpeg-layer ../build/ffmpeg.tar.xz && mkdir -p ../build/ffmpeg-layer/bin && curl -sSfL -o ../build/ffmpeg.tar.xz '${var.ffmpeg_url}' && echo '${var.ffmpeg_sha256}  ../build/ffmpeg.tar.xz' | sha256sum -c - && tar -xJf ../build/ffmpeg.tar.xz -C ../build/ffmpeg-layer/bin --strip-components=1 --wildcards '*/ffmpeg' && rm ../build/ffmpeg.tar.xz"
+    working_dir = path.module
+  }
+}
+
+data "archive_file" "ffmpeg_layer_zip" {
+  type        = "zip"
+  source_dir  = "../build/ffmpeg-layer"
+  output_path = "../ffmpeg-layer.zip"
+
+  depends_on = [null_resource.build_ffmpeg_layer]
+}
+
+resource "aws_lambda_layer_version" "ffmpeg" {
+  layer_name               = "ffmpeg"
+  filename                 = data.archive_file.ffmpeg_layer_zip.output_path
+  source_code_hash         = data.archive_file.ffmpeg_layer_zip.output_base64sha256
+  compatible_runtimes      = ["provided.al2023"]
+  compatible_architectures = ["x86_64"]
+}
+
 # Zip lambda binary
 data "archive_file" "lambda_zip" {
   type        = "zip"
@@ -61,10 +92,13 @@ resource "aws_lambda_function" "upload_lambda" {
   timeout     = 90
   memory_size = 512
 
+  layers = [aws_lambda_layer_version.ffmpeg.arn]
+
   environment {
     variables = {
       OPENAI_API_KEY = var.openai_api_key
       DB_URL         = var.db_url
+      FFMPEG_PATH    = "/opt/bin/ffmpeg"
     }
   }
 
@@ -91,12 +125,204 @@ resource "aws_apigatewayv2_route" "upload_route" {
   target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
 }
 
+resource "aws_apigatewayv2_route" "upload_receipt_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /upload/receipt"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "receipt_image_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /receipts/{id}/image"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
 resource "aws_apigatewayv2_route" "expenses_route" {
   api_id    = aws_apigatewayv2_api.api.id
   route_key = "GET /expenses"
   target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
 }
 
+resource "aws_apigatewayv2_route" "import_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /import"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "reconciliation_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /reconciliation"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "reconcile_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /reconciliation"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "reconciliation_match_delete_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "DELETE /reconciliation/matches/{transaction_id}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expenses_summary_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /expenses/summary"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_update_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "PATCH /expenses/{id}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_delete_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "DELETE /expenses/{id}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_restore_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /expenses/{id}/restore"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_history_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /expenses/{id}/history"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_tag_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /expenses/{id}/tags"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_untag_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "DELETE /expenses/{id}/tags/{tag}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_reimbursable_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "PUT /expenses/{id}/reimbursable"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "reports_list_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /reports"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_create_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /reports"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_get_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /reports/{id}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_document_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /reports/{id}/document"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_add_expenses_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /reports/{id}/expenses"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_remove_expense_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "DELETE /reports/{id}/expenses/{expense_id}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_submit_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /reports/{id}/submit"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "report_reimburse_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /reports/{id}/reimburse"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "ask_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /ask"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expenses_export_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /expenses/export"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_confirm_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /expenses/{id}/confirm"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_duplicate_merge_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /expenses/{id}/duplicate/merge"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "expense_duplicate_dismiss_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /expenses/{id}/duplicate/dismiss"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "review_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /review"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "merchants_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /merchants"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "merchant_aliases_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "POST /merchants/{id}/aliases"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "settings_get_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "GET /settings"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
+resource "aws_apigatewayv2_route" "settings_put_route" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "PUT /settings"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda_integration.id}"
+}
+
 resource "aws_apigatewayv2_route" "health_route" {
   api_id    = aws_apigatewayv2_api.api.id
   route_key = "GET /health"